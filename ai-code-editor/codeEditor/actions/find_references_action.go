@@ -0,0 +1,21 @@
+package codeEditor
+
+type FindReferencesAction struct {
+	actionName string
+	Name       string
+}
+
+func NewFindReferencesAction(name string) *FindReferencesAction {
+	return &FindReferencesAction{
+		actionName: "find_references",
+		Name:       name,
+	}
+}
+
+func (f *FindReferencesAction) ToString() string {
+	return "<find_references>\n<name>" + f.Name + "\n</find_references>"
+}
+
+func (f *FindReferencesAction) GetType() string {
+	return f.actionName
+}
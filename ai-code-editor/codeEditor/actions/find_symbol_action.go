@@ -0,0 +1,21 @@
+package codeEditor
+
+type FindSymbolAction struct {
+	actionName string
+	Name       string
+}
+
+func NewFindSymbolAction(name string) *FindSymbolAction {
+	return &FindSymbolAction{
+		actionName: "find_symbol",
+		Name:       name,
+	}
+}
+
+func (f *FindSymbolAction) ToString() string {
+	return "<find_symbol>\n<name>" + f.Name + "\n</find_symbol>"
+}
+
+func (f *FindSymbolAction) GetType() string {
+	return f.actionName
+}
@@ -0,0 +1,27 @@
+package codeEditor
+
+import "strconv"
+
+type GrepAction struct {
+	actionName string
+	Pattern    string
+	Glob       string // optional, limits the search to matching files
+	Context    int    // lines shown around each match
+}
+
+func NewGrepAction(pattern string, glob string, context int) *GrepAction {
+	return &GrepAction{
+		actionName: "grep",
+		Pattern:    pattern,
+		Glob:       glob,
+		Context:    context,
+	}
+}
+
+func (g *GrepAction) ToString() string {
+	return "<grep>\n<pattern>" + g.Pattern + "\n<glob>" + g.Glob + "\n<context>" + strconv.Itoa(g.Context) + "\n</grep>"
+}
+
+func (g *GrepAction) GetType() string {
+	return g.actionName
+}
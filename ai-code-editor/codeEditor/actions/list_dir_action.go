@@ -0,0 +1,21 @@
+package codeEditor
+
+type ListDirAction struct {
+	actionName string
+	Path       string
+}
+
+func NewListDirAction(path string) *ListDirAction {
+	return &ListDirAction{
+		actionName: "list_dir",
+		Path:       path,
+	}
+}
+
+func (l *ListDirAction) ToString() string {
+	return "<list_dir>\n<path>" + l.Path + "\n</list_dir>"
+}
+
+func (l *ListDirAction) GetType() string {
+	return l.actionName
+}
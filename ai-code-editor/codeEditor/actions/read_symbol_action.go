@@ -0,0 +1,23 @@
+package codeEditor
+
+type ReadSymbolAction struct {
+	actionName string
+	Name       string
+	Path       string // optional, limits the lookup to one file
+}
+
+func NewReadSymbolAction(name string, path string) *ReadSymbolAction {
+	return &ReadSymbolAction{
+		actionName: "read_symbol",
+		Name:       name,
+		Path:       path,
+	}
+}
+
+func (r *ReadSymbolAction) ToString() string {
+	return "<read_symbol>\n<name>" + r.Name + "\n<path>" + r.Path + "\n</read_symbol>"
+}
+
+func (r *ReadSymbolAction) GetType() string {
+	return r.actionName
+}
@@ -0,0 +1,23 @@
+package codeEditor
+
+type RunCommandAction struct {
+	actionName string
+	Command    string
+	Dir        string // optional, relative to the project root
+}
+
+func NewRunCommandAction(command string, dir string) *RunCommandAction {
+	return &RunCommandAction{
+		actionName: "run_command",
+		Command:    command,
+		Dir:        dir,
+	}
+}
+
+func (r *RunCommandAction) ToString() string {
+	return "<run_command>\n<command>" + r.Command + "\n<dir>" + r.Dir + "\n</run_command>"
+}
+
+func (r *RunCommandAction) GetType() string {
+	return r.actionName
+}
@@ -4,6 +4,7 @@ import (
 	codeEditor "ai-code-editor/codeEditor/actions"
 	"encoding/json"
 	"log"
+	"strconv"
 	"strings"
 )
 
@@ -15,9 +16,39 @@ type ActionResponse struct {
 }
 
 type Action struct {
-	Type    string `json:"type"`
-	Path    string `json:"path"`
-	Content string `json:"content,omitempty"`
+	Type      string     `json:"type"`
+	Path      string     `json:"path"`
+	Content   string     `json:"content,omitempty"`
+	StartLine int        `json:"start_line,omitempty"`
+	EndLine   int        `json:"end_line,omitempty"`
+	Edit      string     `json:"action,omitempty"` // replace or insert
+	Name      string     `json:"name,omitempty"`
+	Pattern   string     `json:"pattern,omitempty"`
+	Glob      string     `json:"glob,omitempty"`
+	Context   lenientInt `json:"context,omitempty"`
+	Command   string     `json:"command,omitempty"`
+	Dir       string     `json:"dir,omitempty"`
+}
+
+// lenientInt decodes a number that models sometimes send as a string, such as
+// "2", so one quoted number does not discard every action of a reply.
+// Strings that are not numbers decode to 0.
+type lenientInt int
+
+func (i *lenientInt) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*i = lenientInt(number)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	number, _ = strconv.Atoi(strings.TrimSpace(text))
+	*i = lenientInt(number)
+	return nil
 }
 
 func NewAiResponseParser() *AiResponseParser {
@@ -49,7 +80,23 @@ func (a *AiResponseParser) ParseResponse(response string) []codeEditor.BaseActio
 		case "open_file":
 			actions = append(actions, codeEditor.NewRequestFileAction(action.Path))
 		case "edit_file":
-			actions = append(actions, codeEditor.NewEditFileAction(action.Path, action.Content))
+			editAction := codeEditor.NewEditFileAction(action.Path, action.Content)
+			editAction.StartLine = action.StartLine
+			editAction.EndLine = action.EndLine
+			editAction.Action = action.Edit
+			actions = append(actions, editAction)
+		case "find_symbol":
+			actions = append(actions, codeEditor.NewFindSymbolAction(action.Name))
+		case "find_references":
+			actions = append(actions, codeEditor.NewFindReferencesAction(action.Name))
+		case "read_symbol":
+			actions = append(actions, codeEditor.NewReadSymbolAction(action.Name, action.Path))
+		case "grep":
+			actions = append(actions, codeEditor.NewGrepAction(action.Pattern, action.Glob, int(action.Context)))
+		case "list_dir":
+			actions = append(actions, codeEditor.NewListDirAction(action.Path))
+		case "run_command":
+			actions = append(actions, codeEditor.NewRunCommandAction(action.Command, action.Dir))
 		default:
 			log.Printf("Unknown action type: %s", action.Type)
 		}
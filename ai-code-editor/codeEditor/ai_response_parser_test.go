@@ -0,0 +1,25 @@
+package codeEditor
+
+import (
+	codeEditorActions "ai-code-editor/codeEditor/actions"
+	"testing"
+)
+
+func TestAiResponseParser_GrepContext(t *testing.T) {
+	cases := map[string]int{
+		`{"actions": [{"type": "grep", "pattern": "Load", "context": 3}]}`:      3,
+		`{"actions": [{"type": "grep", "pattern": "Load", "context": "3"}]}`:    3,
+		`{"actions": [{"type": "grep", "pattern": "Load", "context": "some"}]}`: 0,
+		`{"actions": [{"type": "grep", "pattern": "Load"}]}`:                    0,
+	}
+
+	for reply, expected := range cases {
+		actions := NewAiResponseParser().ParseResponse(reply)
+		if len(actions) != 1 {
+			t.Fatalf("Expected one action from %s, got %d", reply, len(actions))
+		}
+		if grep := actions[0].(*codeEditorActions.GrepAction); grep.Context != expected {
+			t.Errorf("Expected context %d from %s, got %d", expected, reply, grep.Context)
+		}
+	}
+}
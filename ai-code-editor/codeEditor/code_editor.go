@@ -3,25 +3,103 @@ package codeEditor
 import (
 	codeEditorActions "ai-code-editor/codeEditor/actions"
 	codeEditorSchemas "ai-code-editor/codeEditor/promptFunctions/schemas"
+	"ai-code-editor/config"
 	"ai-code-editor/ollama"
+	"ai-code-editor/redaction"
 	"ai-code-editor/services"
+	"bufio"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 type CodeEditor struct {
+	numCtx         int
+	reservedTokens int
+	directoryTree  *services.DirectoryTree
+	navigator      *services.SymbolNavigator
+	explorer       *services.WorkspaceExplorer
+	commandRunner  *services.CommandRunner
+	verifier       *services.Verifier
+	verifyRounds   int
+	pathGuard      *services.PathGuard
+	contextSources []services.ContextSource
+	gitContext     *services.GitContextProvider
 }
 
-func NewCodeEditor() *CodeEditor {
-	return &CodeEditor{}
+// contextActionsHelp tells the model which actions it can use to gather context
+const contextActionsHelp = `Available actions:
+- {"type": "open_file", "path": "path/to/file"}: read a whole file
+- {"type": "find_symbol", "name": "Name"}: list where a function, method or type is defined ("Type.Method" narrows methods)
+- {"type": "find_references", "name": "Name"}: list the lines that use a name
+- {"type": "read_symbol", "name": "Name", "path": "optional/path"}: read only the source of one definition, with line numbers
+- {"type": "grep", "pattern": "regex", "glob": "optional *.go", "context": 2}: search file contents with a regular expression
+- {"type": "list_dir", "path": "path/to/dir"}: list the files and directories in a directory ("." for the root)
+- {"type": "run_command", "command": "go test ./...", "dir": "optional/dir"}: run a build, test, lint or format command and read its output
+Prefer find_symbol and read_symbol over opening large files.`
+
+// repairPrompt asks the model to fix the checks its edits broke
+const repairPrompt = `Your edits for the task below could not be applied or broke the checks that must pass.
+Fix the problems with edit_file actions; line numbers refer to the current files.
+
+TASK: %s
+
+%s
+Respond with JSON.`
+
+func NewCodeEditor(config *config.Config) *CodeEditor {
+	verifyChecks := config.VerifyCommands
+	if len(verifyChecks) == 0 {
+		verifyChecks = services.DefaultVerifyChecks(".")
+	}
+	// Checks are configured by the user, so they are their own allowlist
+	verifyRunner := services.NewCommandRunner(".", verifyChecks, config.CommandTimeout, config.CommandMaxOutputBytes, nil)
+
+	for extensions, command := range config.FormatCommands {
+		if err := services.RegisterFormatterCommand(strings.Split(extensions, ","), command); err != nil {
+			log.Printf("Warning: invalid formatter for %s: %v", extensions, err)
+		}
+	}
+
+	deniedPaths := append(append([]string{}, services.DefaultDeniedPaths...), config.DeniedPaths...)
+
+	editor := &CodeEditor{
+		numCtx:         config.NumCtx,
+		reservedTokens: config.ResponseReserveTokens,
+		directoryTree:  services.NewDirectoryTree("    ", 10, config.MaxFileSizeBytes, []string{}),
+		navigator:      services.NewSymbolNavigator(".", config.MaxFileSizeBytes, deniedPaths),
+		explorer:       services.NewWorkspaceExplorer(".", config.MaxFileSizeBytes, deniedPaths),
+		commandRunner:  services.NewCommandRunner(".", config.CommandAllowlist, config.CommandTimeout, config.CommandMaxOutputBytes, confirmCommand),
+		verifier:       services.NewVerifier(".", verifyChecks, verifyRunner),
+		verifyRounds:   config.VerifyRounds,
+		pathGuard:      services.NewPathGuard(".", deniedPaths),
+	}
+
+	if config.GitContext {
+		editor.gitContext = services.NewGitContextProvider(".", config.GitHistoryCommits, deniedPaths)
+		editor.AddContextSource(editor.gitContext)
+	}
+
+	return editor
 }
 
-func (c *CodeEditor) EditCodeBase(client *ollama.Client, model string, basePrompt string, userTask string) {
+// AddContextSource adds a source whose items are packed into the first prompt
+// of a task, such as the uncommitted changes of a git repository
+func (c *CodeEditor) AddContextSource(source services.ContextSource) {
+	c.contextSources = append(c.contextSources, source)
+}
+
+// EditCodeBase gathers context for userTask and edits the code to complete it.
+// It returns the paths of the files it changed, which are none when the edits
+// were rolled back.
+func (c *CodeEditor) EditCodeBase(client *ollama.Client, model string, basePrompt string, userTask string) []string {
 
 	// Reading code
 	c.LearnFromFiles(client, model, basePrompt, userTask)
 
-	c.EditCode(client, model, userTask)
+	return c.EditCode(client, model, userTask)
 }
 
 func (c *CodeEditor) LearnFromFiles(client *ollama.Client, model string, basePrompt string, userTask string) {
@@ -31,10 +109,16 @@ func (c *CodeEditor) LearnFromFiles(client *ollama.Client, model string, basePro
 	// Use the new schema object
 	expectedFormat := codeEditorSchemas.NewFileRequestSchema()
 
-	var initialReply string = c.SendMessage(client, model, expectedFormat, basePrompt+"\n\n USER TASK="+userTask+"\n\n  Open at least 1 file that is relevant to the USER TASK. FIND CONTEXT.")
+	var initialPrompt string = basePrompt + "\n\n USER TASK=" + userTask + "\n\n  Open at least 1 file that is relevant to the USER TASK. FIND CONTEXT.\n\n" + contextActionsHelp
+	// Leave half of the window for the files the model asks for
+	initialPrompt += c.sourceContext(userTask, c.contextBudget(ollama.EstimateTokens(initialPrompt))/2)
+	var initialReply string = c.SendMessage(client, model, expectedFormat, initialPrompt)
 
 	log.Printf("Initial reply:\n %v", initialReply)
 
+	// Tokens already used by the conversation, so file contents never overflow the context window
+	usedTokens := ollama.EstimateTokens(initialPrompt) + ollama.EstimateTokens(initialReply)
+
 	// Parse the response
 	var actions []codeEditorActions.BaseAction = parser.ParseResponse(initialReply)
 	// Execute the actions
@@ -50,19 +134,23 @@ func (c *CodeEditor) LearnFromFiles(client *ollama.Client, model string, basePro
 
 		log.Printf("Attempting to execute actions: %v", actions)
 
-		for _, action := range actions {
-			fileContents += "\n\n" + c.ExecuteAction(action)
-		}
+		fileContents = c.gatherContext(actions, c.contextBudget(usedTokens))
+		fileContents += c.relevantHistory(actions, c.contextBudget(usedTokens+ollama.EstimateTokens(fileContents)))
 
-		reply = c.SendMessage(client, model, expectedFormat, fileContents+"\n\nDo you need more context to solve the USER TASK? If you need more files, provide more files to open, return an empty list of actions if you don't need more context. Respond with JSON.")
+		var prompt string = fileContents + "\n\nDo you need more context to solve the USER TASK? If you need more files, provide more files to open, return an empty list of actions if you don't need more context. Respond with JSON."
+		reply = c.SendMessage(client, model, expectedFormat, prompt)
 		actions = parser.ParseResponse(reply)
 
+		usedTokens += ollama.EstimateTokens(prompt) + ollama.EstimateTokens(reply)
+
 		log.Printf("Intermediate reply: %s", reply)
 	}
 
 }
 
-func (c *CodeEditor) EditCode(client *ollama.Client, model string, userTask string) {
+// EditCode asks the model to edit the code for userTask and verifies the
+// edits. It returns the paths of the files it changed.
+func (c *CodeEditor) EditCode(client *ollama.Client, model string, userTask string) []string {
 	// Create a parser to parse the response
 	parser := NewAiResponseParser()
 
@@ -93,16 +181,59 @@ func (c *CodeEditor) EditCode(client *ollama.Client, model string, userTask stri
 
 	log.Printf("Edit Reply: %s", reply)
 
-	var actions []codeEditorActions.BaseAction = parser.ParseResponse(reply)
+	// Without edits there is nothing to verify, so the checks are not run at all
+	actions := parser.ParseResponse(reply)
+	if !hasEditActions(actions) {
+		log.Printf("No edit actions found in response")
+		return nil
+	}
+
+	// Checks that already fail before the edit are not the edit's fault
+	baseline := c.verifier.Verify()
+	if !baseline.Passed {
+		log.Printf("Warning: checks already fail before the edit; only new failures are repaired:\n%s", c.verifier.Report(baseline))
+	}
+
+	snapshot := services.NewEditSnapshot()
+	editErrors := c.applyEdits(actions, snapshot)
+
+	if !c.verifyEdits(client, model, userTask, snapshot, editErrors, baseline) {
+		return nil
+	}
+	return snapshot.Paths()
+}
+
+// hasEditActions reports whether any of actions is an edit_file action
+func hasEditActions(actions []codeEditorActions.BaseAction) bool {
+	for _, action := range actions {
+		if action.GetType() == "edit_file" {
+			return true
+		}
+	}
+	return false
+}
 
+// applyEdits executes the edit_file actions file by file, recording each
+// file in the snapshot before it is first changed. It returns a description
+// of the edits that could not be applied, such as those with syntax errors.
+func (c *CodeEditor) applyEdits(actions []codeEditorActions.BaseAction, snapshot *services.EditSnapshot) string {
 	// Seperate each action by file
 	var fileActions map[string][]codeEditorActions.BaseAction = make(map[string][]codeEditorActions.BaseAction)
 
-	// Group actions by file path
+	// Group actions by file path, refusing paths outside the project
+	refused := make(map[string]error)
 	for _, action := range actions {
 		if action.GetType() == "edit_file" {
 			editAction := action.(*codeEditorActions.EditFileAction)
-			path := editAction.Path
+			path, err := c.pathGuard.Resolve(editAction.Path)
+			if err != nil {
+				log.Printf("Error: %v", err)
+				refused[editAction.Path] = err
+				continue
+			}
+			editAction.Path = path
+			// The model only saw placeholders for secrets; write the real values
+			editAction.Content = redaction.Shared().Restore(editAction.Content)
 			if _, exists := fileActions[path]; !exists {
 				fileActions[path] = make([]codeEditorActions.BaseAction, 0)
 			}
@@ -111,11 +242,69 @@ func (c *CodeEditor) EditCode(client *ollama.Client, model string, userTask stri
 	}
 
 	// Execute actions for each file
-	for _, actions := range fileActions {
-		if len(actions) > 0 {
-			c.ExecuteEditFileAction(actions)
+	var editErrors strings.Builder
+	for path, err := range refused {
+		fmt.Fprintf(&editErrors, "The edit of %s was not applied: %v\n", path, err)
+	}
+	for path, actions := range fileActions {
+		if len(actions) == 0 {
+			continue
+		}
+		if err := snapshot.Record(path); err != nil {
+			log.Printf("Error: %v", err)
+			continue
+		}
+		if err := c.ExecuteEditFileAction(actions); err != nil {
+			log.Printf("Error editing %s: %v", path, err)
+			fmt.Fprintf(&editErrors, "The edit of %s was not applied: %v\n", path, err)
 		}
 	}
+
+	return editErrors.String()
+}
+
+// verifyEdits runs the checks after an edit. While they fail with problems
+// the baseline run before the edit did not have, or edits could not be
+// applied, the model gets the diagnostics and up to verifyRounds chances to
+// repair its edits, which are rolled back if the checks never pass. It
+// reports whether they passed.
+func (c *CodeEditor) verifyEdits(client *ollama.Client, model string, userTask string, snapshot *services.EditSnapshot, editErrors string, baseline services.VerifyResult) bool {
+	// Nothing changed and nothing was refused; a refused edit still goes back to the model
+	if len(snapshot.Paths()) == 0 && editErrors == "" {
+		return true
+	}
+
+	parser := NewAiResponseParser()
+
+	for round := 0; ; round++ {
+		result := c.verifier.Regressions(baseline, c.verifier.Verify())
+		if result.Passed && editErrors == "" {
+			if len(c.verifier.Checks()) > 0 {
+				log.Printf("Checks passed: %s", strings.Join(c.verifier.Checks(), ", "))
+			}
+			return true
+		}
+		if round == c.verifyRounds {
+			break
+		}
+
+		report := editErrors + c.verifier.Report(result)
+		log.Printf("Checks failed, repair round %d of %d:\n%s", round+1, c.verifyRounds, report)
+
+		reply := c.SendMessage(client, model, codeEditorSchemas.NewEditRequestSchema(), fmt.Sprintf(repairPrompt, userTask, report))
+		actions := parser.ParseResponse(reply)
+		if len(actions) == 0 {
+			log.Printf("No repair edits found in response")
+			break
+		}
+		editErrors = c.applyEdits(actions, snapshot)
+	}
+
+	log.Printf("Warning: checks still fail, rolling back edits to %s", strings.Join(snapshot.Paths(), ", "))
+	if err := snapshot.Restore(); err != nil {
+		log.Printf("Error rolling back edits: %v", err)
+	}
+	return false
 }
 
 func (c *CodeEditor) SendMessage(client *ollama.Client, model string, jsonFormat any, prompt string) string {
@@ -134,6 +323,10 @@ func (c *CodeEditor) SendMessage(client *ollama.Client, model string, jsonFormat
 		req.WithFormat(jsonFormat)
 	}
 
+	if c.numCtx > 0 {
+		req.WithNumCtx(c.numCtx)
+	}
+
 	resp, err := client.ChatCompletion(req)
 	if err != nil {
 		var errorMessage string = fmt.Errorf("error sending message: %w", err).Error()
@@ -149,7 +342,8 @@ func (c *CodeEditor) SendMessage(client *ollama.Client, model string, jsonFormat
 func (c *CodeEditor) ExecuteAction(action codeEditorActions.BaseAction) string {
 	log.Printf("Executing action: %v", action.ToString())
 
-	if action.GetType() == "open_file" {
+	switch action.GetType() {
+	case "open_file":
 		log.Printf("Executing RequestFileAction")
 
 		fileAction, ok := action.(*codeEditorActions.RequestFileAction)
@@ -157,35 +351,337 @@ func (c *CodeEditor) ExecuteAction(action codeEditorActions.BaseAction) string {
 			log.Printf("Error: Failed to convert action to RequestFileAction")
 			return ""
 		}
-		fileContextProvider := services.NewFileContextProvider(fileAction.Path)
+		path, err := c.pathGuard.Resolve(fileAction.Path)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return fmt.Sprintf("open_file %s failed: %v", fileAction.Path, err)
+		}
+		fileContextProvider := services.NewFileContextProvider(path)
+
+		log.Printf("File context provider created with path: %s", path)
+
+		packed := fileContextProvider.GetPackedFileContents(c.newContextPacker(c.contextBudget(0)))
+
+		return withPackReport(packed)
+	case "find_symbol":
+		return c.executeFindSymbol(action.(*codeEditorActions.FindSymbolAction).Name)
+	case "find_references":
+		return c.executeFindReferences(action.(*codeEditorActions.FindReferencesAction).Name)
+	case "read_symbol":
+		readAction := action.(*codeEditorActions.ReadSymbolAction)
+		path := readAction.Path
+		if path != "" {
+			resolved, err := c.pathGuard.Resolve(path)
+			if err != nil {
+				return fmt.Sprintf("read_symbol %s failed: %v", readAction.Name, err)
+			}
+			path = resolved
+		}
+		source, err := c.navigator.ReadSymbol(readAction.Name, path)
+		if err != nil {
+			log.Printf("Error reading symbol: %v", err)
+			return fmt.Sprintf("read_symbol %s failed: %v", readAction.Name, err)
+		}
+		return source
+	case "grep":
+		grepAction := action.(*codeEditorActions.GrepAction)
+		return c.executeGrep(grepAction.Pattern, grepAction.Glob, grepAction.Context)
+	case "list_dir":
+		path := action.(*codeEditorActions.ListDirAction).Path
+		if path == "" {
+			path = "."
+		}
+		resolved, err := c.pathGuard.Resolve(path)
+		if err != nil {
+			return fmt.Sprintf("list_dir %s failed: %v", path, err)
+		}
+		return c.executeListDir(resolved)
+	case "run_command":
+		commandAction := action.(*codeEditorActions.RunCommandAction)
+		dir := commandAction.Dir
+		if dir != "" {
+			resolved, err := c.pathGuard.Resolve(dir)
+			if err != nil {
+				return fmt.Sprintf("run_command %s failed: %v", commandAction.Command, err)
+			}
+			dir = resolved
+		}
+		return c.executeRunCommand(commandAction.Command, dir)
+	}
 
-		log.Printf("File context provider created with path: %s", fileAction.Path)
+	return ""
+}
 
-		fileContents := fileContextProvider.GetFileContents()
+// executeGrep lists the lines matching pattern, with context lines around them
+func (c *CodeEditor) executeGrep(pattern, glob string, context int) string {
+	result, err := c.explorer.Grep(pattern, glob, context)
+	if err != nil {
+		log.Printf("Error searching files: %v", err)
+		return fmt.Sprintf("grep %s failed: %v", pattern, err)
+	}
+	if result.Total == 0 {
+		return fmt.Sprintf("No matches for %s", pattern)
+	}
 
-		return fileContents
+	// Same layout as grep: "path:line:" for matches and "path-line-" for context
+	var output strings.Builder
+	fmt.Fprintf(&output, "Matches for %s:\n", pattern)
+	for i, match := range result.Matches {
+		if i > 0 && context > 0 {
+			output.WriteString("--\n")
+		}
+		for j, line := range match.Before {
+			fmt.Fprintf(&output, "%s-%d- %s\n", match.Path, match.Line-len(match.Before)+j, line)
+		}
+		fmt.Fprintf(&output, "%s:%d: %s\n", match.Path, match.Line, match.Text)
+		for j, line := range match.After {
+			fmt.Fprintf(&output, "%s-%d- %s\n", match.Path, match.Line+1+j, line)
+		}
+	}
+	if result.Total > len(result.Matches) {
+		fmt.Fprintf(&output, "... %d more matches, narrow the pattern or glob\n", result.Total-len(result.Matches))
 	}
+	return output.String()
+}
 
-	return ""
+// executeListDir lists a directory, directories first and marked with a slash
+func (c *CodeEditor) executeListDir(path string) string {
+	listing, err := c.explorer.ListDir(path)
+	if err != nil {
+		log.Printf("Error listing directory: %v", err)
+		return fmt.Sprintf("list_dir %s failed: %v", path, err)
+	}
+	if listing.Total == 0 {
+		return fmt.Sprintf("Directory %s is empty", listing.Path)
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "Contents of %s:\n", listing.Path)
+	for _, entry := range listing.Entries {
+		if entry.IsDir {
+			fmt.Fprintf(&output, "%s/\n", entry.Name)
+		} else {
+			fmt.Fprintf(&output, "%s (%d bytes)\n", entry.Name, entry.Size)
+		}
+	}
+	if listing.Total > len(listing.Entries) {
+		fmt.Fprintf(&output, "... %d more entries\n", listing.Total-len(listing.Entries))
+	}
+	return output.String()
+}
+
+// executeFindSymbol lists the definitions of name
+func (c *CodeEditor) executeFindSymbol(name string) string {
+	definitions, err := c.navigator.FindSymbol(name)
+	if err != nil {
+		log.Printf("Error finding symbol: %v", err)
+		return fmt.Sprintf("find_symbol %s failed: %v", name, err)
+	}
+	if len(definitions) == 0 {
+		return fmt.Sprintf("No definitions of %s found", name)
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Definitions of %s:\n", name)
+	for _, definition := range definitions {
+		fmt.Fprintf(&result, "%s:%d-%d %s: %s\n", definition.Path, definition.StartLine, definition.EndLine, definition.Kind, definition.Signature)
+	}
+	return result.String()
+}
+
+// executeFindReferences lists the lines that use name
+func (c *CodeEditor) executeFindReferences(name string) string {
+	references, total, err := c.navigator.FindReferences(name)
+	if err != nil {
+		log.Printf("Error finding references: %v", err)
+		return fmt.Sprintf("find_references %s failed: %v", name, err)
+	}
+	if total == 0 {
+		return fmt.Sprintf("No references to %s found", name)
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "References to %s:\n", name)
+	for _, reference := range references {
+		fmt.Fprintf(&result, "%s:%d: %s\n", reference.Path, reference.Line, reference.Text)
+	}
+	if total > len(references) {
+		fmt.Fprintf(&result, "... %d more references\n", total-len(references))
+	}
+	return result.String()
+}
+
+// executeRunCommand runs a command and reports its exit code and output
+func (c *CodeEditor) executeRunCommand(command, dir string) string {
+	result, err := c.commandRunner.Run(command, dir)
+	if err != nil {
+		log.Printf("Error running command: %v", err)
+		return fmt.Sprintf("run_command %s failed: %v", command, err)
+	}
+
+	var output strings.Builder
+	switch {
+	case result.TimedOut:
+		fmt.Fprintf(&output, "Command %s timed out\n", command)
+	case result.ExitCode != 0:
+		fmt.Fprintf(&output, "Command %s exited with code %d\n", command, result.ExitCode)
+	default:
+		fmt.Fprintf(&output, "Command %s succeeded\n", command)
+	}
+	if result.Output != "" {
+		output.WriteString("Output:\n" + result.Output)
+	}
+	return output.String()
+}
+
+// confirmCommand asks the user whether a command outside the allowlist may run
+func confirmCommand(command string) bool {
+	fmt.Printf("The model wants to run a command that is not allowlisted:\n  %s\nRun it? [y/N] ", command)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// gatherContext executes the actions, packing the contents of all requested files
+// together so the most relevant ones win when they do not all fit in the budget
+func (c *CodeEditor) gatherContext(actions []codeEditorActions.BaseAction, budget int) string {
+	var contents string = ""
+	paths := make([]string, 0)
+
+	for _, action := range actions {
+		if fileAction, ok := action.(*codeEditorActions.RequestFileAction); ok {
+			path, err := c.pathGuard.Resolve(fileAction.Path)
+			if err != nil {
+				log.Printf("Error: %v", err)
+				contents += fmt.Sprintf("\n\nopen_file %s failed: %v", fileAction.Path, err)
+				continue
+			}
+			paths = append(paths, path)
+			continue
+		}
+
+		result := c.ExecuteAction(action)
+		contents += "\n\n" + result
+		budget -= ollama.EstimateTokens(result)
+	}
+
+	if len(paths) > 0 {
+		fileContextProvider := services.NewFileContextProvider(paths)
+		contents += "\n\n" + withPackReport(fileContextProvider.GetPackedFileContents(c.newContextPacker(budget)))
+	}
+
+	return contents
+}
+
+// sourceContext packs the items of every context source for task into budget.
+// Each source scores relevance its own way, so the scores are normalized per
+// source before the items compete for the budget.
+func (c *CodeEditor) sourceContext(task string, budget int) string {
+	items := make([]services.ContextItem, 0)
+	for _, source := range c.contextSources {
+		sourceItems, err := source.ContextItems(task)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		for _, item := range services.NormalizeRelevance(sourceItems) {
+			// Code from files on the deny list is left out wherever it was found
+			if item.Kind != services.ContextDiff && item.Kind != services.ContextHistory {
+				if _, err := c.pathGuard.Resolve(item.Path); err != nil {
+					log.Printf("Warning: %v", err)
+					continue
+				}
+			}
+			items = append(items, item)
+		}
+	}
+
+	if len(items) == 0 {
+		return ""
+	}
+
+	return "\n\n" + withPackReport(c.newContextPacker(budget).Pack(items))
+}
+
+// relevantHistory packs the recent commit messages touching the files the
+// model asked for into budget, once it is known which files matter
+func (c *CodeEditor) relevantHistory(actions []codeEditorActions.BaseAction, budget int) string {
+	if c.gitContext == nil {
+		return ""
+	}
+
+	files := make([]string, 0, len(actions))
+	for _, action := range actions {
+		path := ""
+		switch typed := action.(type) {
+		case *codeEditorActions.RequestFileAction:
+			path = typed.Path
+		case *codeEditorActions.ReadSymbolAction:
+			path = typed.Path
+		}
+		if path == "" || filepath.IsAbs(path) {
+			continue
+		}
+		if _, err := c.pathGuard.Resolve(path); err == nil {
+			files = append(files, filepath.Clean(path))
+		}
+	}
+
+	c.gitContext.SetRelevantFiles(files)
+	items := c.gitContext.RelevantHistoryItems()
+	if len(items) == 0 {
+		return ""
+	}
+
+	return "\n\n" + withPackReport(c.newContextPacker(budget).Pack(items))
+}
+
+// contextBudget returns the tokens available for file contents once the reply
+// reserve and usedTokens of conversation are taken out of the context window
+func (c *CodeEditor) contextBudget(usedTokens int) int {
+	return services.ContextBudget(c.numCtx, c.reservedTokens+usedTokens)
+}
+
+// newContextPacker creates a packer that falls back to a file's function
+// signatures when the whole file does not fit
+func (c *CodeEditor) newContextPacker(budget int) *services.ContextPacker {
+	packer := services.NewContextPacker(budget)
+	packer.SetSummarizer(func(item services.ContextItem) (string, bool) {
+		signatures := c.directoryTree.GetTree(item.Path)
+		return signatures, signatures != ""
+	})
+	return packer
+}
+
+// withPackReport appends the packing report to the packed text so the model knows
+// which files were shortened or left out
+func withPackReport(packed *services.PackedContext) string {
+	if report := packed.Report(); report != "" {
+		return packed.Text + "\n" + report
+	}
+	return packed.Text
 }
 
-func (c *CodeEditor) ExecuteEditFileAction(actions []codeEditorActions.BaseAction) {
+func (c *CodeEditor) ExecuteEditFileAction(actions []codeEditorActions.BaseAction) error {
 	if len(actions) == 0 {
 		log.Printf("Warning: No actions to execute")
-		return
+		return nil
 	}
 
 	// Ensure all actions are for the same file
 	firstPath := actions[0].(*codeEditorActions.EditFileAction).Path
 	for _, action := range actions {
 		if action.GetType() != "edit_file" {
-			log.Printf("Error: All actions must be edit_file actions")
-			return
+			return fmt.Errorf("all actions must be edit_file actions")
 		}
 		editAction := action.(*codeEditorActions.EditFileAction)
 		if editAction.Path != firstPath {
-			log.Printf("Error: All actions must be for the same file")
-			return
+			return fmt.Errorf("all actions must be for the same file")
 		}
 	}
 
@@ -196,5 +692,5 @@ func (c *CodeEditor) ExecuteEditFileAction(actions []codeEditorActions.BaseActio
 		editFileActions[i] = *action.(*codeEditorActions.EditFileAction)
 	}
 
-	services.EditFile(firstPath, editFileActions)
+	return services.EditFile(firstPath, editFileActions)
 }
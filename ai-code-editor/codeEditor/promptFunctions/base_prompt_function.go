@@ -3,23 +3,41 @@ package promptFunctions
 import (
 	"ai-code-editor/config"
 	"ai-code-editor/ollama"
+	"ai-code-editor/services"
 )
 
 type BasePromptFunction struct {
-	Client *ollama.Client
-	Model  string
+	Client        *ollama.Client
+	Model         string
+	NumCtx        int
+	ReserveTokens int // part of NumCtx kept free for the reply
 }
 
 func NewBasePromptFunction(model string, config *config.Config) *BasePromptFunction {
 	client := ollama.NewClient(config.OllamaBaseURL, true)
+	client.SetResponseReserve(config.ResponseReserveTokens)
 
 	return &BasePromptFunction{
-		Client: client,
-		Model:  model,
+		Client:        client,
+		Model:         model,
+		NumCtx:        config.NumCtx,
+		ReserveTokens: config.ResponseReserveTokens,
 	}
 }
 
+// contextBudget returns the tokens of the context window left for content
+// added to prompt once prompt itself and the reply reserve are taken out
+func (b *BasePromptFunction) contextBudget(prompt string) int {
+	return services.ContextBudget(b.NumCtx, b.ReserveTokens+ollama.EstimateTokens(prompt))
+}
+
 func (b *BasePromptFunction) ExecutePrompt(prompt string) (string, error) {
+	return b.ExecutePromptWithFormat(prompt, nil)
+}
+
+// ExecutePromptWithFormat sends the prompt and asks the model to answer in the
+// given format, either "json" or a JSON schema object. A nil format means free text.
+func (b *BasePromptFunction) ExecutePromptWithFormat(prompt string, format any) (string, error) {
 	req := ollama.ChatRequest{
 		Model:  b.Model,
 		Stream: false,
@@ -31,5 +49,13 @@ func (b *BasePromptFunction) ExecutePrompt(prompt string) (string, error) {
 		},
 	}
 
+	if format != nil {
+		req.WithFormat(format)
+	}
+
+	if b.NumCtx > 0 {
+		req.WithNumCtx(b.NumCtx)
+	}
+
 	return b.Client.ChatCompletion(req)
 }
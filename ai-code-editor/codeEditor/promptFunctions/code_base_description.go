@@ -8,27 +8,35 @@ import (
 )
 
 type CodeBaseDescription struct {
-	Path            string
-	Description     string
-	ProjectTreeJson string
+	Path        string
+	Task        string
+	Description string
+	RepoMap     string
 	*BasePromptFunction
 }
 
-func NewCodeBaseDescription(path string, model string, config *config.Config) *CodeBaseDescription {
-	projectTreeJson := services.NewDirectoryTree(
-		"    ", // indent
-		10,     // maxDepth
-		[]string{ // skipPaths
-			"node_modules",
-			"vendor",
-			".git",
-		},
-	).GetDirectoryString(path)
+// NewCodeBaseDescription describes the codebase at path using a repository map
+// ranked towards the files related to task
+func NewCodeBaseDescription(path string, task string, model string, config *config.Config) *CodeBaseDescription {
+	repoMap := ""
+
+	builtMap, err := services.BuildRepoMap(path, config.MaxFileSizeBytes, []string{ // skipPaths
+		"node_modules",
+		"vendor",
+		".git",
+	})
+	if err != nil {
+		log.Printf("Error building repository map: %v", err)
+	} else {
+		repoMap = builtMap.Render(task, nil, config.RepoMapTokens)
+		builtMap.Close()
+	}
 
 	return &CodeBaseDescription{
 		Path:               path,
+		Task:               task,
 		Description:        "",
-		ProjectTreeJson:    projectTreeJson,
+		RepoMap:            repoMap,
 		BasePromptFunction: NewBasePromptFunction(model, config),
 	}
 }
@@ -37,9 +45,9 @@ func (c *CodeBaseDescription) GetDescription() string {
 	prompt := fmt.Sprintf(`
 Describe the codebase in a few sentences, keep it short and concise, give a short list of important files and directories. Identify things like main entry points, and programming languages used. The codebase is located at
 	%s. 
-Here is the directory structure: 
-	%s 
-	`, c.Path, c.ProjectTreeJson)
+Here is a map of the most important files, ranked by how much the rest of the code uses them, with their main declarations: 
+%s 
+	`, c.Path, c.RepoMap)
 
 	response, err := c.ExecutePrompt(prompt)
 	if err != nil {
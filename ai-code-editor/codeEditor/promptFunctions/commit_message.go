@@ -0,0 +1,218 @@
+package promptFunctions
+
+import (
+	codeEditorSchemas "ai-code-editor/codeEditor/promptFunctions/schemas"
+	"ai-code-editor/config"
+	"ai-code-editor/ollama"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxCommitSubjectLength is the longest subject line kept
+const maxCommitSubjectLength = 72
+
+type CommitMessage struct {
+	*BasePromptFunction
+}
+
+type conventionalCommitResponse struct {
+	Type     string `json:"type"`
+	Scope    string `json:"scope"`
+	Breaking bool   `json:"breaking"`
+	Summary  string `json:"summary"`
+	Body     string `json:"body"`
+}
+
+func NewCommitMessage(model string, config *config.Config) *CommitMessage {
+	return &CommitMessage{
+		BasePromptFunction: NewBasePromptFunction(model, config),
+	}
+}
+
+// Generate writes a commit message for the diff of the edits made for task.
+// The original task is appended to the body so the history records why the
+// change was made. The diff is truncated to what fits the context window.
+func (c *CommitMessage) Generate(task string, diff string) (string, error) {
+	instructions := fmt.Sprintf(`
+Write a git commit message for the change below.
+
+Guidelines:
+1. The first line is a summary in the imperative mood, at most %d characters, without a trailing period
+2. Leave a blank line after the summary, then explain what changed and why in a few short lines
+3. Describe the change itself, do not mention that it was generated
+4. Respond with the commit message only, without quotes or code fences
+
+The change was made for this task: %s
+
+Diff:
+`, maxCommitSubjectLength, task)
+	prompt := instructions + truncateToTokens(diff, c.contextBudget(instructions)) + "\n"
+
+	response, err := c.ExecutePrompt(prompt)
+	if err != nil {
+		return "", fmt.Errorf("error generating commit message: %w", err)
+	}
+
+	message := cleanCommitMessage(response)
+	if message == "" {
+		return "", fmt.Errorf("the model returned an empty commit message")
+	}
+
+	return message + "\n\nTask: " + strings.TrimSpace(task) + "\n", nil
+}
+
+// GenerateConventional writes a conventional commit message, such as
+// "fix(services): handle empty diffs", for a staged diff. The repository map
+// tells the model what the changed code belongs to, and the diff is truncated
+// to what is left of the context window.
+func (c *CommitMessage) GenerateConventional(diff string, repoMap string) (string, error) {
+	instructions := fmt.Sprintf(`
+Describe the staged change below as a conventional commit.
+
+Guidelines:
+1. Pick the type that fits best: %s
+2. The scope is the package or area changed, left empty when the change spans the codebase
+3. The summary says what the change does in the imperative mood, lowercase, without a trailing period
+4. The body explains what changed and why in a few short lines; leave it empty for trivial changes
+5. Only mark the change as breaking when it changes behavior existing users rely on
+
+Map of the repository, ranked towards the changed files:
+%s
+
+Staged diff:
+`, strings.Join(codeEditorSchemas.ConventionalCommitTypes, ", "), repoMap)
+	const closing = "\n\nRespond with JSON.\n"
+	prompt := instructions + truncateToTokens(diff, c.contextBudget(instructions+closing)) + closing
+
+	response, err := c.ExecutePromptWithFormat(prompt, codeEditorSchemas.NewConventionalCommitSchema())
+	if err != nil {
+		return "", fmt.Errorf("error generating commit message: %w", err)
+	}
+
+	var parsed conventionalCommitResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return "", fmt.Errorf("error parsing commit message: %w", err)
+	}
+
+	return formatConventionalCommit(parsed)
+}
+
+// formatConventionalCommit renders "type(scope)!: summary" followed by the body
+func formatConventionalCommit(commit conventionalCommitResponse) (string, error) {
+	commitType := strings.ToLower(strings.TrimSpace(commit.Type))
+	if !slices.Contains(codeEditorSchemas.ConventionalCommitTypes, commitType) {
+		commitType = "chore"
+	}
+
+	summary := strings.TrimSuffix(strings.TrimSpace(firstLine(commit.Summary)), ".")
+	if summary == "" {
+		return "", fmt.Errorf("the model returned an empty commit summary")
+	}
+
+	header := commitType
+	if scope := strings.TrimSpace(commit.Scope); scope != "" {
+		header += "(" + strings.ToLower(strings.ReplaceAll(scope, " ", "-")) + ")"
+	}
+	if commit.Breaking {
+		header += "!"
+	}
+
+	message := truncateSubject(header + ": " + summary)
+	if body := strings.TrimSpace(commit.Body); body != "" {
+		message += "\n\n" + body
+	}
+	if commit.Breaking {
+		message += "\n\nBREAKING CHANGE: " + summary
+	}
+
+	return message + "\n", nil
+}
+
+// FallbackCommitMessage is used when no message could be generated
+func FallbackCommitMessage(task string) string {
+	return truncateSubject("AI edit: "+firstLine(task)) + "\n\nTask: " + strings.TrimSpace(task) + "\n"
+}
+
+// cleanCommitMessage strips code fences and surrounding quotes from a model
+// reply and shortens its subject line
+func cleanCommitMessage(response string) string {
+	lines := strings.Split(strings.TrimSpace(response), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+
+	message := strings.Trim(strings.TrimSpace(strings.Join(kept, "\n")), "\"'`")
+	if message == "" {
+		return ""
+	}
+
+	subject, body, _ := strings.Cut(message, "\n")
+	subject = truncateSubject(strings.TrimSuffix(strings.TrimSpace(subject), "."))
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return subject
+	}
+	return subject + "\n\n" + body
+}
+
+// truncateSubject shortens a subject line to maxCommitSubjectLength at a word boundary
+func truncateSubject(subject string) string {
+	if len(subject) <= maxCommitSubjectLength {
+		return subject
+	}
+
+	cut := subject[:maxCommitSubjectLength]
+	if space := strings.LastIndex(cut, " "); space > maxCommitSubjectLength/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " .,;:")
+}
+
+// truncateDiff keeps the start of a long diff and notes how much was left out
+func truncateDiff(diff string, maxChars int) string {
+	if len(diff) <= maxChars {
+		return diff
+	}
+	return diff[:maxChars] + fmt.Sprintf("\n... diff truncated, %d more characters", len(diff)-maxChars)
+}
+
+// truncateToTokens keeps the longest run of leading whole lines of text that
+// fits in maxTokens, noting how many lines were left out
+func truncateToTokens(text string, maxTokens int) string {
+	if ollama.EstimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	low, high := 0, len(lines)-1
+	for low < high {
+		mid := (low + high + 1) / 2
+		if ollama.EstimateTokens(truncatedLines(lines, mid)) <= maxTokens {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	return truncatedLines(lines, low)
+}
+
+func truncatedLines(lines []string, keep int) string {
+	return strings.Join(lines[:keep], "\n") + fmt.Sprintf("\n... truncated, %d more lines", len(lines)-keep)
+}
+
+// firstLine returns the first non-blank line of text
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
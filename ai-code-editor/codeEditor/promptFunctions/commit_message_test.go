@@ -0,0 +1,83 @@
+package promptFunctions
+
+import (
+	"ai-code-editor/config"
+	"ai-code-editor/ollama"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newPromptServer answers every chat request with reply and records the prompts
+func newPromptServer(t *testing.T, reply string, prompts *[]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var request struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		*prompts = append(*prompts, request.Messages[len(request.Messages)-1].Content)
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": map[string]any{"role": "assistant", "content": reply},
+			"done":    true,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	text := "short line\nanother line\n"
+	if truncated := truncateToTokens(text, 1000); truncated != text {
+		t.Errorf("Expected text that fits to be kept, got %q", truncated)
+	}
+
+	lines := make([]string, 500)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("+\tresult%d := process(input%d)", i, i)
+	}
+	truncated := truncateToTokens(strings.Join(lines, "\n"), 200)
+	if tokens := ollama.EstimateTokens(truncated); tokens > 200 {
+		t.Errorf("Expected at most 200 tokens, got %d", tokens)
+	}
+	if !strings.HasPrefix(truncated, lines[0]+"\n") || !strings.Contains(truncated, "more lines") {
+		t.Errorf("Expected the first lines and a truncation note, got %q", truncated)
+	}
+}
+
+func TestPromptsFitContextWindow(t *testing.T) {
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("+\tresult%d := process(input%d)", i, i)
+	}
+	large := strings.Join(lines, "\n")
+
+	var prompts []string
+	server := newPromptServer(t, "Add processing", &prompts)
+	cfg := &config.Config{OllamaBaseURL: server.URL, NumCtx: 2048, ResponseReserveTokens: 256}
+
+	if _, err := NewCommitMessage("test", cfg).Generate("process input", large); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, err := NewPullRequestDescription("test", cfg).Describe("main", "feature", large, large, large, "map"); err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+
+	for _, prompt := range prompts {
+		if tokens := ollama.EstimateTokens(prompt); tokens > cfg.NumCtx-cfg.ResponseReserveTokens {
+			t.Errorf("Expected the prompt to leave room for the reply, got %d tokens", tokens)
+		}
+	}
+}
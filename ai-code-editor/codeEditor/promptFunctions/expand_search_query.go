@@ -0,0 +1,78 @@
+package promptFunctions
+
+import (
+	codeEditorSchemas "ai-code-editor/codeEditor/promptFunctions/schemas"
+	"ai-code-editor/config"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type ExpandSearchQuery struct {
+	QueryCount int
+	*BasePromptFunction
+}
+
+type searchQueriesResponse struct {
+	Queries []string `json:"queries"`
+}
+
+func NewExpandSearchQuery(model string, config *config.Config, queryCount int) *ExpandSearchQuery {
+	if queryCount <= 0 {
+		queryCount = 4
+	}
+
+	return &ExpandSearchQuery{
+		QueryCount:         queryCount,
+		BasePromptFunction: NewBasePromptFunction(model, config),
+	}
+}
+
+// ExpandQuery rewrites a user task into several targeted code search queries.
+// The original query is not included in the result.
+func (e *ExpandSearchQuery) ExpandQuery(query string) ([]string, error) {
+	prompt := fmt.Sprintf(`
+Given this task or question about a codebase, generate %d search queries that would find the code needed to work on it.
+
+Guidelines for the search queries:
+1. Include likely identifiers: function, method, type and variable names in the casing the code would use
+2. Include the concepts involved, phrased the way a code comment or doc string would describe them
+3. Include guesses at file names that would contain the relevant code
+4. Each query should target a different part of the problem
+5. Avoid generic queries that would match most of the codebase
+
+DO NOT EXCEED THE COUNT REQUESTED: %d
+Respond with JSON in the form {"queries": ["query", "query"]}
+
+Original Query: %s
+`, e.QueryCount, e.QueryCount, query)
+
+	response, err := e.ExecutePromptWithFormat(prompt, codeEditorSchemas.NewSearchQueriesSchema())
+	if err != nil {
+		return nil, fmt.Errorf("error expanding search query: %w", err)
+	}
+
+	var parsed searchQueriesResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing expanded queries: %w", err)
+	}
+
+	// Drop blanks, duplicates and echoes of the original query
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	queries := make([]string, 0, len(parsed.Queries))
+	for _, expanded := range parsed.Queries {
+		trimmed := strings.TrimSpace(expanded)
+		key := strings.ToLower(trimmed)
+		if trimmed == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		queries = append(queries, trimmed)
+		if len(queries) == e.QueryCount {
+			break
+		}
+	}
+
+	return queries, nil
+}
@@ -0,0 +1,53 @@
+package promptFunctions
+
+import (
+	"ai-code-editor/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newChatServer answers every chat request with reply as the assistant message
+func newChatServer(t *testing.T, reply string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": map[string]any{"role": "assistant", "content": reply},
+			"done":    true,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExpandSearchQuery_ExpandQuery(t *testing.T) {
+	reply := `{"queries": ["  LoadInventory ", "loadinventory", "", "Where is inventory loaded", "inventory.go", "parse stock file", "one too many"]}`
+	server := newChatServer(t, reply)
+
+	expander := NewExpandSearchQuery("test", &config.Config{OllamaBaseURL: server.URL, NumCtx: 4096}, 3)
+	queries, err := expander.ExpandQuery("where is inventory loaded")
+	if err != nil {
+		t.Fatalf("ExpandQuery failed: %v", err)
+	}
+
+	// Blanks, duplicates and the original query are dropped and the count is respected
+	if expected := []string{"LoadInventory", "inventory.go", "parse stock file"}; !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Expected %v, got %v", expected, queries)
+	}
+}
+
+func TestExpandSearchQuery_MalformedReply(t *testing.T) {
+	server := newChatServer(t, `{"queries": "LoadInventory"`)
+
+	expander := NewExpandSearchQuery("test", &config.Config{OllamaBaseURL: server.URL, NumCtx: 4096}, 3)
+	if queries, err := expander.ExpandQuery("where is inventory loaded"); err == nil {
+		t.Errorf("Expected an error for a malformed reply, got %v", queries)
+	}
+}
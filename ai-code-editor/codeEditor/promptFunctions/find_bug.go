@@ -0,0 +1,95 @@
+package promptFunctions
+
+import (
+	codeEditorSchemas "ai-code-editor/codeEditor/promptFunctions/schemas"
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// maxFindBugCodeChars bounds how much code is analyzed at once
+const maxFindBugCodeChars = 24000
+
+type FindBug struct {
+	*BasePromptFunction
+}
+
+type bugFindingsResponse struct {
+	Bugs []json.RawMessage `json:"bugs"`
+}
+
+func NewFindBug(model string, config *config.Config) *FindBug {
+	return &FindBug{
+		BasePromptFunction: NewBasePromptFunction(model, config),
+	}
+}
+
+// FindBugs looks for bugs in a piece of code. The additional context says what
+// the user is concerned about or what the code is. Findings that do not match
+// the schema are dropped and line numbers are kept inside the unit.
+func (f *FindBug) FindBugs(unit services.CodeUnit, additionalContext string) ([]services.BugFinding, error) {
+	code := unit.NumberedCode()
+	if len(code) > maxFindBugCodeChars {
+		log.Printf("Warning: analyzing only the start of %s", unit.Location())
+		code = code[:maxFindBugCodeChars] + "\n... [truncated]"
+	}
+
+	prompt := fmt.Sprintf(`
+You are a senior software developer. Find the bugs in the code below.
+
+Guidelines:
+1. Report real bugs: wrong results, crashes, resource leaks, race conditions, unhandled errors, security problems and broken edge cases
+2. Do not report style, naming or missing documentation
+3. Use the line numbers shown before each line of code
+4. Give a confidence from 0 to 1; report only bugs with a confidence of at least 0.3
+5. Suggest a patch as the corrected code for the reported lines when you can
+6. Return an empty list when you find no bugs
+
+Additional context:
+%s
+
+Code from %s:
+%s
+
+Respond with JSON.
+`, additionalContext, unit.Location(), code)
+
+	schema := codeEditorSchemas.NewBugFindingsSchema()
+
+	response, err := f.ExecutePromptWithFormat(prompt, schema)
+	if err != nil {
+		return nil, fmt.Errorf("error finding bugs in %s: %w", unit.Location(), err)
+	}
+
+	var parsed bugFindingsResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing bugs found in %s: %w", unit.Location(), err)
+	}
+
+	// Each finding is validated on its own so one malformed finding does not lose the rest
+	findings := make([]services.BugFinding, 0, len(parsed.Bugs))
+	for i, raw := range parsed.Bugs {
+		if err := codeEditorSchemas.Validate(schema.Properties.Bugs.Items, raw); err != nil {
+			log.Printf("Warning: dropping bug %d in %s: %v", i, unit.Location(), err)
+			continue
+		}
+
+		var finding services.BugFinding
+		if err := json.Unmarshal(raw, &finding); err != nil {
+			log.Printf("Warning: dropping bug %d in %s: %v", i, unit.Location(), err)
+			continue
+		}
+
+		finding.File = unit.Path
+		finding.Symbol = unit.Name
+		finding.Explanation = strings.TrimSpace(finding.Explanation)
+		finding.StartLine = min(max(finding.StartLine, unit.StartLine), unit.EndLine)
+		finding.EndLine = min(max(finding.EndLine, finding.StartLine), unit.EndLine)
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
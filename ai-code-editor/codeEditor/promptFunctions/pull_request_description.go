@@ -0,0 +1,74 @@
+package promptFunctions
+
+import (
+	"ai-code-editor/config"
+	"ai-code-editor/ollama"
+	"fmt"
+	"strings"
+)
+
+const (
+	// pullRequestCommitsShare is the largest part of the context budget given
+	// to the commit log, and pullRequestStatShare the part given to the list
+	// of changed files; the diff gets the rest
+	pullRequestCommitsShare = 4
+	pullRequestStatShare    = 8
+)
+
+type PullRequestDescription struct {
+	*BasePromptFunction
+}
+
+func NewPullRequestDescription(model string, config *config.Config) *PullRequestDescription {
+	return &PullRequestDescription{
+		BasePromptFunction: NewBasePromptFunction(model, config),
+	}
+}
+
+// Describe writes a pull request title and description in Markdown for the
+// commits of a branch. The diff stat lists the files changed against the base
+// and the diff shows the changes themselves. The commits, the diff stat and
+// the diff are truncated to share what is left of the context window.
+func (p *PullRequestDescription) Describe(base, head, commits, diffStat, diff, repoMap string) (string, error) {
+	instructions := fmt.Sprintf(`
+Write a pull request description for merging %s into %s.
+
+Guidelines:
+1. The first line is a short title in the imperative mood, without a trailing period or Markdown
+2. Leave a blank line, then open with one or two sentences saying what the change does and why
+3. Follow with a short bulleted list of the notable changes, grouped by area when there are many
+4. Mention anything reviewers should check closely, such as behavior changes or migrations
+5. Do not list every file, do not invent testing that is not visible in the commits
+6. Respond with the title and description only, without code fences around them
+
+Map of the repository, ranked towards the changed files:
+%s
+
+Commits, oldest first:
+`, head, base, repoMap)
+	const statHeading, diffHeading = "\n\nFiles changed:\n", "\n\nDiff:\n"
+
+	budget := p.contextBudget(instructions + statHeading + diffHeading)
+	commits = truncateToTokens(commits, budget/pullRequestCommitsShare)
+	diffStat = truncateToTokens(diffStat, budget/pullRequestStatShare)
+	diff = truncateToTokens(diff, budget-ollama.EstimateTokens(commits)-ollama.EstimateTokens(diffStat))
+
+	prompt := instructions + commits + statHeading + diffStat + diffHeading + diff + "\n"
+
+	response, err := p.ExecutePrompt(prompt)
+	if err != nil {
+		return "", fmt.Errorf("error generating pull request description: %w", err)
+	}
+
+	description := strings.TrimSpace(response)
+	if description == "" {
+		return "", fmt.Errorf("the model returned an empty pull request description")
+	}
+
+	title, body, _ := strings.Cut(description, "\n")
+	title = truncateSubject(strings.TrimSuffix(strings.TrimSpace(strings.TrimLeft(title, "# ")), "."))
+	if body = strings.TrimSpace(body); body == "" {
+		return title + "\n", nil
+	}
+	return title + "\n\n" + body + "\n", nil
+}
@@ -0,0 +1,116 @@
+package promptFunctions
+
+import (
+	codeEditorSchemas "ai-code-editor/codeEditor/promptFunctions/schemas"
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// maxRerankChunkChars limits how much of each chunk is shown to the reranking model
+	maxRerankChunkChars = 4000
+	// unscoredRerankScore is the neutral score of results the model fails to score
+	unscoredRerankScore = 0.5
+)
+
+type RerankCodeChunks struct {
+	*BasePromptFunction
+	workers int
+}
+
+type relevanceScoreResponse struct {
+	Score float64 `json:"score"`
+}
+
+func NewRerankCodeChunks(model string, config *config.Config) *RerankCodeChunks {
+	return &RerankCodeChunks{
+		BasePromptFunction: NewBasePromptFunction(model, config),
+		workers:            max(config.RerankWorkers, 1),
+	}
+}
+
+// Rerank scores each result against the query with the model, several results
+// at a time, and returns the results ordered by that score. Scores are
+// normalized to the range 0 to 1. Results the model fails to score get a
+// neutral score and are not marked as reranked, so no threshold drops them.
+func (r *RerankCodeChunks) Rerank(query string, results []services.SearchResult) ([]services.SearchResult, error) {
+	reranked := make([]services.SearchResult, len(results))
+	copy(reranked, results)
+
+	jobs := make(chan int)
+	var failures atomic.Int32
+
+	var wg sync.WaitGroup
+	for range min(r.workers, len(reranked)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				score, err := r.scoreChunk(query, reranked[i])
+				if err != nil {
+					log.Printf("Warning: failed to rerank %s: %v", reranked[i].Path, err)
+					failures.Add(1)
+					reranked[i].Score = unscoredRerankScore
+					continue
+				}
+				reranked[i].Score = score
+				reranked[i].Reranked = true
+			}
+		}()
+	}
+
+	for i := range reranked {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed := int(failures.Load()); failed == len(results) && failed > 0 {
+		return nil, fmt.Errorf("failed to score any of %d results", failed)
+	}
+
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+
+	return reranked, nil
+}
+
+func (r *RerankCodeChunks) scoreChunk(query string, result services.SearchResult) (float64, error) {
+	code := result.Content
+	if len(code) > maxRerankChunkChars {
+		code = code[:maxRerankChunkChars]
+	}
+
+	prompt := fmt.Sprintf(`
+You are judging search results for a developer.
+
+Task:
+%s
+
+Code from %s (lines %d-%d):
+%s
+
+How relevant is this code to the task? Answer with a score from 0 to 10, where
+0 means unrelated, 5 means useful background and 10 means the task cannot be done without it.
+Respond with JSON in the form {"score": 7}
+`, query, result.Path, result.StartLine, result.EndLine, code)
+
+	response, err := r.ExecutePromptWithFormat(prompt, codeEditorSchemas.NewRelevanceScoreSchema())
+	if err != nil {
+		return 0, err
+	}
+
+	var parsed relevanceScoreResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return 0, fmt.Errorf("error parsing relevance score: %w", err)
+	}
+
+	return min(max(parsed.Score, 0), 10) / 10, nil
+}
@@ -0,0 +1,70 @@
+package promptFunctions
+
+import (
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRerankCodeChunks_Rerank(t *testing.T) {
+	// a.go and b.go are scored, c.go fails and gets the neutral score
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var request struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		prompt := request.Messages[len(request.Messages)-1].Content
+
+		reply := ""
+		switch {
+		case strings.Contains(prompt, "a.go"):
+			reply = `{"score": 9}`
+		case strings.Contains(prompt, "b.go"):
+			reply = `{"score": 1}`
+		default:
+			http.Error(w, "model failed", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": map[string]any{"role": "assistant", "content": reply},
+			"done":    true,
+		})
+	}))
+	defer server.Close()
+
+	reranker := NewRerankCodeChunks("test", &config.Config{OllamaBaseURL: server.URL, NumCtx: 4096, RerankWorkers: 2})
+	results, err := reranker.Rerank("query", []services.SearchResult{
+		{Path: "b.go", Score: 0.03},
+		{Path: "c.go", Score: 0.02},
+		{Path: "a.go", Score: 0.01},
+	})
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+
+	paths := make([]string, len(results))
+	for i, result := range results {
+		paths[i] = result.Path
+		if result.Score < 0 || result.Score > 1 {
+			t.Errorf("Expected a score between 0 and 1 for %s, got %f", result.Path, result.Score)
+		}
+	}
+	if expected := []string{"a.go", "c.go", "b.go"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+	if results[1].Reranked {
+		t.Error("Expected the result the model failed to score not to be marked as reranked")
+	}
+}
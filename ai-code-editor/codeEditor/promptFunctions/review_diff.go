@@ -0,0 +1,95 @@
+package promptFunctions
+
+import (
+	codeEditorSchemas "ai-code-editor/codeEditor/promptFunctions/schemas"
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// maxReviewDiffChars bounds how much of a file's diff is reviewed at once
+const maxReviewDiffChars = 16000
+
+type ReviewDiff struct {
+	*BasePromptFunction
+}
+
+type reviewFindingsResponse struct {
+	Findings []json.RawMessage `json:"findings"`
+}
+
+func NewReviewDiff(model string, config *config.Config) *ReviewDiff {
+	return &ReviewDiff{
+		BasePromptFunction: NewBasePromptFunction(model, config),
+	}
+}
+
+// Review asks the model for the problems introduced by the diff of one file.
+// The context holds related code from the rest of the codebase. Findings that
+// do not match the schema, point at another file or at lines outside the
+// changed hunks are dropped.
+func (r *ReviewDiff) Review(file services.FileDiff, context string) ([]services.ReviewFinding, error) {
+	prompt := fmt.Sprintf(`
+Review the change to %s below like an experienced reviewer of this codebase.
+
+Guidelines:
+1. Report bugs, security problems, race conditions, missing error handling and broken edge cases introduced by the change
+2. Report the line in the changed file, counting from the hunk headers (the +start in "@@ -a,b +start,count @@")
+3. Use error for bugs and security problems, warning for likely problems and info for worthwhile suggestions
+4. Give a suggested fix as replacement code when you can, otherwise a short description
+5. Do not report style preferences, and do not report problems in code the change does not touch
+6. Return an empty list of findings when the change looks correct
+
+Related code from the codebase:
+%s
+
+Diff of %s:
+%s
+
+Respond with JSON.
+`, file.Path, context, file.Path, truncateDiff(file.Diff(), maxReviewDiffChars))
+
+	schema := codeEditorSchemas.NewReviewFindingsSchema(services.ReviewSeverities)
+
+	response, err := r.ExecutePromptWithFormat(prompt, schema)
+	if err != nil {
+		return nil, fmt.Errorf("error reviewing %s: %w", file.Path, err)
+	}
+
+	var parsed reviewFindingsResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing review of %s: %w", file.Path, err)
+	}
+
+	// Each finding is validated on its own so one malformed finding does not lose the rest
+	findings := make([]services.ReviewFinding, 0, len(parsed.Findings))
+	for i, raw := range parsed.Findings {
+		if err := codeEditorSchemas.Validate(schema.Properties.Findings.Items, raw); err != nil {
+			log.Printf("Warning: dropping finding %d for %s: %v", i, file.Path, err)
+			continue
+		}
+
+		var finding services.ReviewFinding
+		if err := json.Unmarshal(raw, &finding); err != nil {
+			log.Printf("Warning: dropping finding %d for %s: %v", i, file.Path, err)
+			continue
+		}
+
+		if strings.TrimPrefix(finding.File, "./") != file.Path && !strings.HasSuffix(finding.File, "/"+file.Path) {
+			log.Printf("Warning: dropping finding for %s in the review of %s", finding.File, file.Path)
+			continue
+		}
+		if !file.Deleted && !file.InHunk(finding.Line) {
+			log.Printf("Warning: dropping finding at %s:%d outside the changed lines", file.Path, finding.Line)
+			continue
+		}
+		finding.File = file.Path
+		finding.Message = strings.TrimSpace(finding.Message)
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
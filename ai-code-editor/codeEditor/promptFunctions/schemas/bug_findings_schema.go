@@ -0,0 +1,60 @@
+package schemas
+
+type BugFindingsSchema struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Bugs struct {
+			Type  string           `json:"type"`
+			Items BugFindingSchema `json:"items"`
+		} `json:"bugs"`
+	} `json:"properties"`
+	Required []string `json:"required"`
+}
+
+// BugFindingSchema describes one bug found in a piece of code
+type BugFindingSchema struct {
+	Type       string              `json:"type"`
+	Properties map[string]Property `json:"properties"`
+	Required   []string            `json:"required"`
+}
+
+func NewBugFindingsSchema() *BugFindingsSchema {
+	minimumLine, minimumConfidence, maximumConfidence := 1.0, 0.0, 1.0
+
+	schema := &BugFindingsSchema{
+		Type:     "object",
+		Required: []string{"bugs"},
+	}
+	schema.Properties.Bugs.Type = "array"
+	schema.Properties.Bugs.Items = BugFindingSchema{
+		Type: "object",
+		Properties: map[string]Property{
+			"start_line": {
+				Type:        "integer",
+				Description: "First line of the bug, using the line numbers shown with the code",
+				Minimum:     &minimumLine,
+			},
+			"end_line": {
+				Type:        "integer",
+				Description: "Last line of the bug",
+				Minimum:     &minimumLine,
+			},
+			"explanation": {
+				Type:        "string",
+				Description: "What goes wrong, when, and why, in a few sentences",
+			},
+			"confidence": {
+				Type:        "number",
+				Description: "How sure you are that this is a real bug, from 0 to 1",
+				Minimum:     &minimumConfidence,
+				Maximum:     &maximumConfidence,
+			},
+			"suggested_patch": {
+				Type:        "string",
+				Description: "Corrected code replacing the lines from start_line to end_line",
+			},
+		},
+		Required: []string{"start_line", "end_line", "explanation", "confidence"},
+	}
+	return schema
+}
@@ -0,0 +1,40 @@
+package schemas
+
+// ConventionalCommitTypes are the commit types a conventional commit may use
+var ConventionalCommitTypes = []string{"feat", "fix", "refactor", "perf", "docs", "test", "build", "ci", "style", "chore", "revert"}
+
+type ConventionalCommitSchema struct {
+	Type       string              `json:"type"`
+	Properties map[string]Property `json:"properties"`
+	Required   []string            `json:"required"`
+}
+
+func NewConventionalCommitSchema() *ConventionalCommitSchema {
+	return &ConventionalCommitSchema{
+		Type: "object",
+		Properties: map[string]Property{
+			"type": {
+				Type:        "string",
+				Description: "The kind of change",
+				Enum:        ConventionalCommitTypes,
+			},
+			"scope": {
+				Type:        "string",
+				Description: "The package or area changed, such as services or config, or empty when the change is broad",
+			},
+			"breaking": {
+				Type:        "boolean",
+				Description: "Whether the change breaks existing users",
+			},
+			"summary": {
+				Type:        "string",
+				Description: "What the change does in the imperative mood, lowercase, without a trailing period",
+			},
+			"body": {
+				Type:        "string",
+				Description: "What changed and why in a few short lines, or empty for trivial changes",
+			},
+		},
+		Required: []string{"type", "summary"},
+	}
+}
@@ -8,8 +8,14 @@ type FileRequestSchema struct {
 			Items struct {
 				Type       string `json:"type"`
 				Properties struct {
-					Type string `json:"type"`
-					Path string `json:"path"`
+					Type    string `json:"type"`
+					Path    string `json:"path"`
+					Name    string `json:"name"`
+					Pattern string `json:"pattern"`
+					Glob    string `json:"glob"`
+					Context int    `json:"context"`
+					Command string `json:"command"`
+					Dir     string `json:"dir"`
 				} `json:"properties"`
 				Required []string `json:"required"`
 			} `json:"items"`
@@ -23,8 +29,14 @@ func NewFileRequestSchema() *FileRequestSchema {
 	}
 	schema.Properties.Actions.Type = "array"
 	schema.Properties.Actions.Items.Type = "object"
-	schema.Properties.Actions.Items.Properties.Type = "open_file"
+	schema.Properties.Actions.Items.Properties.Type = "open_file | find_symbol | find_references | read_symbol | grep | list_dir | run_command"
 	schema.Properties.Actions.Items.Properties.Path = "path/to/file"
-	schema.Properties.Actions.Items.Required = []string{"type", "path"}
+	schema.Properties.Actions.Items.Properties.Name = "SymbolName"
+	schema.Properties.Actions.Items.Properties.Pattern = "regular expression"
+	schema.Properties.Actions.Items.Properties.Glob = "*.go"
+	schema.Properties.Actions.Items.Properties.Context = 2 // lines of context around grep matches
+	schema.Properties.Actions.Items.Properties.Command = "go test ./..."
+	schema.Properties.Actions.Items.Properties.Dir = "path/to/dir"
+	schema.Properties.Actions.Items.Required = []string{"type"}
 	return schema
 }
@@ -0,0 +1,20 @@
+package schemas
+
+type RelevanceScoreSchema struct {
+	Type       string              `json:"type"`
+	Properties map[string]Property `json:"properties"`
+	Required   []string            `json:"required"`
+}
+
+func NewRelevanceScoreSchema() *RelevanceScoreSchema {
+	return &RelevanceScoreSchema{
+		Type: "object",
+		Properties: map[string]Property{
+			"score": {
+				Type:        "integer",
+				Description: "How relevant the code is to the task, from 0 (unrelated) to 10 (essential)",
+			},
+		},
+		Required: []string{"score"},
+	}
+}
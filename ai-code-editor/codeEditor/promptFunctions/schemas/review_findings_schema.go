@@ -0,0 +1,58 @@
+package schemas
+
+type ReviewFindingsSchema struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Findings struct {
+			Type  string              `json:"type"`
+			Items ReviewFindingSchema `json:"items"`
+		} `json:"findings"`
+	} `json:"properties"`
+	Required []string `json:"required"`
+}
+
+// ReviewFindingSchema describes one finding of a review
+type ReviewFindingSchema struct {
+	Type       string              `json:"type"`
+	Properties map[string]Property `json:"properties"`
+	Required   []string            `json:"required"`
+}
+
+func NewReviewFindingsSchema(severities []string) *ReviewFindingsSchema {
+	minimumLine := 1.0
+
+	schema := &ReviewFindingsSchema{
+		Type:     "object",
+		Required: []string{"findings"},
+	}
+	schema.Properties.Findings.Type = "array"
+	schema.Properties.Findings.Items = ReviewFindingSchema{
+		Type: "object",
+		Properties: map[string]Property{
+			"file": {
+				Type:        "string",
+				Description: "Path of the file the finding is in, as shown in the diff",
+			},
+			"line": {
+				Type:        "integer",
+				Description: "Line of the finding in the changed file",
+				Minimum:     &minimumLine,
+			},
+			"severity": {
+				Type:        "string",
+				Description: "error for bugs and security problems, warning for likely problems, info for suggestions",
+				Enum:        severities,
+			},
+			"message": {
+				Type:        "string",
+				Description: "What is wrong and why, in one or two sentences",
+			},
+			"suggested_fix": {
+				Type:        "string",
+				Description: "Replacement code or a short description of the fix",
+			},
+		},
+		Required: []string{"file", "line", "severity", "message"},
+	}
+	return schema
+}
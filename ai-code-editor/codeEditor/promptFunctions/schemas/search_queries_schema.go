@@ -0,0 +1,24 @@
+package schemas
+
+type SearchQueriesSchema struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Queries struct {
+			Type  string `json:"type"`
+			Items struct {
+				Type string `json:"type"`
+			} `json:"items"`
+		} `json:"queries"`
+	} `json:"properties"`
+	Required []string `json:"required"`
+}
+
+func NewSearchQueriesSchema() *SearchQueriesSchema {
+	schema := &SearchQueriesSchema{
+		Type: "object",
+	}
+	schema.Properties.Queries.Type = "array"
+	schema.Properties.Queries.Items.Type = "string"
+	schema.Required = []string{"queries"}
+	return schema
+}
@@ -0,0 +1,161 @@
+package schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// Validate checks a JSON document against a schema. It supports the keywords
+// the schemas in this package use: type, properties, required, items, enum,
+// minimum and maximum. Models do not always follow the format they are
+// given, so replies are validated before they are used.
+func Validate(schema any, document []byte) error {
+	var value any
+	if err := json.Unmarshal(document, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return ValidateValue(schema, value)
+}
+
+// ValidateValue checks a decoded JSON value against a schema
+func ValidateValue(schema any, value any) error {
+	encoded, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return validateValue(decoded, value, "$")
+}
+
+func validateValue(schema map[string]any, value any, path string) error {
+	if schemaType, ok := schema["type"].(string); ok {
+		if err := validateType(schemaType, value, path); err != nil {
+			return err
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+
+	if minimum, ok := schema["minimum"].(float64); ok {
+		if number, isNumber := value.(float64); isNumber && number < minimum {
+			return fmt.Errorf("%s: %v is less than %v", path, number, minimum)
+		}
+	}
+
+	if maximum, ok := schema["maximum"].(float64); ok {
+		if number, isNumber := value.(float64); isNumber && number > maximum {
+			return fmt.Errorf("%s: %v is greater than %v", path, number, maximum)
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		return validateObject(schema, typed, path)
+	case []any:
+		items, ok := schema["items"].(map[string]any)
+		if !ok {
+			return nil
+		}
+		for i, item := range typed {
+			if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateObject(schema map[string]any, object map[string]any, path string) error {
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if key, isString := name.(string); isString {
+				if _, present := object[key]; !present {
+					return fmt.Errorf("%s: missing required property %s", path, key)
+				}
+			}
+		}
+	}
+
+	properties, ok := schema["properties"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	// Sorted so the first error reported is always the same
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		if err := validateValue(property, object[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateType(schemaType string, value any, path string) error {
+	valid := false
+	switch schemaType {
+	case "object":
+		_, valid = value.(map[string]any)
+	case "array":
+		_, valid = value.([]any)
+	case "string":
+		_, valid = value.(string)
+	case "boolean":
+		_, valid = value.(bool)
+	case "number":
+		_, valid = value.(float64)
+	case "integer":
+		number, isNumber := value.(float64)
+		valid = isNumber && number == math.Trunc(number)
+	case "null":
+		valid = value == nil
+	default:
+		valid = true
+	}
+
+	if !valid {
+		return fmt.Errorf("%s: expected %s, got %s", path, schemaType, jsonTypeName(value))
+	}
+	return nil
+}
+
+// jsonTypeName names the JSON type of a decoded value
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
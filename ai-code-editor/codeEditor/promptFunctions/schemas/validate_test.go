@@ -0,0 +1,50 @@
+package schemas
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	schema := NewReviewFindingsSchema([]string{"error", "warning", "info"})
+
+	tests := []struct {
+		document string
+		err      string
+	}{
+		{`{"findings": []}`, ""},
+		{`{"findings": [{"file": "a.go", "line": 3, "severity": "error", "message": "nil map write"}]}`, ""},
+		{`{"findings": [{"file": "a.go", "line": 3, "severity": "error", "message": "m", "suggested_fix": "x"}]}`, ""},
+		{`{}`, "missing required property findings"},
+		{`{"findings": {}}`, "$.findings: expected array, got object"},
+		{`{"findings": [{"file": "a.go", "line": 3, "severity": "error"}]}`, "$.findings[0]: missing required property message"},
+		{`{"findings": [{"file": "a.go", "line": 3.5, "severity": "error", "message": "m"}]}`, "$.findings[0].line: expected integer"},
+		{`{"findings": [{"file": "a.go", "line": 0, "severity": "error", "message": "m"}]}`, "$.findings[0].line: 0 is less than 1"},
+		{`{"findings": [{"file": "a.go", "line": 3, "severity": "critical", "message": "m"}]}`, "$.findings[0].severity: critical is not one of"},
+		{`{"findings": [`, "invalid JSON"},
+	}
+
+	for _, tt := range tests {
+		err := Validate(schema, []byte(tt.document))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("Validate(%s) failed: %v", tt.document, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("Validate(%s) = %v, expected an error containing %q", tt.document, err, tt.err)
+		}
+	}
+}
+
+func TestValidate_Range(t *testing.T) {
+	items := NewBugFindingsSchema().Properties.Bugs.Items
+
+	valid := `{"start_line": 3, "end_line": 4, "explanation": "leak", "confidence": 0.8}`
+	if err := Validate(items, []byte(valid)); err != nil {
+		t.Errorf("Validate(%s) failed: %v", valid, err)
+	}
+
+	invalid := `{"start_line": 3, "end_line": 4, "explanation": "leak", "confidence": 80}`
+	if err := Validate(items, []byte(invalid)); err == nil || !strings.Contains(err.Error(), "$.confidence: 80 is greater than 1") {
+		t.Errorf("Validate(%s) = %v, expected the confidence to be out of range", invalid, err)
+	}
+}
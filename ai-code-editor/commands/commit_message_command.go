@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"ai-code-editor/codeEditor/promptFunctions"
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type CommitMessageCommand struct {
+	config *config.Config
+	out    io.Writer
+}
+
+func NewCommitMessageCommand(config *config.Config) *CommitMessageCommand {
+	return &CommitMessageCommand{
+		config: config,
+		out:    os.Stdout,
+	}
+}
+
+// Run writes a conventional commit message for the staged changes. Without
+// arguments the message is printed; as a prepare-commit-msg hook it is written
+// to the message file, unless the message was given with -m, -F or --amend or
+// the commit is a merge.
+//
+//	commit-msg [-model name] [message-file [source [sha]]]
+func (c *CommitMessageCommand) Run(args []string) error {
+	flags := flag.NewFlagSet("commit-msg", flag.ContinueOnError)
+	model := flags.String("model", c.config.LargeModel, "model used to write the message")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	hook := parseCommitMessageHook(flags.Args())
+	if hook == nil {
+		message, err := c.generate(*model)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(c.out, message)
+		return nil
+	}
+
+	switch hook.source {
+	case "message", "commit", "merge":
+		return nil
+	}
+
+	message, err := c.generate(*model)
+	if err == nil {
+		err = hook.write(message)
+	}
+	return hook.finish(err)
+}
+
+// generate writes the message for the staged diff, leaving out files on the deny list
+func (c *CommitMessageCommand) generate(model string) (string, error) {
+	repo, err := openGitRepository()
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := repo.StagedDiffRelative()
+	if err != nil {
+		return "", err
+	}
+
+	guard := services.NewPathGuard(".", deniedPaths(c.config))
+	if diff = guard.FilterDiff(diff); strings.TrimSpace(diff) == "" {
+		return "", fmt.Errorf("there are no staged changes to describe")
+	}
+
+	stagedFiles, err := repo.StagedFiles()
+	if err != nil {
+		return "", err
+	}
+
+	return promptFunctions.NewCommitMessage(model, c.config).GenerateConventional(diff, renderRepoMap(c.config, stagedFiles))
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"fmt"
+	"log"
+	"os"
+)
+
+// commitMessageHook holds the arguments git passes to a prepare-commit-msg
+// hook: the file holding the message and where the message came from, one of
+// "", "message", "template", "merge", "squash" or "commit"
+type commitMessageHook struct {
+	messageFile string
+	source      string
+}
+
+// parseCommitMessageHook returns the hook arguments in args, or nil when the
+// command was not run as a hook
+func parseCommitMessageHook(args []string) *commitMessageHook {
+	if len(args) == 0 {
+		return nil
+	}
+
+	hook := &commitMessageHook{messageFile: args[0]}
+	if len(args) > 1 {
+		hook.source = args[1]
+	}
+	return hook
+}
+
+// write puts message above the current content of the message file, which
+// holds the comments git shows in the editor
+func (h *commitMessageHook) write(message string) error {
+	existing, err := os.ReadFile(h.messageFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", h.messageFile, err)
+	}
+
+	if err := os.WriteFile(h.messageFile, append([]byte(message), existing...), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", h.messageFile, err)
+	}
+	return nil
+}
+
+// finish reports an error of a command run as a hook as a warning, so that
+// an unreachable model never blocks a commit
+func (h *commitMessageHook) finish(err error) error {
+	if err != nil {
+		log.Printf("Warning: no message generated: %v", err)
+	}
+	return nil
+}
+
+// openGitRepository opens the repository containing the current directory
+func openGitRepository() (*services.GitRepository, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting current directory: %w", err)
+	}
+
+	repo := services.NewGitRepository(currentDir)
+	if !repo.IsRepository() {
+		return nil, fmt.Errorf("%s is not inside a git repository", currentDir)
+	}
+	return repo, nil
+}
+
+// deniedPaths returns the built-in and configured deny list
+func deniedPaths(cfg *config.Config) []string {
+	return append(append([]string{}, services.DefaultDeniedPaths...), cfg.DeniedPaths...)
+}
+
+// renderRepoMap describes the repository in the current directory, ranked
+// towards focusFiles
+func renderRepoMap(cfg *config.Config, focusFiles []string) string {
+	repoMap, err := services.BuildRepoMap(".", cfg.MaxFileSizeBytes, deniedPaths(cfg))
+	if err != nil {
+		log.Printf("Warning: failed to build repository map: %v", err)
+		return ""
+	}
+	defer repoMap.Close()
+
+	return repoMap.Render("", focusFiles, cfg.RepoMapTokens)
+}
@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"ai-code-editor/codeEditor"
+	"ai-code-editor/codeEditor/promptFunctions"
+	"ai-code-editor/config"
+	"ai-code-editor/ollama"
+	"ai-code-editor/services"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type EditCommand struct {
+	config *config.Config
+	out    io.Writer
+}
+
+func NewEditCommand(config *config.Config) *EditCommand {
+	return &EditCommand{
+		config: config,
+		out:    os.Stdout,
+	}
+}
+
+// Run edits the code in the current directory to complete a task. With -branch
+// the edits are made on a new ai/<task> branch and committed there; -worktree
+// does the same in a separate git worktree so the current checkout is untouched.
+// The code is indexed so the first prompt includes the code related to the
+// task, unless -context=false.
+//
+//	edit [-branch] [-worktree] [-context=false] [-model name] <task>
+func (c *EditCommand) Run(args []string) error {
+	flags := flag.NewFlagSet("edit", flag.ContinueOnError)
+	branch := flags.Bool("branch", false, "make the edits on a new ai/<task> branch and commit them")
+	worktree := flags.Bool("worktree", false, "make the edits on a new branch in a separate git worktree and commit them")
+	model := flags.String("model", c.config.LargeModel, "model used to edit the code")
+	withContext := flags.Bool("context", true, "index the code and add the code related to the task to the first prompt")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	task := strings.Join(flags.Args(), " ")
+	if task == "" {
+		flags.Usage()
+		return fmt.Errorf("a task is required")
+	}
+
+	if !*branch && !*worktree {
+		c.edit(*model, task, *withContext)
+		return nil
+	}
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current directory: %w", err)
+	}
+
+	repo := services.NewGitRepository(currentDir)
+	if !repo.IsRepository() {
+		return fmt.Errorf("%s is not inside a git repository", currentDir)
+	}
+
+	branchName := repo.UniqueBranchName(task)
+	workRepo := repo
+
+	if *worktree {
+		workRepo, err = c.enterWorktree(repo, currentDir, branchName)
+		if err != nil {
+			return err
+		}
+	} else {
+		dirty, err := repo.HasChanges()
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("the working tree has uncommitted changes; commit or stash them, or use -worktree")
+		}
+		if err := repo.CreateBranch(branchName); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(c.out, "Working on branch %s\n", branchName)
+
+	edited := c.edit(*model, task, *withContext)
+
+	return c.commit(workRepo, edited, *model, task, branchName)
+}
+
+// enterWorktree creates a worktree for branch next to the repository and
+// changes into the directory matching currentDir inside it
+func (c *EditCommand) enterWorktree(repo *services.GitRepository, currentDir, branch string) (*services.GitRepository, error) {
+	root, err := repo.Root()
+	if err != nil {
+		return nil, err
+	}
+
+	worktreePath := services.WorktreePath(root, branch)
+	if err := repo.AddWorktree(worktreePath, branch); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(c.out, "Created worktree %s\n", worktreePath)
+
+	// Keep working in the same subdirectory of the project
+	workDir := worktreePath
+	if relDir, err := filepath.Rel(root, currentDir); err == nil && !strings.HasPrefix(relDir, "..") {
+		workDir = filepath.Join(worktreePath, relDir)
+	}
+
+	if err := os.Chdir(workDir); err != nil {
+		return nil, fmt.Errorf("failed to change to worktree %s: %w", workDir, err)
+	}
+
+	return services.NewGitRepository(workDir), nil
+}
+
+// edit runs the code editor on task in the current directory, with the code
+// related to the task as context when withContext is set. It returns the
+// paths of the edited files.
+func (c *EditCommand) edit(model, task string, withContext bool) []string {
+	fmt.Fprintf(c.out, "Using model: %s\n User task: %s\n", model, task)
+
+	basePrompt := services.NewBasePromptProvider().GetPrompt()
+	if description := promptFunctions.NewCodeBaseDescription(".", task, model, c.config); description.RepoMap != "" {
+		basePrompt += "\n\nRepository map:\n" + description.RepoMap
+	}
+
+	editor := codeEditor.NewCodeEditor(c.config)
+	if withContext {
+		c.addRelatedCode(editor)
+	}
+
+	client := ollama.NewClient(c.config.OllamaBaseURL, false)
+	client.SetResponseReserve(c.config.ResponseReserveTokens)
+	return editor.EditCodeBase(client, model, basePrompt, task)
+}
+
+// addRelatedCode indexes the current directory and registers the index as a
+// context source. Edits go ahead without it when the index is not available.
+func (c *EditCommand) addRelatedCode(editor *codeEditor.CodeEditor) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		log.Printf("Warning: error getting current directory: %v", err)
+		return
+	}
+
+	provider, err := NewIndexedContextProvider(c.config, currentDir)
+	if err != nil {
+		log.Printf("Warning: editing without related code: %v", err)
+		return
+	}
+	editor.AddContextSource(provider)
+}
+
+// commit stages the edited files and commits them with a generated message.
+// Only the edited files are staged, so nothing else in the working tree, such
+// as build output, ends up in the commit.
+func (c *EditCommand) commit(repo *services.GitRepository, edited []string, model, task, branch string) error {
+	if err := repo.Stage(existingPaths(edited)); err != nil {
+		return err
+	}
+
+	diff, err := repo.StagedDiff()
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(diff) == "" {
+		fmt.Fprintf(c.out, "No changes were made; nothing committed on %s\n", branch)
+		return nil
+	}
+
+	message, err := promptFunctions.NewCommitMessage(model, c.config).Generate(task, diff)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		message = promptFunctions.FallbackCommitMessage(task)
+	}
+
+	if err := repo.Commit(message); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Committed the edits on %s in %s\n", branch, repo.Dir())
+	return nil
+}
+
+// existingPaths returns the paths that exist, leaving out files an edit meant
+// to create but never wrote, which git cannot stage
+func existingPaths(paths []string) []string {
+	existing := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+	}
+	return existing
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"ai-code-editor/codeEditor/promptFunctions"
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// entryPointQuery finds code to analyze when no query is given
+const entryPointQuery = "main entry point func main program startup"
+
+type FindBugCommand struct {
+	config *config.Config
+	out    io.Writer
+}
+
+func NewFindBugCommand(config *config.Config) *FindBugCommand {
+	return &FindBugCommand{
+		config: config,
+		out:    os.Stdout,
+	}
+}
+
+// Run looks for bugs with the model. By default the codebase is indexed and
+// the code matching -query, or the entry point without one, is analyzed. With
+// -dir every file, or with -each symbol every function and method, under the
+// directory is analyzed instead.
+//
+//	bug [-query text] [-limit 3] [-dir path [-each file|symbol]] [-min-confidence 0.5] [-format terminal|json]
+func (c *FindBugCommand) Run(args []string) error {
+	flags := flag.NewFlagSet("bug", flag.ContinueOnError)
+	query := flags.String("query", "", "focus the bug search on code matching this description")
+	limit := flags.Int("limit", 3, "number of search results to analyze")
+	dir := flags.String("dir", "", "analyze every file under this directory instead of search results")
+	each := flags.String("each", "file", "with -dir, analyze each file or each symbol (function or method)")
+	minConfidence := flags.Float64("min-confidence", 0.5, "only report bugs with at least this confidence, from 0 to 1")
+	format := flags.String("format", "terminal", "output format: terminal or json")
+	model := flags.String("model", c.config.LargeModel, "model used to find bugs")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *query == "" && flags.NArg() > 0 {
+		*query = strings.Join(flags.Args(), " ")
+	}
+
+	if *format != "terminal" && *format != "json" {
+		return fmt.Errorf("unknown format %s, expected terminal or json", *format)
+	}
+	if *each != "file" && *each != "symbol" {
+		return fmt.Errorf("unknown -each %s, expected file or symbol", *each)
+	}
+
+	var units []services.CodeUnit
+	var additionalContext string
+	var err error
+
+	if *dir != "" {
+		units, err = c.directoryUnits(*dir, *each == "symbol")
+		additionalContext = "The code is part of a larger codebase; assume code outside it works as its name suggests."
+	} else {
+		units, err = c.searchUnits(*query, *limit)
+		if *query == "" {
+			additionalContext = "This is the main entry point of the application. Look for potential initialization or configuration issues."
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if *query != "" {
+		additionalContext = strings.TrimSpace(additionalContext + " The user is specifically concerned about: " + *query)
+	}
+
+	if len(units) == 0 {
+		fmt.Fprintln(c.out, "Could not find relevant code matching your query.")
+		return nil
+	}
+
+	finder := promptFunctions.NewFindBug(*model, c.config)
+	findings := make([]services.BugFinding, 0)
+	for i, unit := range units {
+		log.Printf("Analyzing %s (%d/%d)", unit.Location(), i+1, len(units))
+
+		unitFindings, err := finder.FindBugs(unit, additionalContext)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		for _, finding := range unitFindings {
+			if finding.Confidence >= *minConfidence {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	services.SortBugFindings(findings)
+
+	if *format == "json" {
+		return services.WriteBugFindingsJSON(c.out, findings)
+	}
+	return services.WriteBugFindingsText(c.out, findings)
+}
+
+// directoryUnits returns every file, or every function and method, under dir
+func (c *FindBugCommand) directoryUnits(dir string, symbols bool) ([]services.CodeUnit, error) {
+	units, err := services.FileUnits(dir, c.config.MaxFileSizeBytes, deniedPaths(c.config))
+	if err != nil {
+		return nil, err
+	}
+
+	if symbols {
+		units = services.SymbolUnits(units, services.SharedParseCache())
+	}
+	return units, nil
+}
+
+// searchUnits indexes the current directory and returns the code most
+// relevant to query, or to the entry point without one
+func (c *FindBugCommand) searchUnits(query string, limit int) ([]services.CodeUnit, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting current directory: %w", err)
+	}
+
+	provider, err := NewIndexedContextProvider(c.config, currentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if query == "" {
+		query = entryPointQuery
+	}
+	results, err := provider.Search(query, limit, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for %q: %w", query, err)
+	}
+
+	guard := services.NewPathGuard(currentDir, deniedPaths(c.config))
+	units := make([]services.CodeUnit, 0, len(results))
+	for _, unit := range services.SearchResultUnits(results) {
+		if _, err := guard.Resolve(unit.Path); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		units = append(units, unit)
+	}
+	return units, nil
+}
@@ -0,0 +1,19 @@
+package commands
+
+import "strings"
+
+// stringListFlag collects a flag that may be repeated or given as a comma separated list
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			*s = append(*s, trimmed)
+		}
+	}
+	return nil
+}
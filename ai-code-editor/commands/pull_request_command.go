@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"ai-code-editor/codeEditor/promptFunctions"
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type PullRequestCommand struct {
+	config *config.Config
+	out    io.Writer
+}
+
+func NewPullRequestCommand(config *config.Config) *PullRequestCommand {
+	return &PullRequestCommand{
+		config: config,
+		out:    os.Stdout,
+	}
+}
+
+// Run prints a pull request title and description summarizing the commits of
+// head that are not on base. As a prepare-commit-msg hook it describes the
+// branch being merged in merge commits and leaves other commits alone.
+//
+//	pr-describe [-base main] [-head HEAD] [-model name] [message-file [source [sha]]]
+func (c *PullRequestCommand) Run(args []string) error {
+	flags := flag.NewFlagSet("pr-describe", flag.ContinueOnError)
+	base := flags.String("base", "", "branch the commits are merged into, by default the remote's default branch, main or master")
+	head := flags.String("head", "HEAD", "branch or commit to describe")
+	model := flags.String("model", c.config.LargeModel, "model used to write the description")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	hook := parseCommitMessageHook(flags.Args())
+	if hook == nil {
+		description, err := c.describe(*model, *base, *head)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(c.out, description)
+		return nil
+	}
+
+	// While merging, HEAD is the base and MERGE_HEAD the branch being merged
+	if hook.source != "merge" {
+		return nil
+	}
+
+	description, err := c.describe(*model, "HEAD", "MERGE_HEAD")
+	if err == nil {
+		err = hook.write(description)
+	}
+	return hook.finish(err)
+}
+
+// describe summarizes the commits between base and head
+func (c *PullRequestCommand) describe(model, base, head string) (string, error) {
+	repo, err := openGitRepository()
+	if err != nil {
+		return "", err
+	}
+
+	if base == "" {
+		if base, err = repo.DefaultBaseBranch(); err != nil {
+			return "", err
+		}
+	}
+	for _, ref := range []string{base, head} {
+		if !repo.RefExists(ref) {
+			return "", fmt.Errorf("unknown branch or commit %s", ref)
+		}
+	}
+
+	commits, err := repo.RangeLog(base, head)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(commits) == "" {
+		return "", fmt.Errorf("%s has no commits that are not on %s", head, base)
+	}
+
+	diffStat, err := repo.RangeDiff(base, head, true)
+	if err != nil {
+		return "", err
+	}
+	diff, err := repo.RangeDiff(base, head, false)
+	if err != nil {
+		return "", err
+	}
+
+	changedFiles, err := repo.RangeFiles(base, head)
+	if err != nil {
+		return "", err
+	}
+
+	guard := services.NewPathGuard(".", deniedPaths(c.config))
+	diff = guard.FilterDiff(diff)
+
+	return promptFunctions.NewPullRequestDescription(model, c.config).Describe(
+		base, head, commits, diffStat, diff, renderRepoMap(c.config, changedFiles),
+	)
+}
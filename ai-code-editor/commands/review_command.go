@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"ai-code-editor/codeEditor/promptFunctions"
+	"ai-code-editor/config"
+	"ai-code-editor/ollama"
+	"ai-code-editor/services"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	// maxReviewQueryLines bounds how many added lines are used to search for context
+	maxReviewQueryLines = 40
+	// reviewPromptTokens is the size of the review instructions around the diff and context
+	reviewPromptTokens = 512
+)
+
+type ReviewCommand struct {
+	config *config.Config
+	out    io.Writer
+}
+
+func NewReviewCommand(config *config.Config) *ReviewCommand {
+	return &ReviewCommand{
+		config: config,
+		out:    os.Stdout,
+	}
+}
+
+// Run reviews a diff file by file and prints the findings. The diff is every
+// uncommitted change by default, the staged changes with -staged, or the
+// changes of a branch given as base..head. Progress goes to the log so JSON
+// and SARIF output can be redirected to a file.
+//
+//	review [-staged] [-format terminal|json|sarif] [-model name] [-context=false] [base..head]
+func (c *ReviewCommand) Run(args []string) error {
+	flags := flag.NewFlagSet("review", flag.ContinueOnError)
+	staged := flags.Bool("staged", false, "review the staged changes only")
+	format := flags.String("format", "terminal", "output format: terminal, json or sarif")
+	model := flags.String("model", c.config.LargeModel, "model used to review the code")
+	withContext := flags.Bool("context", true, "index the code and add related code to each review")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch *format {
+	case "terminal", "json", "sarif":
+	default:
+		return fmt.Errorf("unknown format %s, expected terminal, json or sarif", *format)
+	}
+	if flags.NArg() > 1 || (*staged && flags.NArg() == 1) {
+		flags.Usage()
+		return fmt.Errorf("give either -staged or a single base..head range")
+	}
+
+	diff, err := c.diff(*staged, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	guard := services.NewPathGuard(".", deniedPaths(c.config))
+	files := services.ParseUnifiedDiff(guard.FilterDiff(diff))
+
+	var contextProvider *services.SemanticFileContextProvider
+	if *withContext && len(files) > 0 {
+		contextProvider = c.indexedContextProvider()
+	}
+
+	reviewer := promptFunctions.NewReviewDiff(*model, c.config)
+	findings := make([]services.ReviewFinding, 0)
+	for _, file := range files {
+		if file.Deleted || file.Binary || len(file.Hunks) == 0 {
+			continue
+		}
+
+		log.Printf("Reviewing %s", file.Path)
+		fileFindings, err := reviewer.Review(file, c.relatedCode(contextProvider, guard, file))
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		findings = append(findings, fileFindings...)
+	}
+	services.SortFindings(findings)
+
+	switch *format {
+	case "json":
+		return services.WriteFindingsJSON(c.out, findings)
+	case "sarif":
+		return services.WriteFindingsSARIF(c.out, "ai-code-editor", findings)
+	default:
+		return services.WriteFindingsText(c.out, findings)
+	}
+}
+
+// diff returns the changes to review
+func (c *ReviewCommand) diff(staged bool, revisionRange string) (string, error) {
+	repo, err := openGitRepository()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case staged:
+		return repo.StagedDiffRelative()
+	case revisionRange != "":
+		// base...head means the same as base..head: the changes made on head
+		base, head, found := strings.Cut(strings.Replace(revisionRange, "...", "..", 1), "..")
+		if !found || base == "" {
+			return "", fmt.Errorf("invalid range %s, expected base..head", revisionRange)
+		}
+		if head == "" {
+			head = "HEAD"
+		}
+		return repo.RangeDiff(base, head, false)
+	default:
+		return repo.DiffAgainst("HEAD")
+	}
+}
+
+// indexedContextProvider indexes the current directory for context searches.
+// Reviews go ahead without context when the index is not available.
+func (c *ReviewCommand) indexedContextProvider() *services.SemanticFileContextProvider {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		log.Printf("Warning: error getting current directory: %v", err)
+		return nil
+	}
+
+	provider, err := NewIndexedContextProvider(c.config, currentDir)
+	if err != nil {
+		log.Printf("Warning: reviewing without related code: %v", err)
+		return nil
+	}
+	return provider
+}
+
+// relatedCode finds the code related to a file's changes, packed into what is
+// left of the context window once the diff is in the prompt. Code from files
+// the guard refuses is left out, as it is from the diff.
+func (c *ReviewCommand) relatedCode(provider *services.SemanticFileContextProvider, guard *services.PathGuard, file services.FileDiff) string {
+	if provider == nil {
+		return "(none)"
+	}
+
+	added := file.AddedLines()
+	if len(added) > maxReviewQueryLines {
+		added = added[:maxReviewQueryLines]
+	}
+	query := file.Path + "\n" + strings.Join(added, "\n")
+
+	found, err := provider.ContextItems(query)
+	if err != nil {
+		log.Printf("Warning: failed to find code related to %s: %v", file.Path, err)
+		return "(none)"
+	}
+
+	items := make([]services.ContextItem, 0, len(found))
+	for _, item := range found {
+		if _, err := guard.Resolve(item.Path); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return "(none)"
+	}
+
+	used := c.config.ResponseReserveTokens + reviewPromptTokens + ollama.EstimateTokens(file.Diff())
+	return services.NewContextPacker(services.ContextBudget(c.config.NumCtx, used)).Pack(items).Text
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxPreviewChars limits how much of each result is printed
+const maxPreviewChars = 200
+
+type SearchCommand struct {
+	config *config.Config
+	out    io.Writer
+}
+
+func NewSearchCommand(config *config.Config) *SearchCommand {
+	return &SearchCommand{
+		config: config,
+		out:    os.Stdout,
+	}
+}
+
+// Run indexes the current directory and prints the code most relevant to the query.
+//
+//	search [-limit 5] [-ext .go] [-dir services/] [-lang go] [-kind function] [-tests exclude] <query>
+func (c *SearchCommand) Run(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := flags.Int("limit", 5, "maximum number of results to return")
+	var extensions, dirs, languages, kinds stringListFlag
+	flags.Var(&extensions, "ext", "only search files with these extensions, e.g. .go,.py")
+	flags.Var(&dirs, "dir", "only search inside these directories, e.g. services/")
+	flags.Var(&languages, "lang", "only search these languages, e.g. go,python")
+	flags.Var(&kinds, "kind", "only search chunks declaring these symbol kinds: function, method, class, interface, type")
+	tests := flags.String("tests", "include", "include, exclude or only search test files")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := strings.Join(flags.Args(), " ")
+	if query == "" {
+		flags.Usage()
+		return fmt.Errorf("a search query is required")
+	}
+
+	testFilter, err := services.ParseTestFilter(*tests)
+	if err != nil {
+		return err
+	}
+
+	filter := &services.SearchFilter{
+		Extensions:   extensions,
+		PathPrefixes: dirs,
+		Languages:    languages,
+		Tests:        testFilter,
+	}
+	for _, kind := range kinds {
+		filter.SymbolKinds = append(filter.SymbolKinds, services.SymbolKind(strings.ToLower(kind)))
+	}
+
+	// Get current directory
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current directory: %w", err)
+	}
+
+	semanticContextProvider, err := NewSemanticContextProvider(c.config, currentDir)
+	if err != nil {
+		return err
+	}
+
+	// Index the current directory
+	fmt.Fprintln(c.out, "Indexing code files...")
+	err = semanticContextProvider.IndexDirectory(currentDir, nil)
+	if err != nil {
+		fmt.Fprintf(c.out, "Warning: Error indexing directory: %v\n", err)
+	}
+
+	fmt.Fprintf(c.out, "\nSearching for: %s\n", query)
+	results, err := semanticContextProvider.Search(query, *limit, filter)
+	if err != nil {
+		return err
+	}
+
+	c.printResults(results)
+	return nil
+}
+
+// printResults prints results grouped by file, keeping the order files first appear in
+func (c *SearchCommand) printResults(results []services.SearchResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(c.out, "No results found.")
+		return
+	}
+
+	fileOrder := make([]string, 0)
+	resultsByFile := make(map[string][]services.SearchResult)
+	for _, result := range results {
+		if _, exists := resultsByFile[result.Path]; !exists {
+			fileOrder = append(fileOrder, result.Path)
+		}
+		resultsByFile[result.Path] = append(resultsByFile[result.Path], result)
+	}
+
+	for _, path := range fileOrder {
+		fmt.Fprintf(c.out, "\nFile: %s\n", path)
+		for _, result := range resultsByFile[path] {
+			fmt.Fprintf(c.out, "  Lines %d-%d (Score: %.3f)\n", result.StartLine, result.EndLine, result.Score)
+			fmt.Fprintln(c.out, "  Preview:")
+
+			preview := result.Content
+			if len(preview) > maxPreviewChars {
+				preview = preview[:maxPreviewChars] + "..."
+			}
+			fmt.Fprintf(c.out, "  %s\n\n", strings.ReplaceAll(preview, "\n", "\n  "))
+		}
+	}
+}
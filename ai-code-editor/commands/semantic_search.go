@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"ai-code-editor/codeEditor/promptFunctions"
+	"ai-code-editor/config"
+	"ai-code-editor/services"
+	"fmt"
+	"log"
+)
+
+// NewSemanticContextProvider creates a semantic file context provider for dir with
+// query expansion and reranking enabled according to the config
+func NewSemanticContextProvider(cfg *config.Config, dir string) (*services.SemanticFileContextProvider, error) {
+	// Initialize the code embedding service
+	codeEmbeddingService, err := services.NewCodeEmbeddingService(cfg, "code_embeddings")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create code embedding service: %w", err)
+	}
+
+	// Initialize the semantic file context provider
+	provider := services.NewSemanticFileContextProvider(cfg, codeEmbeddingService, dir)
+
+	// Optionally rewrite the task into targeted search queries with the small model
+	if cfg.QueryExpansion && cfg.SmallModel != "" {
+		provider.SetQueryExpander(
+			promptFunctions.NewExpandSearchQuery(cfg.SmallModel, cfg, cfg.QueryExpansionCount),
+		)
+	}
+
+	// Optionally rescore search results with a local model and drop weak matches
+	if cfg.SearchRerank && cfg.RerankModel != "" {
+		provider.SetReranker(
+			promptFunctions.NewRerankCodeChunks(cfg.RerankModel, cfg),
+			cfg.SearchThreshold,
+		)
+	}
+
+	return provider, nil
+}
+
+// NewIndexedContextProvider creates a semantic context provider for dir like
+// NewSemanticContextProvider and indexes the code files under dir. Indexing
+// errors are only logged, since searches still find what was indexed.
+func NewIndexedContextProvider(cfg *config.Config, dir string) (*services.SemanticFileContextProvider, error) {
+	provider, err := NewSemanticContextProvider(cfg, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Indexing code files...")
+	if err := provider.IndexDirectory(dir, nil); err != nil {
+		log.Printf("Warning: Error indexing directory: %v", err)
+	}
+
+	return provider, nil
+}
@@ -1,15 +1,80 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
-	Port          string
-	OllamaBaseURL string
-	SmallModel    string
-	MediumModel   string
-	LargeModel    string
-	ChromaURL     string
-	EmbedModel    string
+	Port               string
+	OllamaBaseURL      string
+	SmallModel         string
+	MediumModel        string
+	LargeModel         string
+	ChromaURL          string
+	EmbedModel         string
+	ChunkSizeTokens    int
+	ChunkOverlapTokens int
+	EmbedBatchSize     int
+	IndexWorkers       int
+
+	// Weights for fusing semantic and lexical search rankings
+	SearchSemanticWeight float64
+	SearchLexicalWeight  float64
+	SearchRRFK           int
+
+	// Context window of the chat models and the part of it kept free for replies
+	NumCtx                int
+	ResponseReserveTokens int
+
+	// Query expansion rewrites the search query with the small model
+	QueryExpansion      bool
+	QueryExpansionCount int
+
+	// Reranking rescores search results with a local model and drops those
+	// scoring below SearchThreshold
+	SearchRerank    bool
+	RerankModel     string
+	RerankWorkers   int
+	SearchThreshold float64
+
+	// Token budget of the repository map shown to the models
+	RepoMapTokens int
+
+	// Files larger than this are not indexed
+	MaxFileSizeBytes int64
+
+	// Commands the model may run without confirmation, as prefixes such as
+	// "go test", and the limits applied to every command
+	CommandAllowlist      []string
+	CommandTimeout        time.Duration
+	CommandMaxOutputBytes int
+
+	// Checks run after every edit, such as "go test ./...", and how many times
+	// the model may try to fix a failing check before its edits are rolled back.
+	// No checks means the defaults for the project's language.
+	VerifyCommands []string
+	VerifyRounds   int
+
+	// Formatter commands for edited files by extension list, such as
+	// ".py" -> "black -q -". Go files are always formatted with gofmt.
+	FormatCommands map[string]string
+
+	// Paths model actions may not read or write, in .gitignore syntax, in
+	// addition to the built-in deny list of secrets and .git
+	DeniedPaths []string
+
+	// Secrets are replaced by placeholders in prompts, embeddings and the
+	// vector database. RedactionRules adds named regular expressions.
+	RedactSecrets  bool
+	RedactionRules map[string]string
+
+	// Uncommitted changes and the last GitHistoryCommits commit messages
+	// touching the changed files are added to the prompt in git repositories
+	GitContext        bool
+	GitHistoryCommits int
 }
 
 func Load() *Config {
@@ -37,13 +102,197 @@ func Load() *Config {
 		embedModel = "nomic-embed-text"
 	}
 
+	// Chunk sizes are measured in estimated tokens of the embed model
+	chunkSizeTokens := getEnvIntAtLeast("CHUNK_SIZE_TOKENS", 384, 1)
+	chunkOverlapTokens := getEnvIntAtLeast("CHUNK_OVERLAP_TOKENS", 48, 0)
+
+	// Number of chunks sent per embedding request and vector store insert
+	embedBatchSize := getEnvIntAtLeast("EMBED_BATCH_SIZE", 32, 1)
+	// Number of files indexed concurrently
+	indexWorkers := getEnvInt("INDEX_WORKERS", 4)
+
+	// Hybrid search combines rankings with weighted reciprocal rank fusion
+	searchSemanticWeight := getEnvFloat("SEARCH_SEMANTIC_WEIGHT", 1.0)
+	searchLexicalWeight := getEnvFloat("SEARCH_LEXICAL_WEIGHT", 1.0)
+	searchRRFK := getEnvInt("SEARCH_RRF_K", 60)
+
+	numCtx := getEnvInt("NUM_CTX", 8192)
+	responseReserveTokens := getEnvInt("RESPONSE_RESERVE_TOKENS", 1024)
+
+	queryExpansion := getEnvBool("SEARCH_QUERY_EXPANSION", false)
+	queryExpansionCount := getEnvInt("SEARCH_QUERY_EXPANSION_COUNT", 4)
+
+	searchRerank := getEnvBool("SEARCH_RERANK", false)
+	rerankModel := os.Getenv("RERANK_MODEL")
+	if rerankModel == "" {
+		rerankModel = smallModel
+	}
+	// Number of results scored concurrently by the rerank model
+	rerankWorkers := getEnvIntAtLeast("RERANK_WORKERS", 4, 1)
+	searchThreshold := getEnvFloat("SEARCH_THRESHOLD", 0.4)
+
+	repoMapTokens := getEnvInt("REPO_MAP_TOKENS", 2048)
+
+	maxFileSizeBytes := int64(getEnvInt("MAX_FILE_SIZE_KB", 1024)) * 1024
+
+	commandAllowlist := getEnvList("COMMAND_ALLOWLIST", []string{"go build", "go test", "go vet", "gofmt", "goimports", "golangci-lint", "staticcheck"})
+	commandTimeout := time.Duration(getEnvInt("COMMAND_TIMEOUT_SECONDS", 120)) * time.Second
+	commandMaxOutputBytes := getEnvInt("COMMAND_MAX_OUTPUT_KB", 16) * 1024
+
+	verifyCommands := getEnvList("VERIFY_COMMANDS", []string{})
+	verifyRounds := getEnvInt("VERIFY_ROUNDS", 3)
+
+	formatCommands := getEnvMap("FORMAT_COMMANDS")
+
+	deniedPaths := getEnvList("DENIED_PATHS", []string{})
+
+	redactSecrets := getEnvBool("REDACT_SECRETS", true)
+	redactionRules := getEnvMap("REDACTION_RULES")
+
+	gitContext := getEnvBool("GIT_CONTEXT", true)
+	gitHistoryCommits := getEnvInt("GIT_HISTORY_COMMITS", 5)
+
 	return &Config{
-		Port:          port,
-		OllamaBaseURL: ollamaURL,
-		SmallModel:    smallModel,
-		MediumModel:   mediumModel,
-		LargeModel:    largeModel,
-		ChromaURL:     chromaURL,
-		EmbedModel:    embedModel,
+		Port:               port,
+		OllamaBaseURL:      ollamaURL,
+		SmallModel:         smallModel,
+		MediumModel:        mediumModel,
+		LargeModel:         largeModel,
+		ChromaURL:          chromaURL,
+		EmbedModel:         embedModel,
+		ChunkSizeTokens:    chunkSizeTokens,
+		ChunkOverlapTokens: chunkOverlapTokens,
+		EmbedBatchSize:     embedBatchSize,
+		IndexWorkers:       indexWorkers,
+
+		SearchSemanticWeight: searchSemanticWeight,
+		SearchLexicalWeight:  searchLexicalWeight,
+		SearchRRFK:           searchRRFK,
+
+		NumCtx:                numCtx,
+		ResponseReserveTokens: responseReserveTokens,
+
+		QueryExpansion:      queryExpansion,
+		QueryExpansionCount: queryExpansionCount,
+
+		SearchRerank:    searchRerank,
+		RerankModel:     rerankModel,
+		RerankWorkers:   rerankWorkers,
+		SearchThreshold: searchThreshold,
+
+		RepoMapTokens: repoMapTokens,
+
+		MaxFileSizeBytes: maxFileSizeBytes,
+
+		CommandAllowlist:      commandAllowlist,
+		CommandTimeout:        commandTimeout,
+		CommandMaxOutputBytes: commandMaxOutputBytes,
+
+		VerifyCommands: verifyCommands,
+		VerifyRounds:   verifyRounds,
+
+		FormatCommands: formatCommands,
+
+		DeniedPaths: deniedPaths,
+
+		RedactSecrets:  redactSecrets,
+		RedactionRules: redactionRules,
+
+		GitContext:        gitContext,
+		GitHistoryCommits: gitHistoryCommits,
+	}
+}
+
+// getEnvInt reads an integer environment variable, falling back to defaultValue
+// when it is unset or not a valid integer
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+
+	return parsed
+}
+
+// getEnvIntAtLeast reads an integer environment variable like getEnvInt,
+// also falling back to defaultValue when it is below minimum
+func getEnvIntAtLeast(key string, defaultValue, minimum int) int {
+	if value := getEnvInt(key, defaultValue); value >= minimum {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvFloat reads a floating point environment variable, falling back to
+// defaultValue when it is unset or not a valid number
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return parsed
+}
+
+// getEnvBool reads a boolean environment variable such as "true" or "1",
+// falling back to defaultValue when it is unset or not a valid boolean
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+
+	return parsed
+}
+
+// getEnvList reads a comma separated environment variable, falling back to
+// defaultValue when it is unset. Set it to "none" for an empty list.
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
 	}
+	if value == "none" {
+		return []string{}
+	}
+
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// getEnvMap reads a semicolon separated list of key=value pairs, such as
+// ".py=black -q -;.js,.ts=prettier --stdin-filepath {path}"
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+
+	for _, pair := range strings.Split(os.Getenv(key), ";") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	return values
 }
@@ -4,14 +4,22 @@ go 1.23
 
 toolchain go1.24.0
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/amikos-tech/chroma-go v0.1.4
+	github.com/joho/godotenv v1.5.1
+	github.com/tree-sitter/go-tree-sitter v0.25.0
+	github.com/tree-sitter/tree-sitter-c-sharp v0.23.1
+	github.com/tree-sitter/tree-sitter-go v0.23.4
+	github.com/tree-sitter/tree-sitter-java v0.23.5
+	github.com/tree-sitter/tree-sitter-javascript v0.23.1
+	github.com/tree-sitter/tree-sitter-python v0.23.6
+	github.com/tree-sitter/tree-sitter-rust v0.23.2
+	github.com/tree-sitter/tree-sitter-typescript v0.23.2
+)
 
 require (
 	github.com/Masterminds/semver v1.5.0 // indirect
-	github.com/amikos-tech/chroma-go v0.1.4 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/mattn/go-pointer v0.0.1 // indirect
 	github.com/oklog/ulid v1.3.1 // indirect
-	github.com/tree-sitter/go-tree-sitter v0.25.0 // indirect
-	github.com/tree-sitter/tree-sitter-go v0.23.4 // indirect
 )
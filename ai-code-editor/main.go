@@ -1,11 +1,14 @@
 package main
 
 import (
+	"ai-code-editor/commands"
 	"ai-code-editor/config"
+	"ai-code-editor/redaction"
 	"ai-code-editor/services"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 
 	"github.com/joho/godotenv"
 )
@@ -18,33 +21,77 @@ func main() {
 	}
 
 	config := config.Load()
-
-	// Get current directory
-	currentDir, err := os.Getwd()
-	if err != nil {
-		log.Printf("Error getting current directory: %v", err)
-		return
-	}
+	configureRedaction(config)
 
 	// Check for required arguments
-	if len(os.Args) < 1 {
+	if len(os.Args) < 2 {
 		fmt.Println("Usage: ollama-cli <prompt> [files...]")
+		fmt.Println("       ollama-cli search [flags] <query>")
+		fmt.Println("       ollama-cli edit [-branch] [-worktree] <task>")
+		fmt.Println("       ollama-cli commit-msg [message-file [source [sha]]]")
+		fmt.Println("       ollama-cli pr-describe [-base main] [-head HEAD]")
+		fmt.Println("       ollama-cli review [-staged] [-format terminal|json|sarif] [base..head]")
+		fmt.Println("       ollama-cli bug [-query text] [-dir path [-each file|symbol]]")
 		fmt.Println("Example: ollama-cli 'Fix the bug' file1.go file2.go")
 		os.Exit(1)
 	}
 
-	userTask := os.Args[1]
+	switch os.Args[1] {
+	case "search":
+		err = commands.NewSearchCommand(config).Run(os.Args[2:])
+	case "edit":
+		err = commands.NewEditCommand(config).Run(os.Args[2:])
+	case "commit-msg":
+		err = commands.NewCommitMessageCommand(config).Run(os.Args[2:])
+	case "pr-describe":
+		err = commands.NewPullRequestCommand(config).Run(os.Args[2:])
+	case "review":
+		err = commands.NewReviewCommand(config).Run(os.Args[2:])
+	case "bug":
+		err = commands.NewFindBugCommand(config).Run(os.Args[2:])
+	default:
+		runTask(config, os.Args[1])
+	}
 
-	selectedModel := config.LargeModel
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// configureRedaction applies the redaction settings to the shared redactor
+func configureRedaction(config *config.Config) {
+	redactor := redaction.Shared()
+	redactor.SetEnabled(config.RedactSecrets)
 
-	// Initialize the code embedding service
-	codeEmbeddingService, err := services.NewCodeEmbeddingService(config, "code_embeddings")
+	// Sorted so placeholders are numbered the same way on every run
+	names := make([]string, 0, len(config.RedactionRules))
+	for name := range config.RedactionRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := redactor.AddRule(name, config.RedactionRules[name]); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// runTask finds the files and code relevant to a user task
+func runTask(config *config.Config, userTask string) {
+	// Get current directory
+	currentDir, err := os.Getwd()
 	if err != nil {
-		log.Fatalf("Failed to create code embedding service: %v", err)
+		log.Printf("Error getting current directory: %v", err)
+		return
 	}
 
-	// Initialize the semantic file context provider
-	semanticContextProvider := services.NewSemanticFileContextProvider(codeEmbeddingService, currentDir)
+	selectedModel := config.LargeModel
+
+	semanticContextProvider, err := commands.NewSemanticContextProvider(config, currentDir)
+	if err != nil {
+		log.Fatalf("Failed to create semantic context provider: %v", err)
+	}
 
 	// Index the current directory
 	fmt.Println("Indexing code files...")
@@ -53,7 +100,7 @@ func main() {
 		log.Printf("Warning: Error indexing directory: %v", err)
 	}
 
-	directoryTree := services.NewDirectoryTree("    ", 10, []string{})
+	directoryTree := services.NewDirectoryTree("    ", 10, config.MaxFileSizeBytes, []string{})
 
 	// Generate tree first to populate known files
 	_, err = directoryTree.GenerateTree(currentDir)
@@ -65,10 +112,10 @@ func main() {
 	fmt.Printf("Using model: %s\n User task: %s\n", selectedModel, userTask)
 
 	// Use semantic search to find relevant files
-	relevantFiles, err := semanticContextProvider.GetRelevantFiles(userTask, 5)
+	relevantFiles, err := semanticContextProvider.GetRelevantFilesWithScores(userTask, 5, nil)
 
 	// Get relevant context
-	relevantContext, err := semanticContextProvider.GetRelevantContext(userTask, 10)
+	relevantContext, err := semanticContextProvider.GetRelevantContext(userTask, 10, nil)
 
 	fmt.Printf("Relevant context: %v\n", relevantContext)
 	fmt.Printf("Relevant files: %v\n", relevantFiles)
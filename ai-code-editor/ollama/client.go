@@ -1,6 +1,7 @@
 package ollama
 
 import (
+	"ai-code-editor/redaction"
 	"bufio"
 	"bytes"
 	"encoding/json"
@@ -9,6 +10,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -19,13 +21,26 @@ type Client struct {
 	httpClient *http.Client
 	history    []Message
 	stateless  bool // New field to control stateless behavior
+
+	responseReserve int // tokens of the context window kept free for the reply
+
+	mu                  sync.Mutex
+	modelInfo           map[string]*ModelInfo
+	calibration         map[string]float64 // per model scaling of token estimates
+	tokenCounts         map[tokenCountKey]int
+	tokenizeUnsupported bool
 }
 
+// DefaultResponseReserveTokens is how much of the context window is kept free
+// for the model's reply when checking whether a prompt fits
+const DefaultResponseReserveTokens = 1024
+
 type ChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Stream   bool      `json:"stream"`
-	Format   any       `json:"format"` // Change to any type to support object formats
+	Model    string         `json:"model"`
+	Messages []Message      `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Format   any            `json:"format"` // Change to any type to support object formats
+	Options  map[string]any `json:"options,omitempty"`
 }
 
 type Message struct {
@@ -43,8 +58,12 @@ func NewClient(baseURL string, stateless bool) *Client {
 		httpClient: &http.Client{
 			Timeout: time.Second * 300, // 5 minute timeout
 		},
-		history:   make([]Message, 0),
-		stateless: stateless,
+		history:         make([]Message, 0),
+		stateless:       stateless,
+		responseReserve: DefaultResponseReserveTokens,
+		modelInfo:       make(map[string]*ModelInfo),
+		calibration:     make(map[string]float64),
+		tokenCounts:     make(map[tokenCountKey]int),
 	}
 }
 
@@ -62,18 +81,37 @@ func NewOllamaClient(stateless bool) *Client {
 	baseURL := fmt.Sprintf("http://%s:%s", host, port)
 
 	return &Client{
-		baseURL:    baseURL,
-		httpClient: &http.Client{},
-		history:    make([]Message, 0),
-		stateless:  stateless,
+		baseURL:         baseURL,
+		httpClient:      &http.Client{},
+		history:         make([]Message, 0),
+		stateless:       stateless,
+		responseReserve: DefaultResponseReserveTokens,
+		modelInfo:       make(map[string]*ModelInfo),
+		calibration:     make(map[string]float64),
+		tokenCounts:     make(map[tokenCountKey]int),
 	}
 }
 
+// SetResponseReserve sets how many tokens of the context window are kept free
+// for the model's reply, such as the configured RESPONSE_RESERVE_TOKENS
+func (c *Client) SetResponseReserve(tokens int) {
+	c.responseReserve = max(tokens, 0)
+}
+
 func (r *ChatRequest) WithFormat(format any) *ChatRequest {
 	r.Format = format
 	return r
 }
 
+// WithNumCtx sets the context window the model is loaded with
+func (r *ChatRequest) WithNumCtx(numCtx int) *ChatRequest {
+	if r.Options == nil {
+		r.Options = make(map[string]any)
+	}
+	r.Options["num_ctx"] = numCtx
+	return r
+}
+
 func (c *Client) AddMessage(role, content string) {
 	if !c.stateless {
 		c.history = append(c.history, Message{
@@ -130,6 +168,14 @@ func (c *Client) ChatCompletion(req interface{}) (string, error) {
 		chatReq.Messages = c.history
 	}
 
+	// Secrets never leave the machine, not even to be counted; placeholders are
+	// restored when edits are applied
+	chatReq.Messages = redactMessages(chatReq.Messages)
+
+	// Make sure the prompt fits the model's context window, trimming history if needed
+	promptTokens := c.fitContextWindow(&chatReq)
+	estimatedTokens := estimateMessageTokens(chatReq.Messages)
+
 	// Convert the request to JSON
 	jsonData, err := json.Marshal(chatReq)
 	if err != nil {
@@ -162,6 +208,7 @@ func (c *Client) ChatCompletion(req interface{}) (string, error) {
 	log.Printf("Response: %+v", response)
 
 	var responseString string = ""
+	var promptEvalCount, evalCount int
 
 	scanner := bufio.NewScanner(response.Body)
 	// Set a larger buffer size to handle longer responses
@@ -178,6 +225,14 @@ func (c *Client) ChatCompletion(req interface{}) (string, error) {
 		if response["message"] != nil {
 			responseString += response["message"].(map[string]interface{})["content"].(string)
 		}
+
+		// The final response carries the exact token counts
+		if count, ok := response["prompt_eval_count"].(float64); ok {
+			promptEvalCount = int(count)
+		}
+		if count, ok := response["eval_count"].(float64); ok {
+			evalCount = int(count)
+		}
 	}
 
 	// Check for scanner errors
@@ -185,6 +240,9 @@ func (c *Client) ChatCompletion(req interface{}) (string, error) {
 		return "", fmt.Errorf("error reading response: %w", err)
 	}
 
+	log.Printf("Tokens for %s: prompt %d (counted %d), response %d", chatReq.Model, promptEvalCount, promptTokens, evalCount)
+	c.calibrate(chatReq.Model, estimatedTokens, promptEvalCount)
+
 	// After getting a successful response, add it to history only if not stateless
 	if responseString != "" && !c.stateless {
 		c.AddMessage("assistant", responseString)
@@ -192,3 +250,80 @@ func (c *Client) ChatCompletion(req interface{}) (string, error) {
 
 	return responseString, nil
 }
+
+// redactMessages returns copies of messages with secrets replaced by
+// placeholders, leaving the history untouched
+func redactMessages(messages []Message) []Message {
+	redactor := redaction.Shared()
+
+	redacted := make([]Message, len(messages))
+	for i, message := range messages {
+		redacted[i] = Message{Role: message.Role, Content: redactor.Redact(message.Content)}
+	}
+	return redacted
+}
+
+// ContextWindow returns the context window a request runs with: its num_ctx option
+// when set, otherwise the model's default from /api/show
+func (c *Client) ContextWindow(req ChatRequest) int {
+	if numCtx, ok := req.Options["num_ctx"].(int); ok && numCtx > 0 {
+		return numCtx
+	}
+
+	info, err := c.ShowModel(req.Model)
+	if err != nil {
+		log.Printf("Warning: could not get model info for %s, assuming a %d token context: %v", req.Model, defaultNumCtx, err)
+		return defaultNumCtx
+	}
+
+	return info.ContextWindow()
+}
+
+// fitContextWindow counts the prompt tokens of a request and, when they leave too
+// little room for a reply, drops the oldest history messages. The first message,
+// which holds the task, and the newest message are always kept. The request's
+// messages are the redacted copy of the history, so both are trimmed at the same
+// index and only redacted text is counted. It returns the prompt token count
+// after trimming.
+func (c *Client) fitContextWindow(req *ChatRequest) int {
+	window := c.ContextWindow(*req)
+	limit := max(window-c.responseReserve, 0)
+
+	tokens, _ := c.CountMessageTokens(req.Model, req.Messages)
+	if tokens <= limit {
+		return tokens
+	}
+
+	if !c.stateless && len(req.Messages) == len(c.history) {
+		dropped := 0
+		messages := append([]Message{}, req.Messages...)
+		for tokens > limit && len(messages) > 2 {
+			removed, _ := c.CountTokens(req.Model, messages[1].Content)
+			tokens -= removed + messageOverheadTokens
+			messages = append(messages[:1], messages[2:]...)
+			c.history = append(c.history[:1], c.history[2:]...)
+			dropped++
+		}
+		req.Messages = messages
+
+		if dropped > 0 {
+			log.Printf("Warning: dropped %d history messages to fit the %d token context window of %s", dropped, window, req.Model)
+		}
+	}
+
+	if tokens > limit {
+		log.Printf("Warning: prompt of about %d tokens does not fit the %d token context window of %s; the model will not see all of it",
+			tokens, window, req.Model)
+	}
+
+	return tokens
+}
+
+// estimateMessageTokens returns the uncalibrated heuristic token count of messages
+func estimateMessageTokens(messages []Message) int {
+	total := 0
+	for _, message := range messages {
+		total += EstimateTokens(message.Content) + messageOverheadTokens
+	}
+	return total
+}
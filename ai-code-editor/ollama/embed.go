@@ -0,0 +1,73 @@
+package ollama
+
+import (
+	"ai-code-editor/redaction"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type EmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// Embed returns one embedding per input using a single request to /api/embed
+func (c *Client) Embed(model string, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return [][]float32{}, nil
+	}
+
+	redacted := make([]string, len(inputs))
+	for i, input := range inputs {
+		redacted[i] = redaction.Shared().Redact(input)
+	}
+
+	jsonData, err := json.Marshal(EmbedRequest{
+		Model: model,
+		Input: redacted,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embed request: %w", err)
+	}
+
+	request, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("%s/api/embed", c.baseURL),
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("server returned status code %d: %s", response.StatusCode, string(body))
+	}
+
+	var embedResponse EmbedResponse
+	if err := json.NewDecoder(response.Body).Decode(&embedResponse); err != nil {
+		return nil, fmt.Errorf("error parsing embed response: %w", err)
+	}
+
+	if len(embedResponse.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embedResponse.Embeddings))
+	}
+
+	return embedResponse.Embeddings, nil
+}
@@ -0,0 +1,125 @@
+package ollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultNumCtx is the context window Ollama loads a model with when neither
+// the request nor the model's parameters set num_ctx
+const defaultNumCtx = 4096
+
+// ModelInfo describes a model as reported by /api/show
+type ModelInfo struct {
+	Name          string
+	Family        string
+	ContextLength int // the most tokens the model was trained to handle
+	NumCtx        int // num_ctx from the model's parameters, 0 when unset
+}
+
+type showRequest struct {
+	Model string `json:"model"`
+}
+
+type showResponse struct {
+	Parameters string         `json:"parameters"`
+	ModelInfo  map[string]any `json:"model_info"`
+	Details    struct {
+		Family string `json:"family"`
+	} `json:"details"`
+}
+
+// ContextWindow returns the context window the model runs with by default
+func (m *ModelInfo) ContextWindow() int {
+	window := m.NumCtx
+	if window <= 0 {
+		window = defaultNumCtx
+	}
+	if m.ContextLength > 0 && window > m.ContextLength {
+		window = m.ContextLength
+	}
+	return window
+}
+
+// ShowModel returns information about a model from /api/show. Results are
+// cached for the lifetime of the client.
+func (c *Client) ShowModel(model string) (*ModelInfo, error) {
+	c.mu.Lock()
+	if info, ok := c.modelInfo[model]; ok {
+		c.mu.Unlock()
+		return info, nil
+	}
+	c.mu.Unlock()
+
+	jsonData, err := json.Marshal(showRequest{Model: model})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal show request: %w", err)
+	}
+
+	response, err := c.httpClient.Post(fmt.Sprintf("%s/api/show", c.baseURL), "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("server returned status code %d: %s", response.StatusCode, string(body))
+	}
+
+	var show showResponse
+	if err := json.NewDecoder(response.Body).Decode(&show); err != nil {
+		return nil, fmt.Errorf("error parsing show response: %w", err)
+	}
+
+	info := &ModelInfo{
+		Name:          model,
+		Family:        show.Details.Family,
+		ContextLength: contextLengthFromModelInfo(show.ModelInfo),
+		NumCtx:        numCtxFromParameters(show.Parameters),
+	}
+
+	c.mu.Lock()
+	c.modelInfo[model] = info
+	c.mu.Unlock()
+
+	return info, nil
+}
+
+// contextLengthFromModelInfo reads "<architecture>.context_length" from model_info
+func contextLengthFromModelInfo(modelInfo map[string]any) int {
+	architecture, _ := modelInfo["general.architecture"].(string)
+	if value, ok := modelInfo[architecture+".context_length"].(float64); ok {
+		return int(value)
+	}
+
+	// Fall back to any context length key when the architecture is missing
+	for key, value := range modelInfo {
+		if strings.HasSuffix(key, ".context_length") {
+			if length, ok := value.(float64); ok {
+				return int(length)
+			}
+		}
+	}
+
+	return 0
+}
+
+// numCtxFromParameters reads num_ctx from the Modelfile parameters text, which
+// has one "name value" pair per line
+func numCtxFromParameters(parameters string) int {
+	for _, line := range strings.Split(parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			if value, err := strconv.Atoi(fields[1]); err == nil {
+				return value
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package ollama
+
+import "testing"
+
+func TestNumCtxFromParameters(t *testing.T) {
+	parameters := "stop                           \"<|im_end|>\"\nnum_ctx                        16384\ntemperature                    0.7"
+	if numCtx := numCtxFromParameters(parameters); numCtx != 16384 {
+		t.Errorf("Expected 16384, got %d", numCtx)
+	}
+	if numCtx := numCtxFromParameters("temperature 0.7"); numCtx != 0 {
+		t.Errorf("Expected 0 when num_ctx is unset, got %d", numCtx)
+	}
+}
+
+func TestModelInfo_ContextWindow(t *testing.T) {
+	modelInfo := map[string]any{
+		"general.architecture": "qwen2",
+		"qwen2.context_length": float64(32768),
+	}
+	info := &ModelInfo{ContextLength: contextLengthFromModelInfo(modelInfo)}
+	if window := info.ContextWindow(); window != defaultNumCtx {
+		t.Errorf("Expected the default num_ctx %d, got %d", defaultNumCtx, window)
+	}
+
+	info.NumCtx = 65536
+	if window := info.ContextWindow(); window != 32768 {
+		t.Errorf("Expected num_ctx to be capped at the context length, got %d", window)
+	}
+}
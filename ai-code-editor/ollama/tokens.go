@@ -0,0 +1,196 @@
+package ollama
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// charsPerWordToken is the average number of characters a tokenizer packs into
+// a single token for identifier and word runs
+const charsPerWordToken = 4
+
+// maxTokenCounts bounds how many exact token counts a client remembers
+const maxTokenCounts = 4096
+
+// tokenCountKey identifies a text tokenized by a model without keeping the text
+type tokenCountKey struct {
+	model string
+	hash  [sha256.Size]byte
+}
+
+// knownContextLengths holds the maximum input size, in tokens, of common models
+// served by Ollama. It is used when the server cannot be asked directly.
+var knownContextLengths = map[string]int{
+	"nomic-embed-text":       8192,
+	"mxbai-embed-large":      512,
+	"all-minilm":             256,
+	"snowflake-arctic-embed": 512,
+	"bge-m3":                 8192,
+	"bge-large":              512,
+}
+
+// EstimateTokens approximates how many tokens a model tokenizer produces for text.
+// Word and number runs cost roughly one token per four characters, every
+// punctuation character costs one token and whitespace is free. This tracks code,
+// which is punctuation heavy, much better than a flat bytes-per-token ratio.
+func EstimateTokens(text string) int {
+	tokens := 0
+	wordLength := 0
+
+	flushWord := func() {
+		if wordLength > 0 {
+			tokens += (wordLength + charsPerWordToken - 1) / charsPerWordToken
+			wordLength = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			wordLength++
+		case unicode.IsSpace(r):
+			flushWord()
+		default:
+			flushWord()
+			tokens++
+		}
+	}
+	flushWord()
+
+	return tokens
+}
+
+// KnownContextLength returns the context length of a well known model, or 0 when
+// the model is not known. Tags such as ":latest" are ignored.
+func KnownContextLength(model string) int {
+	name := model
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+
+	return knownContextLengths[name]
+}
+
+// messageOverheadTokens approximates the tokens a chat template adds around each message
+const messageOverheadTokens = 4
+
+type tokenizeRequest struct {
+	Model   string `json:"model"`
+	Content string `json:"content"`
+}
+
+type tokenizeResponse struct {
+	Tokens []int `json:"tokens"`
+}
+
+// CountTokens returns the number of tokens text uses with model and whether the
+// count is exact. It asks the server's /api/tokenize endpoint when available and
+// otherwise falls back to EstimateTokens, scaled by what previous chat responses
+// reported for this model.
+func (c *Client) CountTokens(model, text string) (int, bool) {
+	// History messages are counted again on every turn, so exact counts are remembered
+	key := tokenCountKey{model: model, hash: sha256.Sum256([]byte(text))}
+	c.mu.Lock()
+	tokens, cached := c.tokenCounts[key]
+	c.mu.Unlock()
+	if cached {
+		return tokens, true
+	}
+
+	if tokens, err := c.tokenize(model, text); err == nil {
+		c.mu.Lock()
+		if len(c.tokenCounts) >= maxTokenCounts {
+			clear(c.tokenCounts)
+		}
+		c.tokenCounts[key] = tokens
+		c.mu.Unlock()
+		return tokens, true
+	}
+
+	c.mu.Lock()
+	factor, ok := c.calibration[model]
+	c.mu.Unlock()
+	if !ok {
+		factor = 1
+	}
+
+	return int(math.Ceil(float64(EstimateTokens(text)) * factor)), false
+}
+
+// CountMessageTokens returns the tokens a list of chat messages uses with model
+func (c *Client) CountMessageTokens(model string, messages []Message) (int, bool) {
+	total := 0
+	exact := true
+	for _, message := range messages {
+		tokens, messageExact := c.CountTokens(model, message.Content)
+		total += tokens + messageOverheadTokens
+		exact = exact && messageExact
+	}
+	return total, exact
+}
+
+// tokenize asks the server to tokenize text. Servers without the endpoint are
+// remembered so they are only asked once.
+func (c *Client) tokenize(model, text string) (int, error) {
+	c.mu.Lock()
+	unsupported := c.tokenizeUnsupported
+	c.mu.Unlock()
+	if unsupported {
+		return 0, fmt.Errorf("tokenize is not supported by the server")
+	}
+
+	jsonData, err := json.Marshal(tokenizeRequest{Model: model, Content: text})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal tokenize request: %w", err)
+	}
+
+	response, err := c.httpClient.Post(fmt.Sprintf("%s/api/tokenize", c.baseURL), "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusMethodNotAllowed {
+		c.mu.Lock()
+		c.tokenizeUnsupported = true
+		c.mu.Unlock()
+		return 0, fmt.Errorf("tokenize is not supported by the server")
+	}
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		return 0, fmt.Errorf("server returned status code %d: %s", response.StatusCode, string(body))
+	}
+
+	var tokenized tokenizeResponse
+	if err := json.NewDecoder(response.Body).Decode(&tokenized); err != nil {
+		return 0, fmt.Errorf("error parsing tokenize response: %w", err)
+	}
+
+	return len(tokenized.Tokens), nil
+}
+
+// calibrate adjusts the estimate scaling for model using an exact prompt token
+// count reported by the server for a prompt estimated at estimatedTokens
+func (c *Client) calibrate(model string, estimatedTokens, exactTokens int) {
+	if estimatedTokens <= 0 || exactTokens <= 0 {
+		return
+	}
+
+	ratio := float64(exactTokens) / float64(estimatedTokens)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if previous, ok := c.calibration[model]; ok {
+		// Smooth so one unusual prompt does not swing later estimates
+		ratio = 0.7*previous + 0.3*ratio
+	}
+	c.calibration[model] = ratio
+}
@@ -0,0 +1,229 @@
+package redaction
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// minEntropyLength is the shortest string checked for high entropy
+	minEntropyLength = 24
+	// minEntropyBits is the Shannon entropy per character above which a
+	// string looks random enough to be a key
+	minEntropyBits = 4.0
+)
+
+// placeholderPattern matches the placeholders Redact inserts
+var placeholderPattern = regexp.MustCompile(`\[REDACTED:[a-z0-9_]+:\d+\]`)
+
+// Rule finds secrets. When Group is not 0 only that capture group is the
+// secret, so "API_KEY=value" keeps its name. Validate, when set, can reject
+// a candidate.
+type Rule struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Group    int
+	Validate func(secret string) bool
+}
+
+// DefaultRules detect private keys, common API key formats, secret
+// assignments such as those in .env files and high-entropy strings
+func DefaultRules() []Rule {
+	return []Rule{
+		{Name: "private_key", Pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)},
+		{Name: "aws_access_key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{Name: "github_token", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+		{Name: "slack_token", Pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+		{Name: "api_key", Pattern: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+		{Name: "google_api_key", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+		{Name: "stripe_key", Pattern: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+		{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+		{Name: "connection_string", Pattern: regexp.MustCompile(`\b[a-z][a-z0-9+.-]*://[^\s:/@]+:([^\s@/]{3,})@`), Group: 1},
+		// KEY=value lines as found in .env files and shell scripts
+		{
+			Name:    "env_secret",
+			Pattern: regexp.MustCompile(`(?m)^[ \t]*(?:export[ \t]+)?[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|PRIVATE_?KEY|ACCESS_?KEY|CREDENTIALS?)[A-Z0-9_]*[ \t]*=[ \t]*["']?([^\s"'#]{4,})`),
+			Group:   1,
+		},
+		// Quoted strings assigned to a secret-looking name in code
+		{
+			Name:    "assigned_secret",
+			Pattern: regexp.MustCompile(`(?i)(?:secret|token|passw(?:or)?d|api_?key|private_?key|access_?key)\w*["']?\s*(?::=|=|:)\s*["'` + "`" + `]([^"'` + "`" + `\s]{8,})["'` + "`" + `]`),
+			Group:   1,
+		},
+		{
+			Name:     "high_entropy",
+			Pattern:  regexp.MustCompile(`[A-Za-z0-9+/_=-]{24,}`),
+			Validate: isHighEntropy,
+		},
+	}
+}
+
+// Redactor replaces secrets with placeholders such as [REDACTED:api_key:1]
+// and can put them back. The same secret always gets the same placeholder,
+// so a placeholder the model copies into an edit can be restored.
+type Redactor struct {
+	mu          sync.Mutex
+	enabled     bool
+	rules       []Rule
+	secrets     map[string]string // placeholder -> secret
+	placeholder map[string]string // secret -> placeholder
+}
+
+// NewRedactor creates an enabled redactor using rules
+func NewRedactor(rules []Rule) *Redactor {
+	return &Redactor{
+		enabled:     true,
+		rules:       rules,
+		secrets:     make(map[string]string),
+		placeholder: make(map[string]string),
+	}
+}
+
+var (
+	sharedRedactor     *Redactor
+	sharedRedactorOnce sync.Once
+)
+
+// Shared returns the process wide redactor, which uses DefaultRules. Prompts,
+// embeddings and edits go through it, so placeholders can be restored anywhere.
+func Shared() *Redactor {
+	sharedRedactorOnce.Do(func() {
+		sharedRedactor = NewRedactor(DefaultRules())
+	})
+	return sharedRedactor
+}
+
+// SetEnabled turns redaction on or off. Restore keeps working when it is off.
+func (r *Redactor) SetEnabled(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = enabled
+}
+
+// AddRule adds a rule matching pattern. The first capture group, if any, is
+// the secret.
+func (r *Redactor) AddRule(name, pattern string) error {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid redaction rule %s: %w", name, err)
+	}
+
+	group := 0
+	if regex.NumSubexp() > 0 {
+		group = 1
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, Rule{Name: ruleName(name), Pattern: regex, Group: group})
+	return nil
+}
+
+// Redact replaces the secrets in text with placeholders
+func (r *Redactor) Redact(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.enabled {
+		return text
+	}
+
+	for _, rule := range r.rules {
+		text = r.apply(rule, text)
+	}
+	return text
+}
+
+// Restore replaces the placeholders in text with the secrets they stand for.
+// Unknown placeholders are left as they are.
+func (r *Redactor) Restore(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if secret, ok := r.secrets[placeholder]; ok {
+			return secret
+		}
+		return placeholder
+	})
+}
+
+// apply redacts the matches of one rule, working from the end of the text so
+// earlier offsets stay valid
+func (r *Redactor) apply(rule Rule, text string) string {
+	matches := rule.Pattern.FindAllStringSubmatchIndex(text, -1)
+
+	for i := len(matches) - 1; i >= 0; i-- {
+		start, end := matches[i][2*rule.Group], matches[i][2*rule.Group+1]
+		if start < 0 {
+			continue
+		}
+
+		secret := text[start:end]
+		if placeholderPattern.MatchString(secret) || (rule.Validate != nil && !rule.Validate(secret)) {
+			continue
+		}
+
+		text = text[:start] + r.placeholderFor(rule.Name, secret) + text[end:]
+	}
+
+	return text
+}
+
+// placeholderFor returns the placeholder of secret, creating one if needed
+func (r *Redactor) placeholderFor(name, secret string) string {
+	if placeholder, ok := r.placeholder[secret]; ok {
+		return placeholder
+	}
+
+	placeholder := fmt.Sprintf("[REDACTED:%s:%d]", name, len(r.secrets)+1)
+	r.secrets[placeholder] = secret
+	r.placeholder[secret] = placeholder
+	return placeholder
+}
+
+// isHighEntropy reports whether s mixes letters and digits and looks random
+func isHighEntropy(s string) bool {
+	s = strings.TrimRight(s, "=")
+	if len(s) < minEntropyLength {
+		return false
+	}
+
+	hasLetter := strings.IndexFunc(s, func(r rune) bool { return r >= 'A' && r <= 'z' }) >= 0
+	hasDigit := strings.IndexFunc(s, func(r rune) bool { return r >= '0' && r <= '9' }) >= 0
+	if !hasLetter || !hasDigit {
+		return false
+	}
+
+	return shannonEntropy(s) >= minEntropyBits
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+
+	entropy := 0.0
+	length := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// ruleName makes a rule name usable inside a placeholder
+func ruleName(name string) string {
+	name = strings.ToLower(name)
+	name = regexp.MustCompile(`[^a-z0-9_]+`).ReplaceAllString(name, "_")
+	if name == "" {
+		return "custom"
+	}
+	return name
+}
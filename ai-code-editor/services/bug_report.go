@@ -0,0 +1,77 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// BugFinding is a likely bug found in a piece of code
+type BugFinding struct {
+	File           string  `json:"file"`
+	StartLine      int     `json:"start_line"`
+	EndLine        int     `json:"end_line"`
+	Symbol         string  `json:"symbol,omitempty"`
+	Explanation    string  `json:"explanation"`
+	Confidence     float64 `json:"confidence"` // from 0 to 1
+	SuggestedPatch string  `json:"suggested_patch,omitempty"`
+}
+
+// Location describes where the bug is, such as "services/store.go:12-14"
+func (b BugFinding) Location() string {
+	if b.EndLine > b.StartLine {
+		return fmt.Sprintf("%s:%d-%d", b.File, b.StartLine, b.EndLine)
+	}
+	return fmt.Sprintf("%s:%d", b.File, b.StartLine)
+}
+
+// SortBugFindings orders findings most confident first, then by location
+func SortBugFindings(findings []BugFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Confidence != findings[j].Confidence {
+			return findings[i].Confidence > findings[j].Confidence
+		}
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].StartLine < findings[j].StartLine
+	})
+}
+
+// WriteBugFindingsText prints findings for a terminal, with each suggested patch indented below
+func WriteBugFindingsText(out io.Writer, findings []BugFinding) error {
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(out, "No bugs found.")
+		return err
+	}
+
+	for _, finding := range findings {
+		location := finding.Location()
+		if finding.Symbol != "" {
+			location += " in " + finding.Symbol
+		}
+
+		if _, err := fmt.Fprintf(out, "%s (confidence %.0f%%)\n    %s\n", location, finding.Confidence*100,
+			strings.ReplaceAll(finding.Explanation, "\n", "\n    ")); err != nil {
+			return err
+		}
+		if finding.SuggestedPatch != "" {
+			fmt.Fprintf(out, "    suggested patch:\n        %s\n", strings.ReplaceAll(strings.TrimRight(finding.SuggestedPatch, "\n"), "\n", "\n        "))
+		}
+		fmt.Fprintln(out)
+	}
+
+	_, err := fmt.Fprintf(out, "%d possible bugs\n", len(findings))
+	return err
+}
+
+// WriteBugFindingsJSON writes {"bugs": [...]}
+func WriteBugFindingsJSON(out io.Writer, findings []BugFinding) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Bugs []BugFinding `json:"bugs"`
+	}{Bugs: findings})
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteBugFindingsText(t *testing.T) {
+	findings := []BugFinding{
+		{File: "a.go", StartLine: 3, EndLine: 3, Explanation: "Off by one", Confidence: 0.6},
+		{File: "b.go", StartLine: 7, EndLine: 9, Symbol: "Load", Explanation: "File is never closed", Confidence: 0.9, SuggestedPatch: "defer f.Close()"},
+	}
+	SortBugFindings(findings)
+	if findings[0].File != "b.go" {
+		t.Fatalf("Expected the most confident finding first, got %+v", findings)
+	}
+
+	var out bytes.Buffer
+	if err := WriteBugFindingsText(&out, findings); err != nil {
+		t.Fatalf("WriteBugFindingsText failed: %v", err)
+	}
+
+	text := out.String()
+	for _, expected := range []string{"b.go:7-9 in Load (confidence 90%)", "    File is never closed", "        defer f.Close()", "a.go:3 (confidence 60%)", "2 possible bugs"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected the output to contain %q, got:\n%s", expected, text)
+		}
+	}
+}
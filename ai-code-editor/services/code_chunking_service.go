@@ -2,50 +2,93 @@ package services
 
 import (
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ai-code-editor/config"
+	"ai-code-editor/ollama"
 )
 
 // CodeChunkingService handles splitting code into manageable chunks
 type CodeChunkingService struct {
-	defaultChunkSize int
+	chunkSize    int // maximum chunk size in estimated tokens
+	chunkOverlap int // tokens repeated from the end of the previous chunk
+}
+
+// CodeChunk is a contiguous span of a source file
+type CodeChunk struct {
+	Content   string
+	StartLine int // 1-based, inclusive
+	EndLine   int // 1-based, inclusive
+	Tokens    int
+}
+
+// chunkSegment is a whole line, or a piece of a line too long to fit in one chunk
+type chunkSegment struct {
+	text         string
+	line         int
+	tokens       int
+	continuation bool // true when the segment continues the previous segment's line
 }
 
-// NewCodeChunkingService creates a new instance of the code chunking service
-func NewCodeChunkingService(defaultChunkSize int) *CodeChunkingService {
-	if defaultChunkSize <= 0 {
-		defaultChunkSize = 1000 // Default chunk size if invalid value provided
+// NewCodeChunkingService creates a new instance of the code chunking service.
+// Chunk size and overlap come from the config, which validates them, and the
+// chunk size is capped at the context length of the configured embed model.
+func NewCodeChunkingService(cfg *config.Config) *CodeChunkingService {
+	chunkSize := max(cfg.ChunkSizeTokens, 1)
+	if maxTokens := ollama.KnownContextLength(cfg.EmbedModel); maxTokens > 0 && chunkSize > maxTokens {
+		chunkSize = maxTokens
+	}
+
+	chunkOverlap := max(cfg.ChunkOverlapTokens, 0)
+	// The overlap must leave room for new content in every chunk
+	if chunkOverlap >= chunkSize {
+		chunkOverlap = chunkSize / 2
 	}
 
 	return &CodeChunkingService{
-		defaultChunkSize: defaultChunkSize,
+		chunkSize:    chunkSize,
+		chunkOverlap: chunkOverlap,
 	}
 }
 
-// SplitCodeIntoChunks splits code into chunks of approximately the given size
-func (s *CodeChunkingService) SplitCodeIntoChunks(code string, chunkSize int) []string {
-	if chunkSize <= 0 {
-		chunkSize = s.defaultChunkSize
-	}
+// SplitCodeIntoChunks splits code into chunks of at most the configured number of
+// tokens. Chunks break on line boundaries where possible and each chunk starts
+// with up to the configured overlap of trailing lines from the previous chunk.
+func (s *CodeChunkingService) SplitCodeIntoChunks(code string) []CodeChunk {
+	segments := s.splitIntoSegments(code)
+	chunks := make([]CodeChunk, 0)
 
-	lines := strings.Split(code, "\n")
-	chunks := make([]string, 0)
-
-	currentChunk := ""
-	for _, line := range lines {
-		// If adding this line would exceed chunk size, start a new chunk
-		if len(currentChunk)+len(line)+1 > chunkSize && len(currentChunk) > 0 {
-			chunks = append(chunks, currentChunk)
-			currentChunk = line
-		} else {
-			if len(currentChunk) > 0 {
-				currentChunk += "\n"
+	current := make([]chunkSegment, 0)
+	currentTokens := 0
+	freshSegments := 0 // segments in current that were not carried over as overlap
+
+	for _, segment := range segments {
+		// If adding this segment would exceed chunk size, start a new chunk
+		if currentTokens+segment.tokens > s.chunkSize && freshSegments > 0 {
+			chunks = append(chunks, buildChunk(current, currentTokens))
+			current = s.overlapTail(current)
+			currentTokens = sumSegmentTokens(current)
+			freshSegments = 0
+
+			// Drop overlap that would not leave room for the next segment
+			for len(current) > 0 && currentTokens+segment.tokens > s.chunkSize {
+				currentTokens -= current[0].tokens
+				current = current[1:]
 			}
-			currentChunk += line
 		}
+
+		current = append(current, segment)
+		currentTokens += segment.tokens
+		freshSegments++
 	}
 
-	// Add the last chunk if it's not empty
-	if len(currentChunk) > 0 {
-		chunks = append(chunks, currentChunk)
+	// Add the last chunk unless it only repeats the previous chunk's overlap
+	if freshSegments > 0 {
+		chunk := buildChunk(current, currentTokens)
+		if strings.TrimSpace(chunk.Content) != "" {
+			chunks = append(chunks, chunk)
+		}
 	}
 
 	return chunks
@@ -55,22 +98,158 @@ func (s *CodeChunkingService) SplitCodeIntoChunks(code string, chunkSize int) []
 func (s *CodeChunkingService) ChunkCodeWithMetadata(
 	code string,
 	baseMetadata map[string]interface{},
-	chunkSize int,
 ) ([]string, []map[string]interface{}) {
-	chunks := s.SplitCodeIntoChunks(code, chunkSize)
-	metadataList := make([]map[string]interface{}, len(chunks))
+	codeChunks := s.SplitCodeIntoChunks(code)
+	chunks := make([]string, len(codeChunks))
+	metadataList := make([]map[string]interface{}, len(codeChunks))
+
+	for i, chunk := range codeChunks {
+		chunks[i] = chunk.Content
 
-	for i := range chunks {
 		// Copy base metadata for each chunk
 		chunkMetadata := copyMetadata(baseMetadata)
 		chunkMetadata["chunk_index"] = i
-		chunkMetadata["total_chunks"] = len(chunks)
+		chunkMetadata["total_chunks"] = len(codeChunks)
+		chunkMetadata["start_line"] = chunk.StartLine
+		chunkMetadata["end_line"] = chunk.EndLine
+		chunkMetadata["symbol_kind"] = string(DetectSymbolKind(chunk.Content))
 		metadataList[i] = chunkMetadata
 	}
 
 	return chunks, metadataList
 }
 
+// splitIntoSegments turns code into line segments, breaking any line whose
+// estimated size exceeds the chunk size into several segments
+func (s *CodeChunkingService) splitIntoSegments(code string) []chunkSegment {
+	lines := strings.Split(code, "\n")
+	segments := make([]chunkSegment, 0, len(lines))
+
+	for i, line := range lines {
+		tokens := ollama.EstimateTokens(line)
+		if tokens <= s.chunkSize {
+			segments = append(segments, chunkSegment{text: line, line: i + 1, tokens: tokens})
+			continue
+		}
+
+		for j, piece := range s.splitLongLine(line) {
+			segments = append(segments, chunkSegment{
+				text:         piece,
+				line:         i + 1,
+				tokens:       ollama.EstimateTokens(piece),
+				continuation: j > 0,
+			})
+		}
+	}
+
+	return segments
+}
+
+// splitLongLine breaks a single line into pieces that each fit in a chunk.
+// It prefers to cut after whitespace or punctuation and only cuts inside a
+// word when the word alone is larger than a chunk.
+func (s *CodeChunkingService) splitLongLine(line string) []string {
+	pieces := make([]string, 0)
+
+	for len(line) > 0 {
+		if ollama.EstimateTokens(line) <= s.chunkSize {
+			pieces = append(pieces, line)
+			break
+		}
+
+		cut := s.largestFittingPrefix(line)
+		if boundary := lastBoundary(line[:cut]); boundary > 0 {
+			cut = boundary
+		}
+
+		pieces = append(pieces, line[:cut])
+		line = line[cut:]
+	}
+
+	return pieces
+}
+
+// largestFittingPrefix returns the byte length of the longest prefix of text that
+// fits in a chunk. At least one rune is always returned so splitting makes progress.
+func (s *CodeChunkingService) largestFittingPrefix(text string) int {
+	// runeOffsets[n] is the byte length of the prefix holding n runes
+	runeOffsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		runeOffsets = append(runeOffsets, i)
+	}
+	runeOffsets = append(runeOffsets, len(text))
+
+	// Token estimates only grow as the prefix grows, so binary search the rune count
+	low, high := 1, len(runeOffsets)-1
+	for low < high {
+		mid := (low + high + 1) / 2
+		if ollama.EstimateTokens(text[:runeOffsets[mid]]) <= s.chunkSize {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	return runeOffsets[low]
+}
+
+// overlapTail returns the trailing segments of a finished chunk that fit in the overlap
+func (s *CodeChunkingService) overlapTail(segments []chunkSegment) []chunkSegment {
+	if s.chunkOverlap <= 0 {
+		return make([]chunkSegment, 0)
+	}
+
+	tokens := 0
+	start := len(segments)
+	for start > 0 && tokens+segments[start-1].tokens <= s.chunkOverlap {
+		tokens += segments[start-1].tokens
+		start--
+	}
+
+	tail := make([]chunkSegment, len(segments)-start)
+	copy(tail, segments[start:])
+	return tail
+}
+
+// lastBoundary returns the byte offset just after the last whitespace or
+// punctuation character in text, or 0 when there is none
+func lastBoundary(text string) int {
+	for i := len(text); i > 0; {
+		r, size := utf8.DecodeLastRuneInString(text[:i])
+		if unicode.IsSpace(r) || (unicode.IsPunct(r) && r != '_') {
+			return i
+		}
+		i -= size
+	}
+
+	return 0
+}
+
+func buildChunk(segments []chunkSegment, tokens int) CodeChunk {
+	var content strings.Builder
+	for i, segment := range segments {
+		if i > 0 && !segment.continuation {
+			content.WriteString("\n")
+		}
+		content.WriteString(segment.text)
+	}
+
+	return CodeChunk{
+		Content:   content.String(),
+		StartLine: segments[0].line,
+		EndLine:   segments[len(segments)-1].line,
+		Tokens:    tokens,
+	}
+}
+
+func sumSegmentTokens(segments []chunkSegment) int {
+	total := 0
+	for _, segment := range segments {
+		total += segment.tokens
+	}
+	return total
+}
+
 // Helper function to copy metadata map
 func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
 	if metadata == nil {
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"ai-code-editor/config"
+	"ai-code-editor/ollama"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Helper function to create a chunking service with the given token sizes
+func newTestChunkingService(chunkSize, chunkOverlap int) *CodeChunkingService {
+	return NewCodeChunkingService(&config.Config{
+		EmbedModel:         "nomic-embed-text",
+		ChunkSizeTokens:    chunkSize,
+		ChunkOverlapTokens: chunkOverlap,
+	})
+}
+
+// Helper function to build numbered lines of code
+func numberedLines(count int) string {
+	lines := make([]string, count)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("value%d := compute(%d)", i+1, i+1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestSplitCodeIntoChunks_Empty(t *testing.T) {
+	service := newTestChunkingService(50, 10)
+
+	chunks := service.SplitCodeIntoChunks("")
+	if len(chunks) != 0 {
+		t.Errorf("Expected no chunks, got %d", len(chunks))
+	}
+}
+
+func TestSplitCodeIntoChunks_SmallInputIsOneChunk(t *testing.T) {
+	service := newTestChunkingService(50, 10)
+	code := "package main\n\nfunc main() {}"
+
+	chunks := service.SplitCodeIntoChunks(code)
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+
+	if chunks[0].Content != code {
+		t.Errorf("Expected:\n%s\nGot:\n%s", code, chunks[0].Content)
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 3 {
+		t.Errorf("Expected lines 1-3, got %d-%d", chunks[0].StartLine, chunks[0].EndLine)
+	}
+}
+
+func TestSplitCodeIntoChunks_RespectsTokenLimit(t *testing.T) {
+	service := newTestChunkingService(40, 0)
+
+	chunks := service.SplitCodeIntoChunks(numberedLines(50))
+	if len(chunks) < 2 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if tokens := ollama.EstimateTokens(chunk.Content); tokens > 40 {
+			t.Errorf("Chunk %d has %d tokens, limit is 40", i, tokens)
+		}
+	}
+
+	// Without overlap every line appears exactly once and chunks are contiguous
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].StartLine != chunks[i-1].EndLine+1 {
+			t.Errorf("Chunk %d starts at line %d, previous chunk ended at %d", i, chunks[i].StartLine, chunks[i-1].EndLine)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.EndLine != 50 {
+		t.Errorf("Expected last chunk to end at line 50, got %d", last.EndLine)
+	}
+}
+
+func TestSplitCodeIntoChunks_Overlap(t *testing.T) {
+	service := newTestChunkingService(40, 15)
+
+	chunks := service.SplitCodeIntoChunks(numberedLines(30))
+	if len(chunks) < 2 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+
+	for i := 1; i < len(chunks); i++ {
+		previous, current := chunks[i-1], chunks[i]
+		if current.StartLine > previous.EndLine {
+			t.Errorf("Chunk %d (lines %d-%d) does not overlap chunk %d (lines %d-%d)",
+				i, current.StartLine, current.EndLine, i-1, previous.StartLine, previous.EndLine)
+		}
+		if current.EndLine <= previous.EndLine {
+			t.Errorf("Chunk %d adds no new lines after line %d", i, previous.EndLine)
+		}
+
+		lastLine := previous.Content[strings.LastIndex(previous.Content, "\n")+1:]
+		if !strings.HasPrefix(current.Content, lastLine) && !strings.Contains(current.Content, "\n"+lastLine) {
+			t.Errorf("Expected chunk %d to repeat line %q from the previous chunk", i, lastLine)
+		}
+	}
+}
+
+func TestSplitCodeIntoChunks_VeryLongSingleLine(t *testing.T) {
+	service := newTestChunkingService(30, 5)
+	line := strings.Repeat("callSomething(argument), ", 100)
+
+	chunks := service.SplitCodeIntoChunks(line)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the long line to be split, got %d chunk(s)", len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if tokens := ollama.EstimateTokens(chunk.Content); tokens > 30 {
+			t.Errorf("Chunk %d has %d tokens, limit is 30", i, tokens)
+		}
+		if chunk.StartLine != 1 || chunk.EndLine != 1 {
+			t.Errorf("Chunk %d should map to line 1, got %d-%d", i, chunk.StartLine, chunk.EndLine)
+		}
+		if strings.Contains(chunk.Content, "\n") {
+			t.Errorf("Chunk %d introduced a line break inside a single line", i)
+		}
+	}
+}
+
+func TestSplitCodeIntoChunks_LongLineWithoutBoundaries(t *testing.T) {
+	service := newTestChunkingService(10, 0)
+	word := strings.Repeat("a", 200)
+
+	chunks := service.SplitCodeIntoChunks(word)
+
+	joined := ""
+	for i, chunk := range chunks {
+		if tokens := ollama.EstimateTokens(chunk.Content); tokens > 10 {
+			t.Errorf("Chunk %d has %d tokens, limit is 10", i, tokens)
+		}
+		joined += chunk.Content
+	}
+
+	if joined != word {
+		t.Errorf("Expected chunks to reassemble the original word, got %d characters", len(joined))
+	}
+}
+
+func TestSplitCodeIntoChunks_LongLineBetweenShortLines(t *testing.T) {
+	service := newTestChunkingService(20, 0)
+	code := "first()\n" + strings.Repeat("x + ", 60) + "\nlast()"
+
+	chunks := service.SplitCodeIntoChunks(code)
+
+	if chunks[0].StartLine != 1 {
+		t.Errorf("Expected first chunk to start at line 1, got %d", chunks[0].StartLine)
+	}
+	last := chunks[len(chunks)-1]
+	if last.EndLine != 3 || !strings.HasSuffix(last.Content, "last()") {
+		t.Errorf("Expected last chunk to end with line 3, got line %d:\n%s", last.EndLine, last.Content)
+	}
+}
+
+func TestNewCodeChunkingService_ClampsToEmbedModel(t *testing.T) {
+	service := NewCodeChunkingService(&config.Config{
+		EmbedModel:         "all-minilm:latest",
+		ChunkSizeTokens:    4096,
+		ChunkOverlapTokens: 8192,
+	})
+
+	if service.chunkSize != 256 {
+		t.Errorf("Expected chunk size to be capped at 256, got %d", service.chunkSize)
+	}
+	if service.chunkOverlap >= service.chunkSize {
+		t.Errorf("Expected overlap below chunk size, got %d", service.chunkOverlap)
+	}
+}
+
+func TestChunkCodeWithMetadata(t *testing.T) {
+	service := newTestChunkingService(40, 0)
+
+	chunks, metadatas := service.ChunkCodeWithMetadata(numberedLines(20), map[string]interface{}{"path": "main.go"})
+	if len(chunks) != len(metadatas) {
+		t.Fatalf("Expected one metadata entry per chunk, got %d chunks and %d metadatas", len(chunks), len(metadatas))
+	}
+
+	for i, metadata := range metadatas {
+		if metadata["path"] != "main.go" {
+			t.Errorf("Expected path to be copied into chunk %d metadata", i)
+		}
+		if metadata["chunk_index"] != i || metadata["total_chunks"] != len(chunks) {
+			t.Errorf("Unexpected chunk position in metadata: %v", metadata)
+		}
+		if _, ok := metadata["start_line"].(int); !ok {
+			t.Errorf("Expected start_line in chunk %d metadata", i)
+		}
+	}
+}
@@ -5,16 +5,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"ai-code-editor/config"
+	"ai-code-editor/ollama"
+	"ai-code-editor/redaction"
 
 	chromago "github.com/amikos-tech/chroma-go"
 	"github.com/amikos-tech/chroma-go/collection"
-	ollama "github.com/amikos-tech/chroma-go/pkg/embeddings/ollama"
+	"github.com/amikos-tech/chroma-go/types"
 )
 
 // CodeEmbeddingService handles embedding and retrieving code using Chroma and Ollama
@@ -23,8 +26,22 @@ type CodeEmbeddingService struct {
 	collectionName    string
 	collection        *chromago.Collection
 	chromaURL         string
-	embeddingFunction *ollama.OllamaEmbeddingFunction
+	httpClient        *http.Client
+	embeddingFunction *OllamaEmbeddingFunction
 	chunkingService   *CodeChunkingService
+	lexicalIndex      *LexicalIndex
+	batchSize         int
+
+	pendingMu sync.Mutex
+	pending   []codeRecord // chunks waiting to be embedded with chunks of other files
+}
+
+// codeRecord is an embedded document ready to be written to the vector database
+type codeRecord struct {
+	id        string
+	document  string
+	metadata  map[string]interface{}
+	embedding []float32
 }
 
 // NewCodeEmbeddingService creates a new instance of the code embedding service
@@ -40,10 +57,7 @@ func NewCodeEmbeddingService(cfg *config.Config, collectionName string) (*CodeEm
 		return nil, fmt.Errorf("failed to create Chroma client: %w", err)
 	}
 
-	ef, err := ollama.NewOllamaEmbeddingFunction(ollama.WithBaseURL("http://127.0.0.1:11434"), ollama.WithModel("nomic-embed-text"))
-	if err != nil {
-		fmt.Printf("Error creating Ollama embedding function: %s \n", err)
-	}
+	ef := NewOllamaEmbeddingFunction(ollama.NewClient(cfg.OllamaBaseURL, true), cfg.EmbedModel)
 
 	// Create or get collection
 	collection, err := chromaClient.NewCollection(
@@ -58,15 +72,19 @@ func NewCodeEmbeddingService(cfg *config.Config, collectionName string) (*CodeEm
 	}
 
 	return &CodeEmbeddingService{
-		chromaClient:    chromaClient,
-		collectionName:  collectionName,
-		collection:      collection,
-		chromaURL:       chromaURL,
-		chunkingService: NewCodeChunkingService(1000), // Default 1000 chunk size
+		chromaClient:      chromaClient,
+		collectionName:    collectionName,
+		collection:        collection,
+		chromaURL:         chromaURL,
+		httpClient:        &http.Client{Timeout: 60 * time.Second},
+		embeddingFunction: ef,
+		chunkingService:   NewCodeChunkingService(cfg),
+		lexicalIndex:      NewLexicalIndex(),
+		batchSize:         max(cfg.EmbedBatchSize, 1),
 	}, nil
 }
 
-// StoreCode embeds and stores code in the vector database
+// StoreCode embeds and stores code in the vector database right away
 func (s *CodeEmbeddingService) StoreCode(filePath, code string, metadata map[string]interface{}) error {
 	if metadata == nil {
 		metadata = make(map[string]interface{})
@@ -77,27 +95,128 @@ func (s *CodeEmbeddingService) StoreCode(filePath, code string, metadata map[str
 		metadata["path"] = filePath
 	}
 
-	// Create a unique ID for this code snippet
-	id := fmt.Sprintf("%s-%d", strings.ReplaceAll(filePath, "/", "-"), len(code))
+	records := newCodeRecords([]string{codeID(filePath, code)}, []string{code}, []map[string]interface{}{metadata})
+	s.addLexical(records)
+	return s.storeRecords(records)
+}
 
-	// Use direct API call instead of the collection.Add method
-	endpoint := fmt.Sprintf("%s/api/v1/collections/%s/add", s.chromaURL, s.collectionName)
+// StoreCodeChunks splits code into chunks and queues them to be stored. Queued
+// chunks of all files are embedded and written batchSize at a time, so Flush
+// must be called after the last file. It returns the number of chunks queued.
+func (s *CodeEmbeddingService) StoreCodeChunks(filePath, code string, metadata map[string]interface{}) (int, error) {
+	// Use the chunking service to split the code and get metadata for each chunk
+	chunks, chunkMetadatas := s.chunkingService.ChunkCodeWithMetadata(code, metadata)
 
-	// Prepare request body
-	reqBody := map[string]interface{}{
-		"ids":       []string{id},
-		"metadatas": []map[string]interface{}{metadata},
-		"documents": []string{code},
+	ids := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		if _, exists := chunkMetadatas[i]["path"]; !exists {
+			chunkMetadatas[i]["path"] = filePath
+		}
+		ids[i] = codeID(fmt.Sprintf("%s-chunk-%d", filePath, i), chunk)
+	}
+
+	records := newCodeRecords(ids, chunks, chunkMetadatas)
+	s.addLexical(records)
+	return len(chunks), s.queue(records)
+}
+
+// addLexical adds records to the lexical index before they are embedded, so
+// lexical search keeps working when Ollama or Chroma is down
+func (s *CodeEmbeddingService) addLexical(records []codeRecord) {
+	for _, record := range records {
+		s.lexicalIndex.Add(record.id, record.document, record.metadata)
+	}
+}
+
+// Flush stores the chunks still waiting for a full batch
+func (s *CodeEmbeddingService) Flush() error {
+	s.pendingMu.Lock()
+	records := s.pending
+	s.pending = nil
+	s.pendingMu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+	return s.storeRecords(records)
+}
+
+// queue adds records to the pending ones and stores every full batch
+func (s *CodeEmbeddingService) queue(records []codeRecord) error {
+	s.pendingMu.Lock()
+	s.pending = append(s.pending, records...)
+	batches := make([][]codeRecord, 0)
+	for len(s.pending) >= s.batchSize {
+		batches = append(batches, s.pending[:s.batchSize:s.batchSize])
+		s.pending = s.pending[s.batchSize:]
+	}
+	s.pendingMu.Unlock()
+
+	// Batches are stored outside the lock so other files keep queueing meanwhile
+	for _, batch := range batches {
+		if err := s.storeRecords(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newCodeRecords pairs documents with their IDs and metadata. Stored documents
+// are shown to the model later, so they are kept without secrets.
+func newCodeRecords(ids []string, documents []string, metadatas []map[string]interface{}) []codeRecord {
+	records := make([]codeRecord, len(documents))
+	for i, document := range documents {
+		records[i] = codeRecord{
+			id:       ids[i],
+			document: redaction.Shared().Redact(document),
+			metadata: metadatas[i],
+		}
+	}
+	return records
+}
+
+// storeRecords embeds records with one request to Ollama and writes them to the
+// vector database with one request to Chroma
+func (s *CodeEmbeddingService) storeRecords(records []codeRecord) error {
+	documents := make([]string, len(records))
+	for i, record := range records {
+		documents[i] = record.document
 	}
 
-	// Get embedding from Ollama
-	embedding, err := s.embeddingFunction.EmbedDocuments(context.Background(), []string{code})
+	embeddings, err := s.embeddingFunction.Embed(context.Background(), documents)
 	if err != nil {
-		return fmt.Errorf("failed to get embedding: %w", err)
+		return fmt.Errorf("failed to get embeddings: %w", err)
+	}
+	for i := range records {
+		records[i].embedding = embeddings[i]
+	}
+
+	return s.addRecords(records)
+}
+
+// addRecords writes a batch of embedded records to the collection with a single request
+func (s *CodeEmbeddingService) addRecords(records []codeRecord) error {
+	// Use direct API call instead of the collection.Add method
+	endpoint := fmt.Sprintf("%s/api/v1/collections/%s/add", s.chromaURL, s.collection.ID)
+
+	ids := make([]string, len(records))
+	metadatas := make([]map[string]interface{}, len(records))
+	documents := make([]string, len(records))
+	embeddings := make([][]float32, len(records))
+	for i, record := range records {
+		ids[i] = record.id
+		metadatas[i] = record.metadata
+		documents[i] = record.document
+		embeddings[i] = record.embedding
 	}
 
-	// Add embedding to request
-	reqBody["embeddings"] = [][]float32{*embedding[0].GetFloat32()}
+	// Prepare request body
+	reqBody := map[string]interface{}{
+		"ids":        ids,
+		"metadatas":  metadatas,
+		"documents":  documents,
+		"embeddings": embeddings,
+	}
 
 	// Send request
 	jsonData, err := json.Marshal(reqBody)
@@ -105,83 +224,72 @@ func (s *CodeEmbeddingService) StoreCode(filePath, code string, metadata map[str
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := http.Post(endpoint, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := s.httpClient.Post(endpoint, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to add to collection: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("failed to add to collection: %s", string(body))
 	}
 
-	log.Printf("Stored code from %s in vector database", filePath)
 	return nil
 }
 
-// StoreCodeChunks splits code into chunks and stores them
-func (s *CodeEmbeddingService) StoreCodeChunks(filePath, code string, chunkSize int, metadata map[string]interface{}) error {
-	// Use the chunking service to split the code and get metadata for each chunk
-	chunks, chunkMetadatas := s.chunkingService.ChunkCodeWithMetadata(code, metadata, chunkSize)
-
-	// Store each chunk
-	for i, chunk := range chunks {
-		err := s.StoreCode(
-			fmt.Sprintf("%s-chunk-%d", filePath, i),
-			chunk,
-			chunkMetadatas[i],
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+// codeID creates a unique ID for a code snippet
+func codeID(filePath, code string) string {
+	return fmt.Sprintf("%s-%d", strings.ReplaceAll(filePath, "/", "-"), len(code))
 }
 
-// QuerySimilarCode finds similar code based on a query
-func (s *CodeEmbeddingService) QuerySimilarCode(query string, limit int) ([]map[string]interface{}, error) {
+// QuerySimilarCode finds similar code based on a query, most similar first.
+// Scores are 1/(1+distance), so they fall in (0, 1] and grow with similarity.
+// A nil filter searches everything.
+func (s *CodeEmbeddingService) QuerySimilarCode(query string, limit int, filter *SearchFilter) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 5 // Default limit
 	}
 
-	// Query parameters
-	queryParams := map[string]interface{}{
-		"where": map[string]interface{}{}, // No filtering
-	}
-	includeParams := map[string]interface{}{
-		"documents": true,
-		"metadatas": true,
-		"distances": true,
-	}
-
 	// Query the collection
 	results, err := s.collection.Query(
 		context.Background(),
 		[]string{query}, // Query text
 		int32(limit),
-		queryParams,
-		includeParams,
-		nil, // No query enum needed
+		filter.Where(),
+		nil, // No document filtering
+		[]types.QueryEnum{types.IDocuments, types.IMetadatas, types.IDistances},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query collection: %w", err)
 	}
 
-	// Format results
-	formattedResults := make([]map[string]interface{}, 0)
-	for i, doc := range results.Documents {
-		if i >= len(results.Metadatas) || i >= len(results.Distances) {
+	// Results are grouped per query text and we only sent one
+	formattedResults := make([]SearchResult, 0)
+	if len(results.Documents) == 0 {
+		return formattedResults, nil
+	}
+
+	documents := results.Documents[0]
+	for i, doc := range documents {
+		if i >= len(results.Ids[0]) || i >= len(results.Metadatas[0]) || i >= len(results.Distances[0]) {
 			break
 		}
 
-		formattedResults = append(formattedResults, map[string]interface{}{
-			"document": doc,
-			"metadata": results.Metadatas[i],
-			"distance": results.Distances[i],
-		})
+		score := 1 / (1 + float64(results.Distances[0][i]))
+		formattedResults = append(formattedResults, newSearchResult(results.Ids[0][i], doc, results.Metadatas[0][i], score))
 	}
 
 	return formattedResults, nil
 }
+
+// QueryLexicalCode finds code sharing terms with the query using the BM25 index
+// built while storing code. Only code stored by this process is searched.
+// A nil filter searches everything.
+func (s *CodeEmbeddingService) QueryLexicalCode(query string, limit int, filter *SearchFilter) []SearchResult {
+	if limit <= 0 {
+		limit = 5 // Default limit
+	}
+
+	return s.lexicalIndex.Search(query, limit, filter.Matches)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"ai-code-editor/ollama"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	chromago "github.com/amikos-tech/chroma-go"
+)
+
+func TestCodeEmbeddingService_BatchesAcrossFiles(t *testing.T) {
+	var mu sync.Mutex
+	embedSizes := make([]int, 0)
+	addSizes := make([]int, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/api/embed":
+			var request ollama.EmbedRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			embedSizes = append(embedSizes, len(request.Input))
+
+			embeddings := make([][]float32, len(request.Input))
+			for i := range embeddings {
+				embeddings[i] = []float32{1, 0}
+			}
+			json.NewEncoder(w).Encode(ollama.EmbedResponse{Embeddings: embeddings})
+		case "/api/v1/collections/test/add":
+			var request struct {
+				IDs []string `json:"ids"`
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+			addSizes = append(addSizes, len(request.IDs))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	service := &CodeEmbeddingService{
+		collection:        &chromago.Collection{ID: "test"},
+		chromaURL:         server.URL,
+		httpClient:        &http.Client{Timeout: 5 * time.Second},
+		embeddingFunction: NewOllamaEmbeddingFunction(ollama.NewClient(server.URL, true), "test"),
+		chunkingService:   newTestChunkingService(1000, 0),
+		lexicalIndex:      NewLexicalIndex(),
+		batchSize:         3,
+	}
+
+	// One chunk per file, so only batching across files fills a batch
+	for _, name := range []string{"a.go", "b.go", "c.go", "d.go"} {
+		if _, err := service.StoreCodeChunks(name, "package "+strings.TrimSuffix(name, ".go")+"\n", nil); err != nil {
+			t.Fatalf("StoreCodeChunks failed: %v", err)
+		}
+	}
+	if err := service.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(embedSizes) != 2 || embedSizes[0] != 3 || embedSizes[1] != 1 {
+		t.Errorf("Expected an embed request of 3 chunks and one of the last chunk, got %v", embedSizes)
+	}
+	if len(addSizes) != 2 || addSizes[0] != 3 || addSizes[1] != 1 {
+		t.Errorf("Expected inserts batched like the embeddings, got %v", addSizes)
+	}
+	if results := service.QueryLexicalCode("package", 10, nil); len(results) != 4 {
+		t.Errorf("Expected all 4 chunks in the lexical index, got %d", len(results))
+	}
+}
+
+func TestCodeEmbeddingService_LexicalIndexWithoutBackends(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	service := &CodeEmbeddingService{
+		collection:        &chromago.Collection{ID: "test"},
+		chromaURL:         server.URL,
+		httpClient:        &http.Client{Timeout: 5 * time.Second},
+		embeddingFunction: NewOllamaEmbeddingFunction(ollama.NewClient(server.URL, true), "test"),
+		chunkingService:   newTestChunkingService(1000, 0),
+		lexicalIndex:      NewLexicalIndex(),
+		batchSize:         1,
+	}
+
+	if _, err := service.StoreCodeChunks("store.go", "func LoadInventory() {}\n", nil); err == nil {
+		t.Error("Expected storing to fail while Ollama is down")
+	}
+	if results := service.QueryLexicalCode("LoadInventory", 5, nil); len(results) != 1 {
+		t.Errorf("Expected the chunk to be searchable lexically, got %v", results)
+	}
+}
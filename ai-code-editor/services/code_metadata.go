@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SymbolKind is the kind of declaration a piece of code defines
+type SymbolKind string
+
+const (
+	SymbolKindFunction  SymbolKind = "function"
+	SymbolKindMethod    SymbolKind = "method"
+	SymbolKindClass     SymbolKind = "class"
+	SymbolKindInterface SymbolKind = "interface"
+	SymbolKindType      SymbolKind = "type"
+	SymbolKindConstant  SymbolKind = "constant"
+	SymbolKindVariable  SymbolKind = "variable"
+	SymbolKindNone      SymbolKind = "none"
+)
+
+// languagesByExtension maps file extensions to language names
+var languagesByExtension = map[string]string{
+	".go":   "go",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".mjs":  "javascript",
+	".ts":   "typescript",
+	".tsx":  "typescript",
+	".py":   "python",
+	".java": "java",
+	".c":    "c",
+	".h":    "c",
+	".cpp":  "cpp",
+	".hpp":  "cpp",
+	".cs":   "csharp",
+	".php":  "php",
+	".rb":   "ruby",
+	".rs":   "rust",
+}
+
+// symbolKindPatterns recognizes the first declaration in a chunk. Order matters:
+// more specific patterns come first.
+var symbolKindPatterns = []struct {
+	kind    SymbolKind
+	pattern *regexp.Regexp
+}{
+	{SymbolKindMethod, regexp.MustCompile(`(?m)^\s*func\s*\([^)]*\)\s*\w+`)},
+	{SymbolKindFunction, regexp.MustCompile(`(?m)^\s*(?:export\s+)?(?:async\s+)?(?:func|function|def|fn|pub\s+fn)\s+\w+`)},
+	{SymbolKindInterface, regexp.MustCompile(`(?m)^\s*(?:export\s+)?(?:public\s+)?(?:type\s+\w+\s+interface\b|interface\s+\w+|trait\s+\w+)`)},
+	{SymbolKindClass, regexp.MustCompile(`(?m)^\s*(?:export\s+)?(?:public\s+|abstract\s+|sealed\s+|static\s+|partial\s+)*(?:type\s+\w+\s+struct\b|class\s+\w+|struct\s+\w+|record\s+\w+)`)},
+	{SymbolKindType, regexp.MustCompile(`(?m)^\s*(?:export\s+)?(?:type|enum)\s+\w+`)},
+}
+
+// LanguageForPath returns the language name for a file, or "" when unknown
+func LanguageForPath(path string) string {
+	return languagesByExtension[strings.ToLower(filepath.Ext(path))]
+}
+
+// IsTestFile reports whether a path looks like a test file in any of the
+// supported languages' conventions
+func IsTestFile(path string) bool {
+	slashPath := filepath.ToSlash(path)
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if dir == "test" || dir == "tests" || dir == "__tests__" {
+			return true
+		}
+	}
+
+	base := filepath.Base(slashPath)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+
+	switch {
+	case strings.HasSuffix(name, "_test"): // Go, Python
+		return true
+	case strings.HasPrefix(name, "test_"): // Python
+		return true
+	case strings.HasSuffix(name, ".test"), strings.HasSuffix(name, ".spec"): // JavaScript, TypeScript
+		return true
+	case (ext == ".java" || ext == ".cs") && (strings.HasSuffix(name, "Test") || strings.HasSuffix(name, "Tests")):
+		return true
+	}
+
+	return false
+}
+
+// DetectSymbolKind returns the kind of the first declaration found in a chunk of code
+func DetectSymbolKind(code string) SymbolKind {
+	kind := SymbolKindNone
+	first := -1
+
+	for _, candidate := range symbolKindPatterns {
+		location := candidate.pattern.FindStringIndex(code)
+		if location != nil && (first == -1 || location[0] < first) {
+			kind = candidate.kind
+			first = location[0]
+		}
+	}
+
+	return kind
+}
+
+// fileMetadata returns the metadata stored with every chunk of a file. Besides
+// its own dir, each ancestor directory is stored under dirKey of its depth, so
+// "services/git/x.go" has dir_1 "services" and dir_2 "services/git", letting
+// Chroma filter by path prefix with exact matches.
+func fileMetadata(relPath string) map[string]interface{} {
+	dir := filepath.ToSlash(filepath.Dir(relPath))
+	metadata := map[string]interface{}{
+		"path":      relPath,
+		"dir":       dir,
+		"extension": strings.ToLower(filepath.Ext(relPath)),
+		"language":  LanguageForPath(relPath),
+		"is_test":   IsTestFile(relPath),
+		"type":      "code",
+	}
+
+	if dir != "." {
+		parts := strings.Split(dir, "/")
+		for depth := 1; depth <= len(parts); depth++ {
+			metadata[dirKey(depth)] = strings.Join(parts[:depth], "/")
+		}
+	}
+
+	return metadata
+}
+
+// dirKey returns the metadata key of a file's ancestor directory at depth
+func dirKey(depth int) string {
+	return fmt.Sprintf("dir_%d", depth)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// CodeUnit is a piece of code analyzed on its own: a whole file, one symbol or
+// a search hit
+type CodeUnit struct {
+	Path      string
+	Name      string // the symbol, empty for files and search hits
+	StartLine int    // 1-based
+	EndLine   int
+	Code      string
+}
+
+// Location describes where the unit is, such as "services/store.go:10-42 (Store.Get)"
+func (u CodeUnit) Location() string {
+	location := fmt.Sprintf("%s:%d-%d", u.Path, u.StartLine, u.EndLine)
+	if u.Name != "" {
+		location += " (" + u.Name + ")"
+	}
+	return location
+}
+
+// NumberedCode returns the code with its line numbers in the file
+func (u CodeUnit) NumberedCode() string {
+	lines := strings.Split(strings.TrimSuffix(u.Code, "\n"), "\n")
+	numbered := make([]string, len(lines))
+	for i, line := range lines {
+		numbered[i] = fmt.Sprintf("%d: %s", u.StartLine+i, line)
+	}
+	return strings.Join(numbered, "\n")
+}
+
+// FileUnits returns every code file under dir as a unit. Ignored, oversized
+// and binary files and files matching skipPatterns are left out.
+func FileUnits(dir string, maxFileSize int64, skipPatterns []string) ([]CodeUnit, error) {
+	files, err := NewFileWalker(dir, maxFileSize, skipPatterns).Files(defaultCodeExtensions)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]CodeUnit, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Warning: failed to read %s: %v", file, err)
+			continue
+		}
+		if len(strings.TrimSpace(string(content))) == 0 {
+			continue
+		}
+
+		units = append(units, CodeUnit{
+			Path:      file,
+			StartLine: 1,
+			EndLine:   strings.Count(strings.TrimSuffix(string(content), "\n"), "\n") + 1,
+			Code:      string(content),
+		})
+	}
+
+	return units, nil
+}
+
+// SymbolUnits splits file units into their functions and methods, parsed
+// through parseCache, which is saved afterwards. Files without any, or in
+// languages without symbol support, are kept whole.
+func SymbolUnits(files []CodeUnit, parseCache *ParseCache) []CodeUnit {
+	units := make([]CodeUnit, 0, len(files))
+
+	for _, file := range files {
+		symbols, err := parseCache.Symbols(file.Path)
+		if err != nil {
+			log.Printf("Warning: failed to parse %s: %v", file.Path, err)
+		}
+		if SymbolLanguageForPath(file.Path) == nil || err != nil {
+			units = append(units, file)
+			continue
+		}
+
+		lines := strings.Split(file.Code, "\n")
+		found := false
+		for _, symbol := range symbols {
+			if symbol.Kind != SymbolKindFunction && symbol.Kind != SymbolKindMethod {
+				continue
+			}
+
+			start, end := max(symbol.StartLine, 1), min(symbol.EndLine, len(lines))
+			if start > end {
+				continue
+			}
+
+			units = append(units, CodeUnit{
+				Path:      file.Path,
+				Name:      symbol.Name,
+				StartLine: start,
+				EndLine:   end,
+				Code:      strings.Join(lines[start-1:end], "\n"),
+			})
+			found = true
+		}
+
+		if !found {
+			units = append(units, file)
+		}
+	}
+
+	if err := parseCache.Save(); err != nil {
+		log.Printf("Warning: failed to save parse cache: %v", err)
+	}
+
+	return units
+}
+
+// SearchResultUnits turns search hits into units
+func SearchResultUnits(results []SearchResult) []CodeUnit {
+	units := make([]CodeUnit, 0, len(results))
+	for _, result := range results {
+		if result.Path == "" || strings.TrimSpace(result.Content) == "" {
+			continue
+		}
+
+		startLine := max(result.StartLine, 1)
+		endLine := result.EndLine
+		if endLine < startLine {
+			endLine = startLine + strings.Count(result.Content, "\n")
+		}
+
+		units = append(units, CodeUnit{
+			Path:      result.Path,
+			StartLine: startLine,
+			EndLine:   endLine,
+			Code:      result.Content,
+		})
+	}
+	return units
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeUnits(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"store.go": `package store
+
+type Store struct{}
+
+func (s *Store) Get(key string) string {
+	return key
+}
+
+func New() *Store {
+	return &Store{}
+}
+`,
+		"types.go":     "package store\n\ntype ID int\n",
+		"empty.go":     "\n",
+		".env":         "TOKEN=secret\n",
+		"notes/a.txt":  "not code\n",
+		"secrets/k.go": "package secrets\n",
+	})
+
+	files, err := FileUnits(root, 0, []string{"secrets/"})
+	if err != nil {
+		t.Fatalf("FileUnits failed: %v", err)
+	}
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = filepath.Base(file.Path)
+	}
+	if strings.Join(paths, ",") != "store.go,types.go" {
+		t.Fatalf("Expected store.go and types.go, got %v", paths)
+	}
+	if store := files[0]; store.StartLine != 1 || store.EndLine != 11 {
+		t.Errorf("Expected store.go to span lines 1-11, got %d-%d", store.StartLine, store.EndLine)
+	}
+
+	parseCache := OpenParseCache(filepath.Join(t.TempDir(), "cache.json"))
+	t.Cleanup(parseCache.Close)
+
+	symbols := SymbolUnits(files, parseCache)
+	if len(symbols) != 3 {
+		t.Fatalf("Expected Get, New and the whole of types.go, got %+v", symbols)
+	}
+
+	get := symbols[0]
+	if !strings.Contains(get.Name, "Get") || get.StartLine != 5 || get.EndLine != 7 {
+		t.Errorf("Unexpected unit for Get: %+v", get)
+	}
+	if numbered := get.NumberedCode(); !strings.HasPrefix(numbered, "5: func (s *Store) Get") || !strings.HasSuffix(numbered, "7: }") {
+		t.Errorf("Unexpected numbered code:\n%s", numbered)
+	}
+	if symbols[2].Name != "" || filepath.Base(symbols[2].Path) != "types.go" {
+		t.Errorf("Expected types.go to be kept whole, got %+v", symbols[2])
+	}
+}
+
+func TestSearchResultUnits(t *testing.T) {
+	units := SearchResultUnits([]SearchResult{
+		{Path: "a.go", Content: "func A() {\n}", StartLine: 10, EndLine: 11},
+		{Path: "b.go", Content: "x\ny\nz"},
+		{Path: "", Content: "orphan"},
+	})
+
+	if len(units) != 2 {
+		t.Fatalf("Expected 2 units, got %+v", units)
+	}
+	if units[0].Location() != "a.go:10-11" {
+		t.Errorf("Unexpected location %s", units[0].Location())
+	}
+	if units[1].StartLine != 1 || units[1].EndLine != 3 {
+		t.Errorf("Expected lines 1-3 for a hit without positions, got %d-%d", units[1].StartLine, units[1].EndLine)
+	}
+}
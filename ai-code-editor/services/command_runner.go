@@ -0,0 +1,299 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCommandTimeout bounds how long one command may run
+	DefaultCommandTimeout = 2 * time.Minute
+	// DefaultMaxCommandOutput bounds the output kept from one command, in bytes
+	DefaultMaxCommandOutput = 16 * 1024
+)
+
+// delegatingFlags make an allowlisted tool run another program, so commands
+// using them always need confirmation
+var delegatingFlags = []string{"-exec", "-toolexec", "-overlay", "-vettool"}
+
+// delegatingLinkerFlags do the same from inside a -ldflags value
+var delegatingLinkerFlags = []string{"-extld", "-extldflags"}
+
+// CommandResult is the outcome of a command
+type CommandResult struct {
+	Command   string
+	Dir       string // relative to the root
+	ExitCode  int
+	Output    string // stdout and stderr combined
+	Truncated bool
+	TimedOut  bool
+}
+
+// CommandRunner runs commands requested by the model inside the project root.
+// Commands are executed directly, never through a shell, so they cannot be
+// chained or redirected. Commands not on the allowlist only run when confirm
+// approves them.
+type CommandRunner struct {
+	root      string
+	allowlist [][]string
+	timeout   time.Duration
+	maxOutput int
+	confirm   func(command string) bool
+}
+
+// NewCommandRunner creates a runner for the project at root. Allowlist entries
+// are command prefixes such as "go test"; confirm may be nil to refuse every
+// other command. A zero timeout or maxOutput uses the default.
+func NewCommandRunner(root string, allowlist []string, timeout time.Duration, maxOutput int, confirm func(command string) bool) *CommandRunner {
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	if maxOutput <= 0 {
+		maxOutput = DefaultMaxCommandOutput
+	}
+
+	prefixes := make([][]string, 0, len(allowlist))
+	for _, entry := range allowlist {
+		if fields, err := splitCommand(entry); err == nil && len(fields) > 0 {
+			prefixes = append(prefixes, fields)
+		}
+	}
+
+	return &CommandRunner{
+		root:      root,
+		allowlist: prefixes,
+		timeout:   timeout,
+		maxOutput: maxOutput,
+		confirm:   confirm,
+	}
+}
+
+// Run runs command in dir, a directory relative to the root. A command that
+// starts but fails is not an error; its exit code and output are in the result.
+func (r *CommandRunner) Run(command, dir string) (CommandResult, error) {
+	result := CommandResult{Command: command, Dir: dir}
+
+	args, err := splitCommand(command)
+	if err != nil {
+		return result, err
+	}
+	if len(args) == 0 {
+		return result, fmt.Errorf("empty command")
+	}
+
+	workDir, err := r.workDir(dir)
+	if err != nil {
+		return result, err
+	}
+
+	if !r.Allowed(args) && (r.confirm == nil || !r.confirm(command)) {
+		return result, fmt.Errorf("command %q is not allowed", command)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = workDir
+	// Do not wait forever for children that keep the output pipes open
+	cmd.WaitDelay = time.Second
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err = cmd.Run()
+	result.Output, result.Truncated = truncateOutput(output.String(), r.maxOutput)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		result.TimedOut = true
+		result.ExitCode = -1
+		return result, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+
+	return result, nil
+}
+
+// Allowed reports whether args start with one of the allowlisted commands,
+// pass no flag that runs other programs and name no path outside the working
+// directory, such as "go build -o /usr/bin/x" or "gofmt -w ../x.go"
+func (r *CommandRunner) Allowed(args []string) bool {
+	for i, arg := range args {
+		if hasFlag(arg, delegatingFlags) || escapesWorkDir(arg) {
+			return false
+		}
+
+		// -ldflags takes its value either after "=" or as the next argument
+		if hasFlag(arg, []string{"-ldflags"}) {
+			_, value, ok := strings.Cut(arg, "=")
+			if !ok && i+1 < len(args) {
+				value = args[i+1]
+			}
+			for _, linkerArg := range strings.Fields(value) {
+				if hasFlag(strings.Trim(linkerArg, `'"`), delegatingLinkerFlags) {
+					return false
+				}
+			}
+		}
+	}
+
+	for _, prefix := range r.allowlist {
+		if len(args) < len(prefix) {
+			continue
+		}
+
+		matches := true
+		for i, field := range prefix {
+			if args[i] != field {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasFlag reports whether arg is one of flags, with one or two dashes and
+// optionally a "=value"
+func hasFlag(arg string, flags []string) bool {
+	name, _, _ := strings.Cut(arg, "=")
+	for _, flag := range flags {
+		if name == flag || name == "-"+flag {
+			return true
+		}
+	}
+	return false
+}
+
+// escapesWorkDir reports whether arg, or the value of a "-flag=value"
+// argument, is an absolute path or climbs out of the working directory
+func escapesWorkDir(arg string) bool {
+	if strings.HasPrefix(arg, "-") {
+		_, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return false
+		}
+		arg = value
+	}
+
+	if filepath.IsAbs(arg) {
+		return true
+	}
+	for _, element := range strings.Split(filepath.ToSlash(arg), "/") {
+		if element == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// workDir resolves dir against the root, refusing directories outside it
+func (r *CommandRunner) workDir(dir string) (string, error) {
+	root, err := filepath.Abs(r.root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", r.root, err)
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	if filepath.IsAbs(dir) {
+		return "", fmt.Errorf("directory %s must be relative to the project root", dir)
+	}
+
+	workDir := filepath.Join(root, dir)
+	resolved, err := filepath.EvalSymlinks(workDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory %s: %w", dir, err)
+	}
+
+	relPath, err := filepath.Rel(root, resolved)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("directory %s is outside the project", dir)
+	}
+
+	return resolved, nil
+}
+
+// splitCommand splits a command line into arguments, honoring single and
+// double quotes and backslash escapes
+func splitCommand(command string) ([]string, error) {
+	args := make([]string, 0)
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == '\\' && i+1 < len(runes):
+			i++
+			current.WriteRune(runes[i])
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command %q", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// truncateOutput keeps the start and the end of output, where compilers and
+// test runners print the most useful lines
+func truncateOutput(output string, maxBytes int) (string, bool) {
+	if len(output) <= maxBytes {
+		return output, false
+	}
+
+	head := output[:maxBytes/2]
+	tail := output[len(output)-maxBytes/2:]
+	omitted := len(output) - len(head) - len(tail)
+
+	return fmt.Sprintf("%s\n... [truncated %d bytes] ...\n%s", head, omitted, tail), true
+}
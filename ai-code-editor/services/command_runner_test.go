@@ -0,0 +1,136 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"go test ./...", []string{"go", "test", "./..."}},
+		{`go test -run "TestA|TestB" ./services`, []string{"go", "test", "-run", "TestA|TestB", "./services"}},
+		{`echo 'a b' c\ d ""`, []string{"echo", "a b", "c d", ""}},
+		{"go build; rm -rf /", []string{"go", "build;", "rm", "-rf", "/"}},
+	}
+
+	for _, tt := range tests {
+		args, err := splitCommand(tt.command)
+		if err != nil {
+			t.Errorf("splitCommand(%q) failed: %v", tt.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, tt.expected) {
+			t.Errorf("splitCommand(%q) = %q, expected %q", tt.command, args, tt.expected)
+		}
+	}
+
+	if _, err := splitCommand(`echo "unterminated`); err == nil {
+		t.Error("Expected an error for an unterminated quote")
+	}
+}
+
+func TestCommandRunner_Allowed(t *testing.T) {
+	runner := NewCommandRunner(t.TempDir(), []string{"go test", "go vet", "go build", "gofmt"}, 0, 0, nil)
+
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"go test ./...", true},
+		{"gofmt -l .", true},
+		{"go run main.go", false},
+		{"go", false},
+		{"go test -exec rm ./...", false},
+		{"go test -toolexec=sh ./...", false},
+		{"go vet ./...", true},
+		{"go vet -vettool=/any/prog ./...", false},
+		{"go build -ldflags=-s ./...", true},
+		{"go build -ldflags=-extld=/any/prog ./...", false},
+		{`go build -ldflags "-s -extldflags=-static" ./...`, false},
+		{"go build -o bin/app .", true},
+		{"go build -o /abs/path .", false},
+		{"go build -o=../app .", false},
+		{"gofmt -w ../x.go", false},
+		{"gofmt -w internal/../x.go", false},
+	}
+
+	for _, tt := range tests {
+		args, _ := splitCommand(tt.command)
+		if allowed := runner.Allowed(args); allowed != tt.allowed {
+			t.Errorf("Allowed(%q) = %v, expected %v", tt.command, allowed, tt.allowed)
+		}
+	}
+}
+
+func TestCommandRunner_Run(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	confirmed := make([]string, 0)
+	runner := NewCommandRunner(root, []string{"pwd"}, time.Second, 0, func(command string) bool {
+		confirmed = append(confirmed, command)
+		return command == "sh -c 'exit 3'"
+	})
+
+	result, err := runner.Run("pwd", "sub")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.ExitCode != 0 || filepath.Base(strings.TrimSpace(result.Output)) != "sub" {
+		t.Errorf("Expected pwd to run in sub, got %+v", result)
+	}
+
+	result, err = runner.Run("sh -c 'exit 3'", "")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", result.ExitCode)
+	}
+
+	if _, err := runner.Run("ls", ""); err == nil {
+		t.Error("Expected a declined command to fail")
+	}
+	if !reflect.DeepEqual(confirmed, []string{"sh -c 'exit 3'", "ls"}) {
+		t.Errorf("Expected confirmation only for commands outside the allowlist, got %v", confirmed)
+	}
+
+	for _, dir := range []string{"..", "/tmp", "missing"} {
+		if _, err := runner.Run("pwd", dir); err == nil {
+			t.Errorf("Expected an error running in %s", dir)
+		}
+	}
+}
+
+func TestCommandRunner_Timeout(t *testing.T) {
+	runner := NewCommandRunner(t.TempDir(), []string{"sleep"}, 100*time.Millisecond, 0, nil)
+
+	result, err := runner.Run("sleep 5", "")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !result.TimedOut {
+		t.Errorf("Expected the command to time out, got %+v", result)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	output, truncated := truncateOutput("short", 10)
+	if truncated || output != "short" {
+		t.Errorf("Expected short output to be kept, got %q", output)
+	}
+
+	output, truncated = truncateOutput("head-"+strings.Repeat("x", 100)+"-tail", 10)
+	if !truncated || !strings.HasPrefix(output, "head-") || !strings.HasSuffix(output, "-tail") || !strings.Contains(output, "[truncated 100 bytes]") {
+		t.Errorf("Expected head and tail to be kept, got %q", output)
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai-code-editor/ollama"
+)
+
+// minTruncatedTokens is the smallest remaining budget worth filling with a
+// truncated item; below it the item is dropped instead
+const minTruncatedTokens = 64
+
+// ContextItemKind is the kind of content offered to the packer
+type ContextItemKind int
+
+const (
+	ContextChunk      ContextItemKind = iota // a retrieved snippet of a file
+	ContextSignatures                        // the declarations of a file
+	ContextFile                              // a whole file
+	ContextDiff                              // uncommitted or staged changes
+	ContextHistory                           // recent commit messages
+)
+
+func (k ContextItemKind) String() string {
+	switch k {
+	case ContextChunk:
+		return "chunk"
+	case ContextSignatures:
+		return "signatures"
+	case ContextDiff:
+		return "diff"
+	case ContextHistory:
+		return "history"
+	default:
+		return "file"
+	}
+}
+
+// ContextItem is a piece of code that may be included in a prompt
+type ContextItem struct {
+	Kind      ContextItemKind
+	Path      string
+	Content   string
+	StartLine int // for chunks, 1-based
+	EndLine   int
+	Relevance float64 // higher is packed first
+}
+
+// ContextSource offers context items for a task, such as requested files,
+// search results or uncommitted changes, to be packed into a prompt
+type ContextSource interface {
+	ContextItems(task string) ([]ContextItem, error)
+}
+
+// NormalizeRelevance rescales the relevance of one source's items to the range
+// 0 to 1, keeping their order, so items from sources scoring on different
+// scales can be packed together. Equal relevances all become 1.
+func NormalizeRelevance(items []ContextItem) []ContextItem {
+	if len(items) == 0 {
+		return items
+	}
+
+	lowest, highest := items[0].Relevance, items[0].Relevance
+	for _, item := range items[1:] {
+		lowest = min(lowest, item.Relevance)
+		highest = max(highest, item.Relevance)
+	}
+
+	normalized := make([]ContextItem, len(items))
+	for i, item := range items {
+		if highest > lowest {
+			item.Relevance = (item.Relevance - lowest) / (highest - lowest)
+		} else {
+			item.Relevance = 1
+		}
+		normalized[i] = item
+	}
+
+	return normalized
+}
+
+// PackedItem records how an item was packed
+type PackedItem struct {
+	Item       ContextItem
+	Tokens     int // tokens used in the packed text, 0 when dropped
+	Truncated  bool
+	Summarized bool
+}
+
+// PackedContext is the result of packing items into a token budget
+type PackedContext struct {
+	Text     string
+	Tokens   int
+	Budget   int
+	Included []PackedItem
+	Dropped  []PackedItem
+}
+
+// ContextPacker fits context items into a token budget. Items are taken in order
+// of relevance; an item that does not fit is summarized when a summarizer is
+// set, otherwise truncated, and dropped when neither fits.
+type ContextPacker struct {
+	budget     int
+	summarizer func(item ContextItem) (string, bool)
+}
+
+// NewContextPacker creates a packer for the given budget in tokens
+func NewContextPacker(budgetTokens int) *ContextPacker {
+	return &ContextPacker{budget: max(budgetTokens, 0)}
+}
+
+// ContextBudget returns the tokens left for context in a window of numCtx tokens
+// after reserving room for the model's reply and the rest of the prompt
+func ContextBudget(numCtx, reservedTokens int) int {
+	return max(numCtx-reservedTokens, 0)
+}
+
+// SetSummarizer sets a function that produces a shorter stand-in for an item,
+// such as a file's function signatures. It returns false when it has none.
+func (p *ContextPacker) SetSummarizer(summarizer func(item ContextItem) (string, bool)) {
+	p.summarizer = summarizer
+}
+
+// Pack renders as many items as fit in the budget, most relevant first. Chunks
+// win ties over signatures, and signatures over whole files.
+func (p *ContextPacker) Pack(items []ContextItem) *PackedContext {
+	ordered := make([]ContextItem, len(items))
+	copy(ordered, items)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Relevance != ordered[j].Relevance {
+			return ordered[i].Relevance > ordered[j].Relevance
+		}
+		return ordered[i].Kind < ordered[j].Kind
+	})
+
+	packed := &PackedContext{
+		Budget:   p.budget,
+		Included: make([]PackedItem, 0),
+		Dropped:  make([]PackedItem, 0),
+	}
+
+	var text strings.Builder
+	for _, item := range ordered {
+		remaining := p.budget - packed.Tokens
+
+		rendered := renderContextItem(item, item.Content)
+		tokens := ollama.EstimateTokens(rendered)
+		packedItem := PackedItem{Item: item}
+
+		if tokens > remaining {
+			rendered, tokens = p.shrink(item, remaining, &packedItem)
+		}
+
+		if rendered == "" {
+			packed.Dropped = append(packed.Dropped, packedItem)
+			continue
+		}
+
+		packedItem.Tokens = tokens
+		packed.Tokens += tokens
+		packed.Included = append(packed.Included, packedItem)
+		text.WriteString(rendered)
+	}
+
+	packed.Text = text.String()
+	return packed
+}
+
+// shrink tries to fit an item into the remaining budget by summarizing it, then by
+// truncating it. It returns an empty string when the item has to be dropped.
+func (p *ContextPacker) shrink(item ContextItem, remaining int, packedItem *PackedItem) (string, int) {
+	if remaining < minTruncatedTokens {
+		return "", 0
+	}
+
+	if p.summarizer != nil && item.Kind == ContextFile {
+		if summary, ok := p.summarizer(item); ok && summary != "" {
+			rendered := renderContextItem(ContextItem{Kind: ContextSignatures, Path: item.Path}, summary)
+			if tokens := ollama.EstimateTokens(rendered); tokens <= remaining {
+				packedItem.Summarized = true
+				return rendered, tokens
+			}
+		}
+	}
+
+	lines := strings.Split(item.Content, "\n")
+	// Keep the longest prefix of whole lines that fits, leaving room for the marker
+	low, high := 0, len(lines)
+	for low < high {
+		mid := (low + high + 1) / 2
+		candidate := renderContextItem(item, truncatedContent(lines, mid))
+		if ollama.EstimateTokens(candidate) <= remaining {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	if low == 0 {
+		return "", 0
+	}
+
+	rendered := renderContextItem(item, truncatedContent(lines, low))
+	packedItem.Truncated = true
+	return rendered, ollama.EstimateTokens(rendered)
+}
+
+// Report describes what was packed so it can be logged or shown to the model.
+// It returns an empty string when everything fit untouched.
+func (c *PackedContext) Report() string {
+	var report strings.Builder
+
+	for _, packedItem := range c.Included {
+		switch {
+		case packedItem.Summarized:
+			fmt.Fprintf(&report, "- %s was too large and is shown as signatures only\n", describeContextItem(packedItem.Item))
+		case packedItem.Truncated:
+			fmt.Fprintf(&report, "- %s was truncated\n", describeContextItem(packedItem.Item))
+		}
+	}
+	for _, packedItem := range c.Dropped {
+		fmt.Fprintf(&report, "- %s was left out (about %d tokens)\n",
+			describeContextItem(packedItem.Item), ollama.EstimateTokens(packedItem.Item.Content))
+	}
+
+	if report.Len() == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Context limited to %d of %d tokens:\n%s", c.Tokens, c.Budget, report.String())
+}
+
+func truncatedContent(lines []string, keep int) string {
+	if keep >= len(lines) {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:keep], "\n") + fmt.Sprintf("\n... [truncated %d more lines]", len(lines)-keep)
+}
+
+func describeContextItem(item ContextItem) string {
+	if item.Kind == ContextChunk {
+		return fmt.Sprintf("%s lines %d-%d", item.Path, item.StartLine, item.EndLine)
+	}
+	return item.Path
+}
+
+// renderContextItem formats an item the way file contents are shown to the model.
+// Diffs and history are labeled by their Path, such as "staged changes".
+func renderContextItem(item ContextItem, content string) string {
+	switch item.Kind {
+	case ContextDiff:
+		return fmt.Sprintf("\n<Git Context>\n%s\n```diff\n%s\n```\n</Git Context>\n", item.Path, content)
+	case ContextHistory:
+		return fmt.Sprintf("\n<Git Context>\n%s\n```\n%s\n```\n</Git Context>\n", item.Path, content)
+	}
+
+	label := item.Path
+	switch item.Kind {
+	case ContextChunk:
+		label = fmt.Sprintf("%s (lines %d-%d)", item.Path, item.StartLine, item.EndLine)
+	case ContextSignatures:
+		label = fmt.Sprintf("%s (signatures only)", item.Path)
+	}
+
+	return fmt.Sprintf("\n<File Context>\n%s\n```%s\n%s\n```\n</File Context>\n",
+		label, item.Path, content)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"ai-code-editor/ollama"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Helper function to build a file item of roughly the given number of lines
+func fileItem(path string, lines int, relevance float64) ContextItem {
+	content := make([]string, lines)
+	for i := range content {
+		content[i] = fmt.Sprintf("result%d := process(input%d)", i, i)
+	}
+	return ContextItem{Kind: ContextFile, Path: path, Content: strings.Join(content, "\n"), Relevance: relevance}
+}
+
+func TestContextPacker_EverythingFits(t *testing.T) {
+	packer := NewContextPacker(10000)
+	packed := packer.Pack([]ContextItem{fileItem("a.go", 5, 1), fileItem("b.go", 5, 0.5)})
+
+	if len(packed.Included) != 2 || len(packed.Dropped) != 0 {
+		t.Fatalf("Expected both files included, got %d included and %d dropped", len(packed.Included), len(packed.Dropped))
+	}
+	if packed.Report() != "" {
+		t.Errorf("Expected no report when everything fits, got:\n%s", packed.Report())
+	}
+	if !strings.Contains(packed.Text, "<File Context>\na.go\n```a.go") {
+		t.Errorf("Expected files in the File Context format, got:\n%s", packed.Text)
+	}
+}
+
+func TestContextPacker_NeverExceedsBudget(t *testing.T) {
+	packer := NewContextPacker(300)
+	packed := packer.Pack([]ContextItem{
+		fileItem("low.go", 40, 0.1),
+		fileItem("high.go", 40, 0.9),
+		{Kind: ContextChunk, Path: "chunk.go", Content: "func chunk() {}", StartLine: 3, EndLine: 3, Relevance: 0.9},
+	})
+
+	if tokens := ollama.EstimateTokens(packed.Text); tokens > 300 {
+		t.Errorf("Packed text has %d tokens, budget is 300", tokens)
+	}
+
+	// The chunk wins the relevance tie and the most relevant file comes next
+	if packed.Included[0].Item.Path != "chunk.go" || packed.Included[1].Item.Path != "high.go" {
+		t.Errorf("Unexpected packing order: %v", packed.Included)
+	}
+	if !packed.Included[1].Truncated {
+		t.Errorf("Expected high.go to be truncated to fit")
+	}
+	if len(packed.Dropped) != 1 || packed.Dropped[0].Item.Path != "low.go" {
+		t.Errorf("Expected low.go to be dropped, got %v", packed.Dropped)
+	}
+
+	report := packed.Report()
+	if !strings.Contains(report, "high.go was truncated") || !strings.Contains(report, "low.go was left out") {
+		t.Errorf("Expected report to mention truncated and dropped files, got:\n%s", report)
+	}
+}
+
+func TestContextPacker_UsesSummarizer(t *testing.T) {
+	packer := NewContextPacker(200)
+	packer.SetSummarizer(func(item ContextItem) (string, bool) {
+		return "func process(input string) string", true
+	})
+
+	packed := packer.Pack([]ContextItem{fileItem("big.go", 200, 1)})
+	if len(packed.Included) != 1 || !packed.Included[0].Summarized {
+		t.Fatalf("Expected big.go to be summarized, got %v", packed.Included)
+	}
+	if !strings.Contains(packed.Text, "big.go (signatures only)") {
+		t.Errorf("Expected the summary to be labeled, got:\n%s", packed.Text)
+	}
+}
+
+func TestContextBudget(t *testing.T) {
+	if budget := ContextBudget(8192, 1024); budget != 7168 {
+		t.Errorf("Expected 7168, got %d", budget)
+	}
+	if budget := ContextBudget(1000, 4000); budget != 0 {
+		t.Errorf("Expected an exhausted budget to be 0, got %d", budget)
+	}
+}
+
+func TestNormalizeRelevance(t *testing.T) {
+	// Fused search scores are tiny, but the best chunk still ranks with the best file
+	chunks := NormalizeRelevance([]ContextItem{{Relevance: 0.033}, {Relevance: 0.016}, {Relevance: 0.0245}})
+	expected := []float64{1, 0, 0.5}
+	for i, item := range chunks {
+		if diff := item.Relevance - expected[i]; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Expected relevance %f for item %d, got %f", expected[i], i, item.Relevance)
+		}
+	}
+
+	if single := NormalizeRelevance([]ContextItem{{Relevance: 0.3}}); single[0].Relevance != 1 {
+		t.Errorf("Expected a lone item to get relevance 1, got %f", single[0].Relevance)
+	}
+	if empty := NormalizeRelevance(nil); len(empty) != 0 {
+		t.Errorf("Expected no items, got %v", empty)
+	}
+}
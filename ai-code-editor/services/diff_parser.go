@@ -0,0 +1,135 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches "@@ -12,3 +12,4 @@" lines; a missing count means 1
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// DiffHunk is one block of changes of a file
+type DiffHunk struct {
+	OldStart int
+	OldLines int
+	NewStart int // 1-based line of the hunk in the changed file
+	NewLines int
+	Content  string // the hunk including its @@ header
+}
+
+// FileDiff is the part of a unified diff changing one file
+type FileDiff struct {
+	Path    string // path after the change, the old path for deleted files
+	OldPath string
+	Deleted bool
+	Binary  bool
+	Hunks   []DiffHunk
+}
+
+// Diff returns the file's part of the diff, without the git headers
+func (f FileDiff) Diff() string {
+	hunks := make([]string, len(f.Hunks))
+	for i, hunk := range f.Hunks {
+		hunks[i] = hunk.Content
+	}
+	return strings.Join(hunks, "\n")
+}
+
+// AddedLines returns the content of the lines the diff adds
+func (f FileDiff) AddedLines() []string {
+	added := make([]string, 0)
+	for _, hunk := range f.Hunks {
+		for _, line := range strings.Split(hunk.Content, "\n") {
+			if strings.HasPrefix(line, "+") {
+				added = append(added, line[1:])
+			}
+		}
+	}
+	return added
+}
+
+// InHunk reports whether a line of the changed file is inside one of the hunks
+func (f FileDiff) InHunk(line int) bool {
+	for _, hunk := range f.Hunks {
+		if line >= hunk.NewStart && line < hunk.NewStart+max(hunk.NewLines, 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseUnifiedDiff splits a git diff into its files and hunks
+func ParseUnifiedDiff(diff string) []FileDiff {
+	files := make([]FileDiff, 0)
+	var current *FileDiff
+	var hunk *DiffHunk
+	var hunkLines []string
+
+	flushHunk := func() {
+		if hunk != nil {
+			hunk.Content = strings.Join(hunkLines, "\n")
+			current.Hunks = append(current.Hunks, *hunk)
+			hunk, hunkLines = nil, nil
+		}
+	}
+	flushFile := func() {
+		if current != nil {
+			flushHunk()
+			files = append(files, *current)
+			current = nil
+		}
+	}
+
+	for _, line := range strings.Split(diff, "\n") {
+		if match := diffFileHeader.FindStringSubmatch(line); match != nil {
+			flushFile()
+			current = &FileDiff{OldPath: match[1], Path: match[2]}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if match := hunkHeader.FindStringSubmatch(line); match != nil {
+			flushHunk()
+			hunk = &DiffHunk{
+				OldStart: atoiOr(match[1], 0),
+				OldLines: atoiOr(match[2], 1),
+				NewStart: atoiOr(match[3], 0),
+				NewLines: atoiOr(match[4], 1),
+			}
+			hunkLines = []string{line}
+			continue
+		}
+
+		if hunk != nil {
+			// Content lines start with a space, + or -, "\ No newline" notes with a backslash
+			if line != "" && strings.ContainsRune(" +-\\", rune(line[0])) {
+				hunkLines = append(hunkLines, line)
+				continue
+			}
+			flushHunk()
+		}
+
+		switch {
+		case strings.HasPrefix(line, "deleted file mode"):
+			current.Deleted = true
+			current.Path = current.OldPath
+		case strings.HasPrefix(line, "Binary files"):
+			current.Binary = true
+		}
+	}
+	flushFile()
+
+	return files
+}
+
+// atoiOr parses s, returning fallback when it is empty or not a number
+func atoiOr(s string, fallback int) int {
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++
++import "fmt"
+
+@@ -10 +11,2 @@ func main() {
+-	println("hi")
++	fmt.Println("hi")
++	fmt.Println("bye")
+diff --git a/old.go b/old.go
+deleted file mode 100644
+index 3333333..0000000
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+diff --git a/logo.png b/logo.png
+Binary files a/logo.png and b/logo.png differ
+`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	files := ParseUnifiedDiff(testDiff)
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %d: %+v", len(files), files)
+	}
+
+	main := files[0]
+	if main.Path != "main.go" || len(main.Hunks) != 2 {
+		t.Fatalf("Unexpected main.go diff: %+v", main)
+	}
+	if hunk := main.Hunks[1]; hunk.NewStart != 11 || hunk.NewLines != 2 || hunk.OldLines != 1 {
+		t.Errorf("Unexpected second hunk: %+v", hunk)
+	}
+	expectedAdded := []string{"", `import "fmt"`, `	fmt.Println("hi")`, `	fmt.Println("bye")`}
+	if added := main.AddedLines(); !reflect.DeepEqual(added, expectedAdded) {
+		t.Errorf("AddedLines() = %q, expected %q", added, expectedAdded)
+	}
+	for line, expected := range map[int]bool{1: true, 4: true, 5: false, 11: true, 12: true, 13: false} {
+		if main.InHunk(line) != expected {
+			t.Errorf("InHunk(%d) = %v, expected %v", line, !expected, expected)
+		}
+	}
+
+	if deleted := files[1]; !deleted.Deleted || deleted.Path != "old.go" || len(deleted.Hunks) != 1 {
+		t.Errorf("Unexpected deleted file: %+v", deleted)
+	}
+	if binary := files[2]; !binary.Binary || len(binary.Hunks) != 0 {
+		t.Errorf("Unexpected binary file: %+v", binary)
+	}
+}
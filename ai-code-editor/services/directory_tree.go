@@ -3,22 +3,20 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
-	"path/filepath"
 	"strings"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
-	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
 )
 
 // DirectoryTree represents a service for generating directory tree structures
 type DirectoryTree struct {
 	indent          string
 	maxDepth        int
+	maxFileSize     int64
 	skipPaths       []string
-	parser          *tree_sitter.Parser
-	tree            *tree_sitter.Tree
+	parseCache      *ParseCache
 	directoryString string
 	knownFiles      []string
 }
@@ -47,8 +45,10 @@ type FileInfo struct {
 	FunctionSignatures []string `json:"functionSignatures"`
 }
 
-// NewDirectoryTree creates a new DirectoryTree service with default settings
-func NewDirectoryTree(indent string, maxDepth int, skipPaths []string) *DirectoryTree {
+// NewDirectoryTree creates a new DirectoryTree service with default settings.
+// skipPaths are skipped in addition to ignored files, in .gitignore syntax, and
+// so are files larger than maxFileSize bytes (0 means DefaultMaxFileSize).
+func NewDirectoryTree(indent string, maxDepth int, maxFileSize int64, skipPaths []string) *DirectoryTree {
 	if indent == "" {
 		indent = "    "
 	}
@@ -56,25 +56,18 @@ func NewDirectoryTree(indent string, maxDepth int, skipPaths []string) *Director
 		maxDepth = 100 // reasonable default
 	}
 
-	parser := tree_sitter.NewParser()
-
 	return &DirectoryTree{
-		indent:     indent,
-		maxDepth:   maxDepth,
-		skipPaths:  skipPaths,
-		parser:     parser,
-		knownFiles: make([]string, 0),
+		indent:      indent,
+		maxDepth:    maxDepth,
+		maxFileSize: maxFileSize,
+		skipPaths:   skipPaths,
+		parseCache:  SharedParseCache(),
+		knownFiles:  make([]string, 0),
 	}
 }
 
 // GenerateTree generates a tree-sitter tree representation of the directory structure
 func (dt *DirectoryTree) GenerateTree(rootPath string) (*DirectoryStructure, error) {
-	if dt.tree != nil {
-		dt.tree.Close()
-	}
-
-	dt.parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_go.Language()))
-
 	structure := &DirectoryStructure{
 		Directory: Directory{
 			Path:  rootPath,
@@ -87,18 +80,18 @@ func (dt *DirectoryTree) GenerateTree(rootPath string) (*DirectoryStructure, err
 		return nil, fmt.Errorf("error generating directory tree: %w", err)
 	}
 
+	if err := dt.parseCache.Save(); err != nil {
+		log.Printf("Warning: failed to save parse cache: %v", err)
+	}
+
 	return structure, nil
 }
 
-// Close releases resources associated with the DirectoryTree
+// Close saves the parse cache and clears the known files. The cache itself is
+// shared and stays open.
 func (dt *DirectoryTree) Close() {
-	if dt.tree != nil {
-		dt.tree.Close()
-		dt.tree = nil
-	}
-	if dt.parser != nil {
-		dt.parser.Close()
-		dt.parser = nil
+	if err := dt.parseCache.Save(); err != nil {
+		log.Printf("Warning: failed to save parse cache: %v", err)
 	}
 	dt.ClearKnownFiles()
 }
@@ -119,162 +112,87 @@ func (dt *DirectoryTree) GetDirectoryString(rootPath string) string {
 	return string(jsonBytes)
 }
 
+// GetTree returns an outline of the symbols declared in a file, or an empty
+// string when the file's language is not supported
 func (dt *DirectoryTree) GetTree(filePath string) string {
 	log.Printf("Getting tree for file: %s", filePath)
 
-	// Only parse .go files
-	if !strings.HasSuffix(filePath, ".go") {
-		log.Printf("Skipping non-Go file: %s", filePath)
+	if SymbolLanguageForPath(filePath) == nil {
+		log.Printf("Skipping unsupported file: %s", filePath)
 		return ""
 	}
 
-	fileContents, err := os.ReadFile(filePath)
+	symbols, err := dt.parseCache.Symbols(filePath)
 	if err != nil {
-		log.Printf("Error reading file %s: %v", filePath, err)
-		return ""
-	}
-
-	tree := dt.parser.Parse(nil, nil)
-	if tree == nil {
-		log.Printf("Failed to create parse tree for %s", filePath)
+		log.Printf("Error parsing file %s: %v", filePath, err)
 		return ""
 	}
 
-	tree = dt.parser.Parse(fileContents, nil)
-	if tree == nil {
-		log.Printf("Failed to parse file %s", filePath)
-		return ""
-	}
-
-	return dt.GetFunctionDeclarations(tree.RootNode(), fileContents)
+	return renderOutline(symbols)
 }
 
+// GetFunctionDeclarations returns an outline of the declarations in a parsed Go
+// file: functions, methods, types with their fields or methods, and exported
+// constants and variables, each with its doc comment
 func (dt *DirectoryTree) GetFunctionDeclarations(node *tree_sitter.Node, fileContents []byte) string {
-	cursor := node.Walk()
-	defer cursor.Close()
+	return renderOutline(goSymbols(node, fileContents, ""))
+}
 
+// renderOutline writes each symbol's doc comment, signature and members
+func renderOutline(symbols []Symbol) string {
 	var declarations strings.Builder
-
-	// Move the cursor to the first child
-	cursor.GotoFirstChild()
-
-	// Traverse the AST
-	for {
-		if cursor.Node().Kind() == "method_declaration" || cursor.Node().Kind() == "function_declaration" {
-			funcNode := cursor.Node()
-
-			// Get function name
-			nameNode := funcNode.ChildByFieldName("name")
-			if nameNode != nil {
-				declarations.WriteString("func ")
-
-				// Handle receiver for methods
-				if cursor.Node().Kind() == "method_declaration" {
-					receiverNode := funcNode.ChildByFieldName("receiver")
-					if receiverNode != nil {
-						declarations.WriteString("(")
-						declarations.WriteString(receiverNode.Utf8Text(fileContents))
-						declarations.WriteString(") ")
-					}
-				}
-
-				declarations.WriteString(nameNode.Utf8Text(fileContents))
-
-				// Get parameters
-				paramListNode := funcNode.ChildByFieldName("parameters")
-				if paramListNode != nil {
-					declarations.WriteString(paramListNode.Utf8Text(fileContents))
-				}
-
-				// Get return type if exists
-				resultNode := funcNode.ChildByFieldName("result")
-				if resultNode != nil {
-					declarations.WriteString(" ")
-					declarations.WriteString(resultNode.Utf8Text(fileContents))
-				}
-
-				declarations.WriteString("\n")
-			}
+	for _, symbol := range symbols {
+		if symbol.Doc != "" {
+			declarations.WriteString(symbol.Doc)
+			declarations.WriteString("\n")
 		}
 
-		if !cursor.GotoNextSibling() {
-			break
+		declarations.WriteString(symbol.Signature)
+		if len(symbol.Members) > 0 {
+			declarations.WriteString(" {\n")
+			for _, member := range symbol.Members {
+				declarations.WriteString("\t" + member + "\n")
+			}
+			declarations.WriteString("}")
 		}
+		declarations.WriteString("\n")
 	}
-
 	return declarations.String()
 }
 
-// Add new method to build the directory structure
+// buildDirectoryStructure records the files under path that are not ignored and
+// the signatures of those in a supported language
 func (dt *DirectoryTree) buildDirectoryStructure(path string, structure *DirectoryStructure) error {
-	info, err := os.Stat(path)
-	if err != nil {
-		return err
-	}
-
-	if strings.HasPrefix(info.Name(), ".") {
-		return nil
-	}
-
-	for _, skipPath := range dt.skipPaths {
-		if strings.Contains(path, skipPath) {
-			return nil
-		}
-	}
+	walker := NewFileWalker(path, dt.maxFileSize, dt.skipPaths)
 
-	if info.IsDir() {
-		entries, err := os.ReadDir(path)
-		if err != nil {
-			return err
-		}
+	return walker.Walk(nil, func(filePath string, info fs.FileInfo) error {
+		dt.knownFiles = append(dt.knownFiles, filePath)
 
-		for _, entry := range entries {
-			childPath := filepath.Join(path, entry.Name())
-			err := dt.buildDirectoryStructure(childPath, structure)
-			if err != nil {
-				return err
-			}
-		}
-	} else {
-		if !strings.HasPrefix(info.Name(), ".") {
-			dt.knownFiles = append(dt.knownFiles, path)
-		}
-
-		if strings.HasSuffix(path, ".go") {
-			signatures := dt.GetFunctionSignatures(path)
+		if SymbolLanguageForPath(filePath) != nil {
 			fileInfo := FileInfo{
-				Path:               path,
-				FunctionSignatures: signatures,
+				Path:               filePath,
+				FunctionSignatures: dt.GetFunctionSignatures(filePath),
 			}
 			structure.Directory.Files = append(structure.Directory.Files, fileInfo)
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
-// Add new method to get function signatures
+// GetFunctionSignatures returns the signatures of the symbols declared in a file
 func (dt *DirectoryTree) GetFunctionSignatures(filePath string) []string {
-	fileContents, err := os.ReadFile(filePath)
+	symbols, err := dt.parseCache.Symbols(filePath)
 	if err != nil {
-		log.Printf("Error reading file %s: %v", filePath, err)
+		log.Printf("Error parsing file %s: %v", filePath, err)
 		return nil
 	}
 
-	tree := dt.parser.Parse(nil, nil)
-	if tree == nil {
-		log.Printf("Failed to create parse tree for %s", filePath)
-		return nil
+	signatures := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		signatures[i] = symbol.Signature
 	}
-
-	tree = dt.parser.Parse(fileContents, nil)
-	if tree == nil {
-		log.Printf("Failed to parse file %s", filePath)
-		return nil
-	}
-
-	declarations := dt.GetFunctionDeclarations(tree.RootNode(), fileContents)
-	return strings.Split(strings.TrimSpace(declarations), "\n")
+	return signatures
 }
 
 // Add this new method
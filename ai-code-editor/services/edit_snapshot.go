@@ -0,0 +1,66 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// EditSnapshot remembers the contents files had before they were edited, so
+// a failed edit can be rolled back
+type EditSnapshot struct {
+	contents map[string][]byte // nil for files that did not exist
+}
+
+// NewEditSnapshot creates an empty snapshot
+func NewEditSnapshot() *EditSnapshot {
+	return &EditSnapshot{contents: make(map[string][]byte)}
+}
+
+// Record remembers the current contents of path. Only the first call for a
+// path counts, so the snapshot keeps the state before any edit.
+func (s *EditSnapshot) Record(path string) error {
+	if _, ok := s.contents[path]; ok {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to snapshot %s: %w", path, err)
+	}
+	if content == nil && err == nil {
+		content = []byte{}
+	}
+
+	s.contents[path] = content
+	return nil
+}
+
+// Paths returns the recorded paths in lexical order
+func (s *EditSnapshot) Paths() []string {
+	paths := make([]string, 0, len(s.contents))
+	for path := range s.contents {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Restore writes back the recorded contents and removes files that did not exist
+func (s *EditSnapshot) Restore() error {
+	for _, path := range s.Paths() {
+		content := s.contents[path]
+		if content == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			continue
+		}
+
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditSnapshot_Restore(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{"edited.go": "original"})
+	edited := filepath.Join(root, "edited.go")
+	created := filepath.Join(root, "created.go")
+
+	snapshot := NewEditSnapshot()
+	for _, path := range []string{edited, created} {
+		if err := snapshot.Record(path); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	writeTestFiles(t, root, map[string]string{"edited.go": "first edit", "created.go": "new"})
+	// Recording again must keep the original contents
+	if err := snapshot.Record(edited); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	writeTestFiles(t, root, map[string]string{"edited.go": "second edit"})
+
+	if err := snapshot.Restore(); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if content := readFile(t, edited); content != "original" {
+		t.Errorf("Expected original contents, got %q", content)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("Expected created file to be removed, got %v", err)
+	}
+}
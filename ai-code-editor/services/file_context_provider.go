@@ -56,3 +56,46 @@ func (f *FileContextProvider) GetFileContents() string {
 
 	return fileContents
 }
+
+// GetContextItems reads the provider's files as context items. Files listed
+// earlier are treated as more relevant.
+func (f *FileContextProvider) GetContextItems() []ContextItem {
+	items := make([]ContextItem, 0, len(f.files))
+
+	for i, file := range f.files {
+		// Clean the path to remove any duplicate separators
+		cleanPath := filepath.Clean(file)
+
+		content, err := os.ReadFile(cleanPath)
+		if err != nil {
+			log.Printf("Error reading file %s: %v", cleanPath, err)
+			continue
+		}
+
+		items = append(items, ContextItem{
+			Kind:      ContextFile,
+			Path:      cleanPath,
+			Content:   string(content),
+			Relevance: 1 / float64(i+1),
+		})
+	}
+
+	return items
+}
+
+// ContextItems returns the provider's files whatever the task, making it a ContextSource
+func (f *FileContextProvider) ContextItems(task string) ([]ContextItem, error) {
+	return f.GetContextItems(), nil
+}
+
+// GetPackedFileContents returns the file contents fitted into the packer's budget
+func (f *FileContextProvider) GetPackedFileContents(packer *ContextPacker) *PackedContext {
+	log.Printf("Getting packed contents for files: %v", f.files)
+
+	packed := packer.Pack(f.GetContextItems())
+	if report := packed.Report(); report != "" {
+		log.Printf("%s", report)
+	}
+
+	return packed
+}
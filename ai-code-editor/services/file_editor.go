@@ -2,11 +2,13 @@ package services
 
 import (
 	codeEditor "ai-code-editor/codeEditor/actions"
-	"bufio"
 	"os"
 	"strings"
 )
 
+// EditFile applies the actions to the file at path. The result is formatted
+// with the formatter registered for the file's language before it is written;
+// when it does not parse the file is left unchanged and a *SyntaxError is returned.
 func EditFile(path string, actions []codeEditor.EditFileAction) error {
 	// Read the file into memory
 	content, err := os.ReadFile(path)
@@ -47,8 +49,9 @@ func EditFile(path string, actions []codeEditor.EditFileAction) error {
 			continue // Skip invalid range
 		}
 
-		// Create a new slice with content before the replacement
-		prefix := lines[:startLine]
+		// Create a new slice with content before the replacement, copied so
+		// appending cannot overwrite the lines after it
+		prefix := append(make([]string, 0, len(lines)+len(newLines)), lines[:startLine]...)
 		// Add the new content
 		prefix = append(prefix, newLines...)
 		// Add the content after the replacement
@@ -80,20 +83,11 @@ func EditFile(path string, actions []codeEditor.EditFileAction) error {
 		}
 	}
 
-	// Write back to file
-	file, err := os.Create(path)
+	formatted, err := FormatSource(path, []byte(strings.Join(lines, "\n")))
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	writer := bufio.NewWriter(file)
-	for i, line := range lines {
-		if i > 0 {
-			writer.WriteString("\n")
-		}
-		writer.WriteString(line)
-	}
-
-	return writer.Flush()
+	// Write back to file
+	return os.WriteFile(path, formatted, 0644)
 }
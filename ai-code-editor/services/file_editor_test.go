@@ -108,3 +108,53 @@ func TestEditFile_MultipleActions(t *testing.T) {
 		t.Errorf("Expected:\n%s\nGot:\n%s", expected, result)
 	}
 }
+
+func TestEditFile_FormatsGo(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(tmpFile, []byte("package main\n\nfunc main() {\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	actions := []codeEditor.EditFileAction{
+		{
+			Action:    "replace",
+			StartLine: 3,
+			EndLine:   4,
+			Content:   "func main() {\nprintln(\"hi\")\n}",
+		},
+	}
+
+	if err := EditFile(tmpFile, actions); err != nil {
+		t.Fatalf("EditFile failed: %v", err)
+	}
+
+	expected := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	if content := readFile(t, tmpFile); content != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+}
+
+func TestEditFile_GoSyntaxErrorKeepsFile(t *testing.T) {
+	initialContent := "package main\n\nfunc main() {\n}\n"
+	tmpFile := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(tmpFile, []byte(initialContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	actions := []codeEditor.EditFileAction{
+		{
+			Action:    "replace",
+			StartLine: 3,
+			EndLine:   4,
+			Content:   "func main() {",
+		},
+	}
+
+	err := EditFile(tmpFile, actions)
+	if _, ok := err.(*SyntaxError); !ok {
+		t.Fatalf("Expected a SyntaxError, got %v", err)
+	}
+	if content := readFile(t, tmpFile); content != initialContent {
+		t.Errorf("Expected file to be unchanged, got %q", content)
+	}
+}
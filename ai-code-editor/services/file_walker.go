@@ -0,0 +1,192 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DefaultMaxFileSize is the largest file scanners read, in bytes
+	DefaultMaxFileSize = 1 << 20
+	// binarySniffLength is how much of a file is checked for NUL bytes
+	binarySniffLength = 8000
+	// projectIgnoreFile lists paths the tool skips in addition to .gitignore
+	projectIgnoreFile = ".aicodeignore"
+)
+
+// defaultCodeExtensions are scanned when no extensions are given
+var defaultCodeExtensions = []string{".go", ".js", ".ts", ".py", ".java", ".c", ".cpp", ".h", ".hpp", ".cs", ".php", ".rb", ".rs"}
+
+// FileWalker lists the files of a project the way git sees them. It honors
+// .gitignore files at any depth, .git/info/exclude and .aicodeignore files,
+// and skips hidden entries, files larger than the size limit and binary files.
+type FileWalker struct {
+	root         string
+	maxFileSize  int64
+	skipPatterns []string
+}
+
+// NewFileWalker creates a walker for root. skipPatterns are extra patterns in
+// .gitignore syntax, such as "vendor/"; a maxFileSize of 0 means DefaultMaxFileSize.
+func NewFileWalker(root string, maxFileSize int64, skipPatterns []string) *FileWalker {
+	if maxFileSize <= 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+
+	return &FileWalker{
+		root:         root,
+		maxFileSize:  maxFileSize,
+		skipPatterns: skipPatterns,
+	}
+}
+
+// Walk calls fn for every file that is not ignored, too large or binary, in
+// lexical order. When extensions is not empty only files with one of them are
+// visited.
+func (w *FileWalker) Walk(extensions []string, fn func(path string, info fs.FileInfo) error) error {
+	extMap := make(map[string]bool)
+	for _, ext := range extensions {
+		extMap[strings.ToLower(ext)] = true
+	}
+
+	rules := w.baseRules()
+
+	return filepath.WalkDir(w.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if relPath != "." {
+			if strings.HasPrefix(entry.Name(), ".") || rules.ignored(relPath, entry.IsDir()) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if entry.IsDir() {
+			base := relPath
+			if base == "." {
+				base = ""
+			}
+			rules.addDirectory(path, base)
+			return nil
+		}
+
+		if len(extMap) > 0 && !extMap[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > w.maxFileSize {
+			return nil
+		}
+
+		if binary, err := isBinaryFile(path); err != nil || binary {
+			return nil
+		}
+
+		return fn(path, info)
+	})
+}
+
+// Files returns the paths of the files Walk visits
+func (w *FileWalker) Files(extensions []string) ([]string, error) {
+	files := make([]string, 0)
+
+	err := w.Walk(extensions, func(path string, info fs.FileInfo) error {
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", w.root, err)
+	}
+
+	return files, nil
+}
+
+// ListDir returns the entries of the directory relDir, relative to the root,
+// that a walk would not skip, directories first
+func (w *FileWalker) ListDir(relDir string) ([]fs.DirEntry, error) {
+	relDir = strings.Trim(filepath.ToSlash(filepath.Clean(relDir)), "/")
+	if relDir == "." {
+		relDir = ""
+	}
+
+	// Ignore files of every directory from the root down apply
+	rules := w.baseRules()
+	rules.addDirectory(w.root, "")
+	if relDir != "" {
+		parts := strings.Split(relDir, "/")
+		for i := range parts {
+			base := strings.Join(parts[:i+1], "/")
+			if strings.HasPrefix(parts[i], ".") || rules.ignored(base, true) {
+				return nil, fmt.Errorf("directory %s is ignored", relDir)
+			}
+			rules.addDirectory(filepath.Join(w.root, filepath.FromSlash(base)), base)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(w.root, filepath.FromSlash(relDir)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", relDir, err)
+	}
+
+	dirs := make([]fs.DirEntry, 0)
+	files := make([]fs.DirEntry, 0)
+	for _, entry := range entries {
+		relPath := entry.Name()
+		if relDir != "" {
+			relPath = relDir + "/" + entry.Name()
+		}
+		if strings.HasPrefix(entry.Name(), ".") || rules.ignored(relPath, entry.IsDir()) {
+			continue
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, entry)
+		} else {
+			files = append(files, entry)
+		}
+	}
+
+	return append(dirs, files...), nil
+}
+
+// baseRules returns the skip patterns and .git/info/exclude, which apply everywhere
+func (w *FileWalker) baseRules() *ignoreRules {
+	rules := &ignoreRules{}
+	for _, pattern := range w.skipPatterns {
+		rules.addPattern(pattern, "")
+	}
+	rules.addFile(filepath.Join(w.root, ".git", "info", "exclude"), "")
+	return rules
+}
+
+// isBinaryFile reports whether the start of a file contains a NUL byte
+func isBinaryFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, binarySniffLength)
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+
+	return bytes.IndexByte(buffer[:n], 0) >= 0, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Helper function to create files under root from a map of relative paths to contents
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for relPath, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+// Helper function to list walked files relative to root
+func walkedFiles(t *testing.T, walker *FileWalker, root string, extensions []string) []string {
+	t.Helper()
+	files, err := walker.Files(extensions)
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	relFiles := make([]string, len(files))
+	for i, file := range files {
+		relPath, _ := filepath.Rel(root, file)
+		relFiles[i] = filepath.ToSlash(relPath)
+	}
+	return relFiles
+}
+
+func TestFileWalker_HonorsIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".gitignore":               "build/\n*.log\n!keep.log\n/root_only.go\n",
+		".git/info/exclude":        "scratch.go\n",
+		".aicodeignore":            "docs/**/*.md\n",
+		"main.go":                  "package main",
+		"root_only.go":             "package main",
+		"pkg/root_only.go":         "package pkg",
+		"build/out.go":             "package build",
+		"debug.log":                "log",
+		"keep.log":                 "log",
+		"scratch.go":               "package main",
+		"docs/guide/intro.md":      "# Intro",
+		"docs/readme.txt":          "readme",
+		"web/.gitignore":           "generated.js\n",
+		"web/generated.js":         "x",
+		"web/app.js":               "x",
+		"generated.js":             "x",
+		".env":                     "SECRET=1",
+		"node_modules/lib/x.js":    "x",
+		"vendor_utils.go":          "package main",
+		"vendor/github.com/x/x.go": "package x",
+	})
+
+	walker := NewFileWalker(root, 0, []string{"node_modules", "vendor/"})
+	files := walkedFiles(t, walker, root, nil)
+	expectedFiles := []string{
+		"docs/readme.txt",
+		"generated.js",
+		"keep.log",
+		"main.go",
+		"pkg/root_only.go",
+		"vendor_utils.go",
+		"web/app.js",
+	}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("Expected %v, got %v", expectedFiles, files)
+	}
+}
+
+func TestFileWalker_SkipsLargeAndBinaryFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"small.go":  "package main",
+		"large.go":  "package main\n" + strings.Repeat("// filler\n", 100),
+		"binary.go": "package main\x00\x01",
+		"notes.txt": "notes",
+	})
+
+	files := walkedFiles(t, NewFileWalker(root, 200, nil), root, []string{".go"})
+	if !reflect.DeepEqual(files, []string{"small.go"}) {
+		t.Errorf("Expected only small.go, got %v", files)
+	}
+}
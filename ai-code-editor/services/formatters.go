@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// formatterTimeout bounds how long an external formatter may run
+const formatterTimeout = 30 * time.Second
+
+// Formatter formats the source of a file before it is written. It returns a
+// *SyntaxError when the source cannot be parsed.
+type Formatter func(path string, source []byte) ([]byte, error)
+
+// SyntaxError reports the positions where a file failed to parse
+type SyntaxError struct {
+	Path        string
+	Diagnostics []Diagnostic
+}
+
+func (e *SyntaxError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, diagnostic := range e.Diagnostics {
+		switch {
+		case diagnostic.Line == 0:
+			messages[i] = fmt.Sprintf("%s: %s", e.Path, diagnostic.Message)
+		case diagnostic.Column == 0:
+			messages[i] = fmt.Sprintf("%s:%d: %s", e.Path, diagnostic.Line, diagnostic.Message)
+		default:
+			messages[i] = fmt.Sprintf("%s:%d:%d: %s", e.Path, diagnostic.Line, diagnostic.Column, diagnostic.Message)
+		}
+	}
+	return "syntax error: " + strings.Join(messages, "; ")
+}
+
+// formatters maps lowercased file extensions to their formatter
+var formatters = make(map[string]Formatter)
+
+// RegisterFormatter sets the formatter for files with the given extensions
+func RegisterFormatter(extensions []string, formatter Formatter) {
+	for _, ext := range extensions {
+		formatters[strings.ToLower(ext)] = formatter
+	}
+}
+
+// RegisterFormatterCommand formats files with the given extensions by piping
+// them through command, such as "black -q -" or "prettier --stdin-filepath {path}".
+// {path} is replaced by the path of the file. A failing command is reported
+// as a syntax error with its output.
+func RegisterFormatterCommand(extensions []string, command string) error {
+	args, err := splitCommand(command)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("empty formatter command")
+	}
+
+	RegisterFormatter(extensions, func(path string, source []byte) ([]byte, error) {
+		return runFormatterCommand(args, path, source)
+	})
+	return nil
+}
+
+// FormatSource formats source with the formatter registered for path.
+// Files without a formatter are returned unchanged.
+func FormatSource(path string, source []byte) ([]byte, error) {
+	formatter, ok := formatters[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return source, nil
+	}
+	return formatter(path, source)
+}
+
+func init() {
+	RegisterFormatter([]string{".go"}, formatGo)
+}
+
+// formatGo checks that source parses and formats it like gofmt
+func formatGo(path string, source []byte) ([]byte, error) {
+	fileSet := token.NewFileSet()
+	if _, err := parser.ParseFile(fileSet, path, source, parser.ParseComments|parser.AllErrors); err != nil {
+		return nil, goSyntaxError(path, err)
+	}
+
+	formatted, err := format.Source(source)
+	if err != nil {
+		return nil, goSyntaxError(path, err)
+	}
+	return formatted, nil
+}
+
+// goSyntaxError converts the errors of go/parser to a SyntaxError
+func goSyntaxError(path string, err error) error {
+	syntaxError := &SyntaxError{Path: path}
+
+	var errorList scanner.ErrorList
+	if !errors.As(err, &errorList) {
+		syntaxError.Diagnostics = []Diagnostic{{Path: path, Message: err.Error()}}
+		return syntaxError
+	}
+
+	for i, parseError := range errorList {
+		if i == maxDiagnosticsPerCheck {
+			break
+		}
+		syntaxError.Diagnostics = append(syntaxError.Diagnostics, Diagnostic{
+			Path:    path,
+			Line:    parseError.Pos.Line,
+			Column:  parseError.Pos.Column,
+			Message: parseError.Msg,
+		})
+	}
+	return syntaxError
+}
+
+// runFormatterCommand pipes source through a formatter command
+func runFormatterCommand(args []string, path string, source []byte) ([]byte, error) {
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = strings.ReplaceAll(arg, "{path}", path)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), formatterTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, expanded[0], expanded[1:]...)
+	cmd.Stdin = bytes.NewReader(source)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to run formatter %s: %w", expanded[0], err)
+		}
+
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		diagnostics := ParseDiagnostics(message)
+		if len(diagnostics) == 0 {
+			diagnostics = []Diagnostic{{Path: path, Message: message}}
+		}
+		return nil, &SyntaxError{Path: path, Diagnostics: diagnostics}
+	}
+
+	return stdout.Bytes(), nil
+}
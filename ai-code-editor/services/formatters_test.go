@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFormatSource_Go(t *testing.T) {
+	source := "package main\nfunc main(){\nx:=1\n_ = x\n}\n"
+
+	formatted, err := FormatSource("main.go", []byte(source))
+	if err != nil {
+		t.Fatalf("FormatSource failed: %v", err)
+	}
+
+	expected := "package main\n\nfunc main() {\n\tx := 1\n\t_ = x\n}\n"
+	if string(formatted) != expected {
+		t.Errorf("Expected %q, got %q", expected, formatted)
+	}
+}
+
+func TestFormatSource_GoSyntaxError(t *testing.T) {
+	source := "package main\n\nfunc main() {\n\tif true {\n}\n"
+
+	_, err := FormatSource("main.go", []byte(source))
+
+	var syntaxError *SyntaxError
+	if !errors.As(err, &syntaxError) {
+		t.Fatalf("Expected a SyntaxError, got %v", err)
+	}
+	if len(syntaxError.Diagnostics) == 0 || syntaxError.Diagnostics[0].Line != 5 {
+		t.Errorf("Expected a diagnostic on line 5, got %+v", syntaxError.Diagnostics)
+	}
+}
+
+func TestFormatSource_UnregisteredExtension(t *testing.T) {
+	source := []byte("  anything {")
+
+	formatted, err := FormatSource("notes.txt", source)
+	if err != nil || !reflect.DeepEqual(formatted, source) {
+		t.Errorf("Expected source unchanged, got %q, %v", formatted, err)
+	}
+}
+
+func TestRegisterFormatterCommand(t *testing.T) {
+	t.Cleanup(func() { delete(formatters, ".upper"); delete(formatters, ".fail") })
+
+	if err := RegisterFormatterCommand([]string{".upper"}, "tr a-z A-Z"); err != nil {
+		t.Fatalf("RegisterFormatterCommand failed: %v", err)
+	}
+	formatted, err := FormatSource("file.upper", []byte("shout"))
+	if err != nil || string(formatted) != "SHOUT" {
+		t.Errorf("Expected SHOUT, got %q, %v", formatted, err)
+	}
+
+	if err := RegisterFormatterCommand([]string{".fail"}, "sh -c 'echo {path}:3:1: bad token >&2; exit 1'"); err != nil {
+		t.Fatalf("RegisterFormatterCommand failed: %v", err)
+	}
+	_, err = FormatSource("file.fail", []byte("x"))
+
+	var syntaxError *SyntaxError
+	if !errors.As(err, &syntaxError) {
+		t.Fatalf("Expected a SyntaxError, got %v", err)
+	}
+	expected := []Diagnostic{{Path: "file.fail", Line: 3, Column: 1, Message: "bad token"}}
+	if !reflect.DeepEqual(syntaxError.Diagnostics, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, syntaxError.Diagnostics)
+	}
+}
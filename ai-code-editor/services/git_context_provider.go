@@ -0,0 +1,144 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	// DefaultGitHistoryCommits is how many recent commit messages are offered
+	DefaultGitHistoryCommits = 5
+	// gitChangesRelevance ranks uncommitted changes with the most relevant
+	// files, since a task like "finish what I started" depends on them
+	gitChangesRelevance = 1
+	// gitHistoryRelevance ranks commit messages below the files they describe
+	gitHistoryRelevance = 0.3
+)
+
+// GitContextProvider offers what the user has been doing in a git working
+// tree: the unstaged and staged changes, the untracked files and the recent
+// commit messages touching the changed or relevant files. Files on the deny
+// list are left out of the diffs.
+type GitContextProvider struct {
+	repo          *GitRepository
+	historyCount  int
+	guard         *PathGuard
+	relevantFiles []string
+}
+
+// NewGitContextProvider creates a provider for the working tree at dir,
+// offering up to historyCount commit messages and leaving out the changes to
+// files matching deniedPatterns, in .gitignore syntax
+func NewGitContextProvider(dir string, historyCount int, deniedPatterns []string) *GitContextProvider {
+	if historyCount < 0 {
+		historyCount = DefaultGitHistoryCommits
+	}
+
+	return &GitContextProvider{
+		repo:         NewGitRepository(dir),
+		historyCount: historyCount,
+		guard:        NewPathGuard(dir, deniedPatterns),
+	}
+}
+
+// SetRelevantFiles adds files, relative to the working tree directory, whose
+// history is offered along with that of the changed files
+func (p *GitContextProvider) SetRelevantFiles(files []string) {
+	p.relevantFiles = files
+}
+
+// ContextItems returns the changes and recent history of the working tree.
+// The task is not used: the changes matter whatever it is. Outside a git
+// repository there are no items.
+func (p *GitContextProvider) ContextItems(task string) ([]ContextItem, error) {
+	if !p.repo.IsRepository() {
+		return []ContextItem{}, nil
+	}
+
+	items := make([]ContextItem, 0, 4)
+
+	unstaged, err := p.repo.WorkingTreeDiff()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working tree changes: %w", err)
+	}
+	if diff := p.guard.FilterDiff(unstaged); diff != "" {
+		items = append(items, ContextItem{Kind: ContextDiff, Path: "unstaged changes (git diff)", Content: diff, Relevance: gitChangesRelevance})
+	}
+
+	staged, err := p.repo.StagedDiffRelative()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged changes: %w", err)
+	}
+	if diff := p.guard.FilterDiff(staged); diff != "" {
+		items = append(items, ContextItem{Kind: ContextDiff, Path: "staged changes (git diff --cached)", Content: diff, Relevance: gitChangesRelevance})
+	}
+
+	untracked, err := p.repo.UntrackedFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	if untracked = p.allowedFiles(untracked); len(untracked) > 0 {
+		items = append(items, ContextItem{Kind: ContextHistory, Path: "new files not yet added to git", Content: strings.Join(untracked, "\n"), Relevance: gitChangesRelevance})
+	}
+
+	if history := p.history(); history != "" {
+		items = append(items, ContextItem{Kind: ContextHistory, Path: "recent commits", Content: history, Relevance: gitHistoryRelevance})
+	}
+
+	return items, nil
+}
+
+// RelevantHistoryItems returns the recent commit messages touching the
+// relevant files only, for when they are known after the first prompt
+func (p *GitContextProvider) RelevantHistoryItems() []ContextItem {
+	files := p.allowedFiles(p.relevantFiles)
+	if p.historyCount == 0 || len(files) == 0 || !p.repo.IsRepository() {
+		return []ContextItem{}
+	}
+
+	commits, err := p.repo.Log(p.historyCount, uniqueStrings(files))
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return []ContextItem{}
+	}
+	if commits = strings.TrimSpace(commits); commits == "" {
+		return []ContextItem{}
+	}
+
+	return []ContextItem{{Kind: ContextHistory, Path: "recent commits touching " + strings.Join(files, ", "), Content: commits, Relevance: gitHistoryRelevance}}
+}
+
+// history returns the recent commit messages touching the changed and
+// relevant files, or the most recent ones when nothing has changed
+func (p *GitContextProvider) history() string {
+	if p.historyCount == 0 {
+		return ""
+	}
+
+	// A repository without commits has no history and no HEAD to diff against
+	changed, err := p.repo.ChangedFiles()
+	if err != nil {
+		return ""
+	}
+
+	files := p.allowedFiles(append(changed, p.relevantFiles...))
+	commits, err := p.repo.Log(p.historyCount, uniqueStrings(files))
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return ""
+	}
+
+	return strings.TrimSpace(commits)
+}
+
+// allowedFiles returns the files that are not on the deny list
+func (p *GitContextProvider) allowedFiles(files []string) []string {
+	allowed := make([]string, 0, len(files))
+	for _, file := range files {
+		if p.guard.Allowed(file) {
+			allowed = append(allowed, file)
+		}
+	}
+	return allowed
+}
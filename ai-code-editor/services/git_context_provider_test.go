@@ -0,0 +1,110 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitContextProvider_ContextItems(t *testing.T) {
+	repo, dir := newTestRepository(t)
+
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	writeFile(".env", "API_KEY=one\n")
+	writeFile("util.go", "package main\n")
+	if err := repo.StageAll(); err != nil {
+		t.Fatalf("StageAll failed: %v", err)
+	}
+	if err := repo.Commit("Add util.go\n\nStarts the helpers."); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// One unstaged change, one staged change and one new file
+	writeFile("main.go", "package main\n\nfunc main() {}\n")
+	writeFile(".env", "API_KEY=two\n")
+	writeFile("util.go", "package main\n\nfunc helper() {}\n")
+	if _, err := repo.run(nil, "add", "util.go"); err != nil {
+		t.Fatalf("Failed to stage util.go: %v", err)
+	}
+	writeFile("draft.go", "package main\n")
+	writeFile("id_rsa", "key\n")
+
+	provider := NewGitContextProvider(dir, 5, DefaultDeniedPaths)
+	items, err := provider.ContextItems("finish what I started")
+	if err != nil {
+		t.Fatalf("ContextItems failed: %v", err)
+	}
+
+	byPath := make(map[string]ContextItem)
+	for _, item := range items {
+		byPath[item.Path] = item
+	}
+
+	unstaged := byPath["unstaged changes (git diff)"]
+	if !strings.Contains(unstaged.Content, "+func main() {}") {
+		t.Errorf("Expected the unstaged diff of main.go, got %q", unstaged.Content)
+	}
+	if strings.Contains(unstaged.Content, "API_KEY") {
+		t.Errorf("Expected the .env change to be left out, got %q", unstaged.Content)
+	}
+
+	if staged := byPath["staged changes (git diff --cached)"]; !strings.Contains(staged.Content, "+func helper() {}") {
+		t.Errorf("Expected the staged diff of util.go, got %q", staged.Content)
+	}
+
+	if untracked := byPath["new files not yet added to git"]; untracked.Content != "draft.go" {
+		t.Errorf("Expected only draft.go as a new file, got %q", untracked.Content)
+	}
+
+	history := byPath["recent commits"]
+	if !strings.Contains(history.Content, "Add util.go") || !strings.Contains(history.Content, "Initial commit") {
+		t.Errorf("Expected the commits touching the changed files, got %q", history.Content)
+	}
+	if history.Relevance >= unstaged.Relevance {
+		t.Error("Expected the history to rank below the changes")
+	}
+}
+
+func TestGitContextProvider_RelevantHistoryItems(t *testing.T) {
+	repo, dir := newTestRepository(t)
+
+	for _, name := range []string{"store.go", ".env"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := repo.StageAll(); err != nil {
+			t.Fatalf("StageAll failed: %v", err)
+		}
+		if err := repo.Commit("Add " + name); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+
+	provider := NewGitContextProvider(dir, 5, DefaultDeniedPaths)
+	if items := provider.RelevantHistoryItems(); len(items) != 0 {
+		t.Errorf("Expected no items without relevant files, got %v", items)
+	}
+
+	provider.SetRelevantFiles([]string{"store.go", ".env"})
+	items := provider.RelevantHistoryItems()
+	if len(items) != 1 {
+		t.Fatalf("Expected one history item, got %v", items)
+	}
+	if !strings.Contains(items[0].Content, "Add store.go") || strings.Contains(items[0].Content, "Initial commit") || strings.Contains(items[0].Content, "Add .env") {
+		t.Errorf("Expected only the commits touching store.go, got %q", items[0].Content)
+	}
+}
+
+func TestGitContextProvider_OutsideRepository(t *testing.T) {
+	items, err := NewGitContextProvider(t.TempDir(), 5, nil).ContextItems("task")
+	if err != nil || len(items) != 0 {
+		t.Errorf("Expected no items outside a repository, got %v, %v", items, err)
+	}
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// BranchPrefix starts the names of branches created for tasks
+	BranchPrefix = "ai/"
+	// maxBranchSlugLength bounds the part of a branch name taken from the task
+	maxBranchSlugLength = 40
+)
+
+// nonSlugCharacters are replaced by dashes in branch names
+var nonSlugCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// GitRepository runs the local git binary in a working tree
+type GitRepository struct {
+	dir string
+}
+
+// NewGitRepository creates a repository for the working tree containing dir
+func NewGitRepository(dir string) *GitRepository {
+	return &GitRepository{dir: dir}
+}
+
+// Dir returns the directory git runs in
+func (g *GitRepository) Dir() string {
+	return g.dir
+}
+
+// IsRepository reports whether dir is inside a git working tree
+func (g *GitRepository) IsRepository() bool {
+	output, err := g.run(nil, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(output) == "true"
+}
+
+// Root returns the top level directory of the working tree
+func (g *GitRepository) Root() (string, error) {
+	output, err := g.run(nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// CurrentBranch returns the checked out branch, or "HEAD" when detached
+func (g *GitRepository) CurrentBranch() (string, error) {
+	output, err := g.run(nil, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// HasChanges reports whether the working tree has uncommitted or untracked changes
+func (g *GitRepository) HasChanges() (bool, error) {
+	output, err := g.run(nil, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// BranchExists reports whether a local branch exists
+func (g *GitRepository) BranchExists(branch string) bool {
+	_, err := g.run(nil, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// UniqueBranchName returns the branch name for task, adding a number when a
+// branch with that name already exists
+func (g *GitRepository) UniqueBranchName(task string) string {
+	base := BranchName(task)
+	name := base
+	for i := 2; g.BranchExists(name); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+// CreateBranch creates branch at the current commit and checks it out
+func (g *GitRepository) CreateBranch(branch string) error {
+	_, err := g.run(nil, "switch", "-c", branch)
+	return err
+}
+
+// AddWorktree checks out a new branch at the current commit in a separate
+// working tree at path
+func (g *GitRepository) AddWorktree(path, branch string) error {
+	_, err := g.run(nil, "worktree", "add", "-b", branch, path)
+	return err
+}
+
+// StageAll stages every change in the working tree
+func (g *GitRepository) StageAll() error {
+	_, err := g.run(nil, "add", "-A")
+	return err
+}
+
+// Stage stages the changes to paths, leaving other changes unstaged
+func (g *GitRepository) Stage(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	_, err := g.run(nil, append([]string{"add", "--"}, paths...)...)
+	return err
+}
+
+// StagedDiff returns the diff of the staged changes
+func (g *GitRepository) StagedDiff() (string, error) {
+	return g.run(nil, "diff", "--cached")
+}
+
+// WorkingTreeDiff returns the diff of the changes that are not staged, with
+// paths relative to the repository directory
+func (g *GitRepository) WorkingTreeDiff() (string, error) {
+	return g.run(nil, "diff", "--relative")
+}
+
+// DiffAgainst returns the staged and unstaged changes since ref, with paths
+// relative to the repository directory
+func (g *GitRepository) DiffAgainst(ref string) (string, error) {
+	return g.run(nil, "diff", "--relative", ref)
+}
+
+// StagedDiffRelative returns the diff of the staged changes below the
+// repository directory, with paths relative to it
+func (g *GitRepository) StagedDiffRelative() (string, error) {
+	return g.run(nil, "diff", "--cached", "--relative")
+}
+
+// ChangedFiles returns the staged and unstaged changed files, relative to the
+// repository directory
+func (g *GitRepository) ChangedFiles() ([]string, error) {
+	output, err := g.run(nil, "diff", "HEAD", "--relative", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(output), nil
+}
+
+// UntrackedFiles returns the files git does not track and does not ignore
+func (g *GitRepository) UntrackedFiles() ([]string, error) {
+	output, err := g.run(nil, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(output), nil
+}
+
+// Log returns the last count commit messages, with their short hash and date,
+// of the commits touching paths, or of all commits when paths is empty
+func (g *GitRepository) Log(count int, paths []string) (string, error) {
+	args := []string{"log", fmt.Sprintf("--max-count=%d", count), "--date=short", "--format=%h %ad %s%n%b"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	return g.run(nil, args...)
+}
+
+// StagedFiles returns the staged files below the repository directory,
+// relative to it
+func (g *GitRepository) StagedFiles() ([]string, error) {
+	output, err := g.run(nil, "diff", "--cached", "--relative", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(output), nil
+}
+
+// RefExists reports whether ref names a commit
+func (g *GitRepository) RefExists(ref string) bool {
+	_, err := g.run(nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	return err == nil
+}
+
+// DefaultBaseBranch returns the branch work is usually merged into: the
+// remote's default branch when known, otherwise main or master
+func (g *GitRepository) DefaultBaseBranch() (string, error) {
+	if output, err := g.run(nil, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimSpace(output), nil
+	}
+
+	for _, branch := range []string{"main", "master"} {
+		if g.BranchExists(branch) {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("no base branch found; pass one explicitly")
+}
+
+// RangeLog returns the messages of the commits reachable from head but not
+// from base, oldest first
+func (g *GitRepository) RangeLog(base, head string) (string, error) {
+	return g.run(nil, "log", "--reverse", "--format=commit %h%n%B", base+".."+head)
+}
+
+// RangeDiff returns the changes made on head since it forked from base, with
+// paths relative to the repository directory. With stat only the changed
+// files and line counts are returned.
+func (g *GitRepository) RangeDiff(base, head string, stat bool) (string, error) {
+	args := []string{"diff", "--relative"}
+	if stat {
+		args = append(args, "--stat")
+	}
+	return g.run(nil, append(args, base+"..."+head)...)
+}
+
+// RangeFiles returns the paths changed on head since it forked from base,
+// relative to the repository directory
+func (g *GitRepository) RangeFiles(base, head string) ([]string, error) {
+	output, err := g.run(nil, "diff", "--relative", "--name-only", base+"..."+head)
+	if err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(output), nil
+}
+
+// Commit commits the staged changes with message
+func (g *GitRepository) Commit(message string) error {
+	_, err := g.run(strings.NewReader(message), "commit", "--quiet", "--file", "-")
+	return err
+}
+
+// run runs git with args in the repository directory, feeding it stdin when
+// not nil, and returns its standard output
+func (g *GitRepository) run(stdin *strings.Reader, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.dir}, args...)...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s failed: %s", args[0], message)
+	}
+
+	return stdout.String(), nil
+}
+
+// nonEmptyLines splits output into lines, dropping blank ones
+func nonEmptyLines(output string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// BranchName returns the branch name for a task, such as "ai/fix-the-login-bug"
+func BranchName(task string) string {
+	slug := strings.Trim(nonSlugCharacters.ReplaceAllString(strings.ToLower(task), "-"), "-")
+	if len(slug) > maxBranchSlugLength {
+		slug = strings.TrimRight(slug[:maxBranchSlugLength], "-")
+	}
+	if slug == "" {
+		slug = "task"
+	}
+	return BranchPrefix + slug
+}
+
+// WorktreePath returns where the worktree for branch is created: a directory
+// next to the repository root named after the repository and the branch
+func WorktreePath(root, branch string) string {
+	name := filepath.Base(root) + "-" + strings.ReplaceAll(strings.TrimPrefix(branch, BranchPrefix), "/", "-")
+	return filepath.Join(filepath.Dir(root), name)
+}
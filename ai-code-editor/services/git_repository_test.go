@@ -0,0 +1,194 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepository creates a git repository with one commit in a temp directory
+func newTestRepository(t *testing.T) (*GitRepository, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	repo := NewGitRepository(dir)
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		if _, err := repo.run(nil, args...); err != nil {
+			t.Fatalf("Failed to set up repository: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := repo.StageAll(); err != nil {
+		t.Fatalf("StageAll failed: %v", err)
+	}
+	if err := repo.Commit("Initial commit"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	return repo, dir
+}
+
+func TestBranchName(t *testing.T) {
+	tests := []struct {
+		task     string
+		expected string
+	}{
+		{"Fix the login bug", "ai/fix-the-login-bug"},
+		{"  Add `--verbose` flag!  ", "ai/add-verbose-flag"},
+		{"Rename UserService.Get to UserService.Find in every package of the project", "ai/rename-userservice-get-to-userservice-fi"},
+		{"???", "ai/task"},
+	}
+
+	for _, tt := range tests {
+		if name := BranchName(tt.task); name != tt.expected {
+			t.Errorf("BranchName(%q) = %q, expected %q", tt.task, name, tt.expected)
+		}
+	}
+}
+
+func TestWorktreePath(t *testing.T) {
+	path := WorktreePath("/src/project", "ai/fix-bug")
+	if expected := filepath.Join("/src", "project-fix-bug"); path != expected {
+		t.Errorf("Expected %s, got %s", expected, path)
+	}
+}
+
+func TestGitRepository_BranchAndCommit(t *testing.T) {
+	repo, dir := newTestRepository(t)
+
+	if !repo.IsRepository() {
+		t.Fatal("Expected the directory to be a repository")
+	}
+	if dirty, err := repo.HasChanges(); err != nil || dirty {
+		t.Fatalf("Expected a clean working tree, got %v, %v", dirty, err)
+	}
+
+	branch := repo.UniqueBranchName("Fix bug")
+	if err := repo.CreateBranch(branch); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if current, _ := repo.CurrentBranch(); current != "ai/fix-bug" {
+		t.Errorf("Expected to be on ai/fix-bug, got %s", current)
+	}
+	if next := repo.UniqueBranchName("Fix bug"); next != "ai/fix-bug-2" {
+		t.Errorf("Expected ai/fix-bug-2 for an existing branch, got %s", next)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if dirty, _ := repo.HasChanges(); !dirty {
+		t.Error("Expected changes after editing a file")
+	}
+
+	// Only the edited file is staged, not unrelated files in the working tree
+	if err := os.WriteFile(filepath.Join(dir, "build.log"), []byte("output\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := repo.Stage([]string{filepath.Join(dir, "main.go")}); err != nil {
+		t.Fatalf("Stage failed: %v", err)
+	}
+	diff, err := repo.StagedDiff()
+	if err != nil || !strings.Contains(diff, "+func main() {}") {
+		t.Fatalf("Expected the staged diff to contain the edit, got %q, %v", diff, err)
+	}
+	if strings.Contains(diff, "build.log") {
+		t.Errorf("Expected build.log not to be staged, got %q", diff)
+	}
+
+	if err := repo.Commit("Add main function\n\nTask: Fix bug\n"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	message, err := repo.run(nil, "log", "-1", "--format=%B")
+	if err != nil || !strings.HasPrefix(message, "Add main function\n\nTask: Fix bug") {
+		t.Errorf("Unexpected commit message %q, %v", message, err)
+	}
+}
+
+func TestGitRepository_AddWorktree(t *testing.T) {
+	repo, dir := newTestRepository(t)
+
+	worktreePath := WorktreePath(dir, "ai/fix-bug")
+	t.Cleanup(func() { os.RemoveAll(worktreePath) })
+
+	if err := repo.AddWorktree(worktreePath, "ai/fix-bug"); err != nil {
+		t.Fatalf("AddWorktree failed: %v", err)
+	}
+
+	worktree := NewGitRepository(worktreePath)
+	if current, _ := worktree.CurrentBranch(); current != "ai/fix-bug" {
+		t.Errorf("Expected the worktree on ai/fix-bug, got %s", current)
+	}
+	if current, _ := repo.CurrentBranch(); current != "main" {
+		t.Errorf("Expected the main checkout to stay on main, got %s", current)
+	}
+	if _, err := os.Stat(filepath.Join(worktreePath, "main.go")); err != nil {
+		t.Errorf("Expected the worktree to contain main.go: %v", err)
+	}
+}
+
+func TestGitRepository_Range(t *testing.T) {
+	repo, dir := newTestRepository(t)
+
+	if base, err := repo.DefaultBaseBranch(); err != nil || base != "main" {
+		t.Errorf("Expected main as the base branch, got %q, %v", base, err)
+	}
+
+	if err := repo.CreateBranch("feature"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	for i, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := repo.StageAll(); err != nil {
+			t.Fatalf("StageAll failed: %v", err)
+		}
+		if i == 0 {
+			if staged, err := repo.StagedFiles(); err != nil || len(staged) != 1 || staged[0] != "a.go" {
+				t.Errorf("Expected a.go to be staged, got %v, %v", staged, err)
+			}
+		}
+		if err := repo.Commit("Add " + name); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+
+	commits, err := repo.RangeLog("main", "HEAD")
+	if err != nil {
+		t.Fatalf("RangeLog failed: %v", err)
+	}
+	if first, second := strings.Index(commits, "Add a.go"), strings.Index(commits, "Add b.go"); first < 0 || second < first {
+		t.Errorf("Expected both commits oldest first, got %q", commits)
+	}
+	if strings.Contains(commits, "Initial commit") {
+		t.Errorf("Expected commits on main to be left out, got %q", commits)
+	}
+
+	stat, err := repo.RangeDiff("main", "HEAD", true)
+	if err != nil || !strings.Contains(stat, "a.go") || !strings.Contains(stat, "b.go") {
+		t.Errorf("Expected the diff stat to list a.go and b.go, got %q, %v", stat, err)
+	}
+
+	files, err := repo.RangeFiles("main", "HEAD")
+	if err != nil || len(files) != 2 || files[0] != "a.go" || files[1] != "b.go" {
+		t.Errorf("Expected a.go and b.go to have changed, got %v, %v", files, err)
+	}
+
+	if !repo.RefExists("main") || repo.RefExists("missing") {
+		t.Error("Expected RefExists to find main only")
+	}
+}
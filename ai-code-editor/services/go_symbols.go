@@ -0,0 +1,218 @@
+package services
+
+import (
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// maxConstValueLength caps constant values shown in signatures
+const maxConstValueLength = 60
+
+// goSymbols returns the top level functions, methods, types and exported
+// constants and variables of a Go file, with their doc comments. Structs list
+// their fields and interfaces their method sets as members.
+func goSymbols(root *tree_sitter.Node, source []byte, path string) []Symbol {
+	symbols := make([]Symbol, 0)
+
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		node := root.NamedChild(i)
+
+		switch node.Kind() {
+		case "function_declaration", "method_declaration":
+			nameNode := node.ChildByFieldName("name")
+			if nameNode == nil {
+				continue
+			}
+
+			kind := SymbolKindFunction
+			if node.Kind() == "method_declaration" {
+				kind = SymbolKindMethod
+			}
+			symbol := newSymbol(node, nameNode.Utf8Text(source), kind, goFunctionSignature(node, source), path)
+			symbol.Doc = goDocComment(node, source)
+			symbols = append(symbols, symbol)
+
+		case "type_declaration":
+			for _, spec := range goSpecs(node) {
+				if symbol, ok := goTypeSymbol(spec, source, path); ok {
+					symbols = append(symbols, symbol)
+				}
+			}
+
+		case "const_declaration", "var_declaration":
+			for _, spec := range goSpecs(node) {
+				symbols = append(symbols, goValueSymbols(spec, source, path)...)
+			}
+		}
+	}
+
+	return symbols
+}
+
+// goSpecs returns the specs of a declaration, looking inside grouped var blocks
+func goSpecs(declaration *tree_sitter.Node) []*tree_sitter.Node {
+	specs := make([]*tree_sitter.Node, 0)
+	for i := uint(0); i < declaration.NamedChildCount(); i++ {
+		child := declaration.NamedChild(i)
+		switch child.Kind() {
+		case "var_spec_list":
+			specs = append(specs, goSpecs(child)...)
+		case "type_spec", "type_alias", "const_spec", "var_spec":
+			specs = append(specs, child)
+		}
+	}
+	return specs
+}
+
+// goTypeSymbol describes a type spec. Struct fields and interface methods
+// become members.
+func goTypeSymbol(spec *tree_sitter.Node, source []byte, path string) (Symbol, bool) {
+	nameNode := spec.ChildByFieldName("name")
+	typeNode := spec.ChildByFieldName("type")
+	if nameNode == nil || typeNode == nil {
+		return Symbol{}, false
+	}
+
+	name := nameNode.Utf8Text(source)
+	signature := "type " + name
+	if typeParameters := spec.ChildByFieldName("type_parameters"); typeParameters != nil {
+		signature += typeParameters.Utf8Text(source)
+	}
+	if spec.Kind() == "type_alias" {
+		signature += " ="
+	}
+
+	kind := SymbolKindType
+	var members []string
+
+	switch typeNode.Kind() {
+	case "struct_type":
+		kind = SymbolKindClass
+		signature += " struct"
+		for i := uint(0); i < typeNode.NamedChildCount(); i++ {
+			if fields := typeNode.NamedChild(i); fields.Kind() == "field_declaration_list" {
+				members = goMembers(fields, "field_declaration", source)
+			}
+		}
+	case "interface_type":
+		kind = SymbolKindInterface
+		signature += " interface"
+		members = goMembers(typeNode, "", source)
+	default:
+		signature += " " + typeNode.Utf8Text(source)
+	}
+
+	symbol := newSymbol(spec, name, kind, signature, path)
+	symbol.Doc = goDocComment(spec, source)
+	symbol.Members = members
+	return symbol, true
+}
+
+// goMembers returns the named children of a struct field list or interface as
+// source lines, keeping comments on the same line. An empty kind accepts any
+// child that is not a comment.
+func goMembers(list *tree_sitter.Node, kind string, source []byte) []string {
+	members := make([]string, 0)
+
+	for i := uint(0); i < list.NamedChildCount(); i++ {
+		child := list.NamedChild(i)
+		if child.Kind() == "comment" || (kind != "" && child.Kind() != kind) {
+			continue
+		}
+
+		member := strings.Join(strings.Fields(child.Utf8Text(source)), " ")
+		if next := child.NextNamedSibling(); next != nil && next.Kind() == "comment" && next.StartPosition().Row == child.EndPosition().Row {
+			member += " " + next.Utf8Text(source)
+		}
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// goValueSymbols describes the exported names of a const or var spec
+func goValueSymbols(spec *tree_sitter.Node, source []byte, path string) []Symbol {
+	keyword, kind := "var", SymbolKindVariable
+	if spec.Kind() == "const_spec" {
+		keyword, kind = "const", SymbolKindConstant
+	}
+
+	suffix := ""
+	if typeNode := spec.ChildByFieldName("type"); typeNode != nil {
+		suffix += " " + typeNode.Utf8Text(source)
+	}
+	if value := spec.ChildByFieldName("value"); value != nil && kind == SymbolKindConstant {
+		text := strings.Join(strings.Fields(value.Utf8Text(source)), " ")
+		if len(text) > maxConstValueLength {
+			text = text[:maxConstValueLength] + "..."
+		}
+		suffix += " = " + text
+	}
+
+	doc := goDocComment(spec, source)
+	symbols := make([]Symbol, 0)
+	for i := uint(0); i < spec.NamedChildCount(); i++ {
+		child := spec.NamedChild(i)
+		if child.Kind() != "identifier" || spec.FieldNameForNamedChild(uint32(i)) != "name" {
+			continue
+		}
+
+		name := child.Utf8Text(source)
+		if !isExported(name) {
+			continue
+		}
+
+		symbol := newSymbol(spec, name, kind, keyword+" "+name+suffix, path)
+		symbol.Doc = doc
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+// goDocComment returns the comment lines directly above a declaration. A spec
+// that is alone in its declaration uses the comment above the declaration.
+func goDocComment(node *tree_sitter.Node, source []byte) string {
+	lines := make([]string, 0)
+	row := node.StartPosition().Row
+
+	for previous := node.PrevSibling(); previous != nil && previous.Kind() == "comment"; previous = previous.PrevSibling() {
+		if previous.EndPosition().Row+1 != row {
+			break
+		}
+		lines = append([]string{previous.Utf8Text(source)}, lines...)
+		row = previous.StartPosition().Row
+	}
+
+	if len(lines) == 0 {
+		if parent := node.Parent(); parent != nil && strings.HasSuffix(parent.Kind(), "_declaration") &&
+			parent.Kind() != "function_declaration" && parent.Kind() != "method_declaration" && len(goSpecs(parent)) == 1 {
+			return goDocComment(parent, source)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// goFunctionSignature renders a function or method declaration without its body
+func goFunctionSignature(node *tree_sitter.Node, source []byte) string {
+	var signature strings.Builder
+	signature.WriteString("func ")
+
+	if receiver := node.ChildByFieldName("receiver"); receiver != nil {
+		signature.WriteString(receiver.Utf8Text(source) + " ")
+	}
+	signature.WriteString(node.ChildByFieldName("name").Utf8Text(source))
+	if typeParameters := node.ChildByFieldName("type_parameters"); typeParameters != nil {
+		signature.WriteString(typeParameters.Utf8Text(source))
+	}
+	if parameters := node.ChildByFieldName("parameters"); parameters != nil {
+		signature.WriteString(parameters.Utf8Text(source))
+	}
+	if result := node.ChildByFieldName("result"); result != nil {
+		signature.WriteString(" " + result.Utf8Text(source))
+	}
+
+	return signature.String()
+}
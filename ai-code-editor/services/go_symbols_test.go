@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestGoOutline(t *testing.T) {
+	source := `package store
+
+// DefaultLimit is used when no limit is given
+const DefaultLimit = 10
+
+const (
+	// ModeFast skips validation
+	ModeFast Mode = iota
+	modeHidden
+)
+
+var ErrMissing = errors.New("missing")
+
+// Store keeps items in memory
+type Store struct {
+	items map[string]string // keyed by id
+	Limit int
+}
+
+// Reader reads items
+type Reader interface {
+	Get(key string) (string, bool)
+}
+
+// Get returns the item for key
+func (s *Store) Get(key string) (string, bool) {
+	return s.items[key], true
+}
+`
+
+	extractor := NewSymbolExtractor()
+	defer extractor.Close()
+
+	outline := renderOutline(extractor.Extract("store.go", []byte(source)))
+	expected := `// DefaultLimit is used when no limit is given
+const DefaultLimit = 10
+// ModeFast skips validation
+const ModeFast Mode = iota
+var ErrMissing
+// Store keeps items in memory
+type Store struct {
+	items map[string]string // keyed by id
+	Limit int
+}
+// Reader reads items
+type Reader interface {
+	Get(key string) (string, bool)
+}
+// Get returns the item for key
+func (s *Store) Get(key string) (string, bool)
+`
+	if outline != expected {
+		t.Errorf("Expected outline:\n%s\nGot:\n%s", expected, outline)
+	}
+}
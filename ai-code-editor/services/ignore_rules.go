@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern is one line of a .gitignore style file
+type ignorePattern struct {
+	base    string // directory of the ignore file relative to the root, "" for the root
+	regex   *regexp.Regexp
+	negate  bool // a "!" pattern that re-includes matching paths
+	dirOnly bool // a pattern ending in "/" that only matches directories
+}
+
+// ignoreRules holds the patterns that apply to a walk. Later patterns take
+// precedence over earlier ones, as in git.
+type ignoreRules struct {
+	patterns []ignorePattern
+}
+
+// addFile adds the patterns of an ignore file located in the directory base.
+// Missing files are ignored.
+func (r *ignoreRules) addFile(filePath, base string) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		r.addPattern(scanner.Text(), base)
+	}
+}
+
+// addDirectory adds the .gitignore and .aicodeignore files of the directory
+// dir, whose path relative to the root is base
+func (r *ignoreRules) addDirectory(dir, base string) {
+	r.addFile(filepath.Join(dir, ".gitignore"), base)
+	r.addFile(filepath.Join(dir, projectIgnoreFile), base)
+}
+
+// addPattern parses a single pattern line relative to the directory base
+func (r *ignoreRules) addPattern(line, base string) {
+	pattern, ok := newIgnorePattern(line, base)
+	if ok {
+		r.patterns = append(r.patterns, pattern)
+	}
+}
+
+// ignored reports whether a slash separated path relative to the root is
+// ignored. The last matching pattern decides.
+func (r *ignoreRules) ignored(relPath string, isDir bool) bool {
+	ignored := false
+
+	for _, pattern := range r.patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+
+		target := relPath
+		if pattern.base != "" {
+			if !strings.HasPrefix(relPath, pattern.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(relPath, pattern.base+"/")
+		}
+
+		if pattern.regex.MatchString(target) {
+			ignored = !pattern.negate
+		}
+	}
+
+	return ignored
+}
+
+// newIgnorePattern parses a .gitignore line. It returns false for blank lines
+// and comments.
+func newIgnorePattern(line, base string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, "\r")
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	pattern := ignorePattern{base: base}
+
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// A pattern with a slash is relative to the ignore file's directory,
+	// otherwise it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expression := globToRegexp(line)
+	if anchored {
+		expression = "^" + expression + "$"
+	} else {
+		expression = "(?:^|/)" + expression + "$"
+	}
+
+	regex, err := regexp.Compile(expression)
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	pattern.regex = regex
+
+	return pattern, true
+}
+
+// globToRegexp converts a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var expression strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			expression.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case c == '*':
+			expression.WriteString("[^/]*")
+		case c == '?':
+			expression.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expression.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expression.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expression.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return expression.String()
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const progressBarWidth = 30
+
+// IndexProgress renders a single-line progress bar for indexing, showing files
+// and chunks processed per second. It is safe for concurrent use. As a writer
+// it prints lines, such as log output, above the bar.
+type IndexProgress struct {
+	mu          sync.Mutex
+	out         io.Writer
+	totalFiles  int
+	doneFiles   int
+	doneChunks  int
+	started     time.Time
+	lastRender  time.Time
+	minInterval time.Duration
+}
+
+// NewIndexProgress creates a progress bar for totalFiles files writing to out
+func NewIndexProgress(out io.Writer, totalFiles int) *IndexProgress {
+	return &IndexProgress{
+		out:         out,
+		totalFiles:  totalFiles,
+		started:     time.Now(),
+		minInterval: 100 * time.Millisecond,
+	}
+}
+
+// FileDone records a finished file and the number of chunks it produced
+func (p *IndexProgress) FileDone(chunks int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.doneFiles++
+	p.doneChunks += chunks
+
+	// Throttle redraws, but always draw the final state
+	if time.Since(p.lastRender) >= p.minInterval || p.doneFiles == p.totalFiles {
+		p.render()
+	}
+}
+
+// Write clears the bar, prints text on its own line and draws the bar again
+func (p *IndexProgress) Write(text []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := fmt.Fprintf(p.out, "\r\033[K%s", text); err != nil {
+		return 0, err
+	}
+	if len(text) > 0 && text[len(text)-1] != '\n' {
+		fmt.Fprintln(p.out)
+	}
+	p.render()
+	return len(text), nil
+}
+
+// Finish draws the final state and moves to a new line
+func (p *IndexProgress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.render()
+	fmt.Fprintln(p.out)
+}
+
+func (p *IndexProgress) render() {
+	p.lastRender = time.Now()
+
+	filled := progressBarWidth
+	if p.totalFiles > 0 {
+		filled = p.doneFiles * progressBarWidth / p.totalFiles
+	}
+
+	elapsed := time.Since(p.started).Seconds()
+	filesPerSecond, chunksPerSecond := 0.0, 0.0
+	if elapsed > 0 {
+		filesPerSecond = float64(p.doneFiles) / elapsed
+		chunksPerSecond = float64(p.doneChunks) / elapsed
+	}
+
+	fmt.Fprintf(p.out, "\rIndexing [%s%s] %d/%d files, %d chunks | %.1f files/s, %.1f chunks/s",
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", progressBarWidth-filled),
+		p.doneFiles, p.totalFiles, p.doneChunks,
+		filesPerSecond, chunksPerSecond,
+	)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// definitionBoost is how many extra times an identifier counts when the
+	// document defines it rather than only referencing it
+	definitionBoost = 3
+)
+
+// definitionPattern matches the name introduced by common declaration keywords
+// across languages, including Go method receivers
+var definitionPattern = regexp.MustCompile(
+	`\b(?:func|type|class|def|interface|struct|enum|trait|fn|impl|record)\s+(?:\([^)]*\)\s*)?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// LexicalIndex is an in-memory BM25 index over code chunks. Identifiers are
+// indexed whole and split into their camelCase and snake_case parts, so a query
+// for ExecuteEditFileAction matches the exact identifier first and related
+// names second. It is safe for concurrent use.
+type LexicalIndex struct {
+	mu            sync.RWMutex
+	documents     map[string]*lexicalDocument
+	postings      map[string]map[string]int // term -> document ID -> term frequency
+	totalTermsLen int
+}
+
+type lexicalDocument struct {
+	id       string
+	content  string
+	metadata map[string]interface{}
+	length   int
+}
+
+// NewLexicalIndex creates an empty lexical index
+func NewLexicalIndex() *LexicalIndex {
+	return &LexicalIndex{
+		documents: make(map[string]*lexicalDocument),
+		postings:  make(map[string]map[string]int),
+	}
+}
+
+// Add indexes a document, replacing any document with the same ID
+func (l *LexicalIndex) Add(id, content string, metadata map[string]interface{}) {
+	terms := tokenizeCode(content)
+	for _, name := range definedNames(content) {
+		for range definitionBoost {
+			terms = append(terms, strings.ToLower(name))
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.remove(id)
+
+	l.documents[id] = &lexicalDocument{
+		id:       id,
+		content:  content,
+		metadata: metadata,
+		length:   len(terms),
+	}
+	l.totalTermsLen += len(terms)
+
+	for _, term := range terms {
+		docs, ok := l.postings[term]
+		if !ok {
+			docs = make(map[string]int)
+			l.postings[term] = docs
+		}
+		docs[id]++
+	}
+}
+
+// Len returns the number of indexed documents
+func (l *LexicalIndex) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return len(l.documents)
+}
+
+// Search returns up to limit documents ranked by BM25 score for the query.
+// Documents for which match returns false are skipped; a nil match keeps all.
+func (l *LexicalIndex) Search(query string, limit int, match func(metadata map[string]interface{}) bool) []SearchResult {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if len(l.documents) == 0 {
+		return []SearchResult{}
+	}
+
+	averageLength := float64(l.totalTermsLen) / float64(len(l.documents))
+	documentCount := float64(len(l.documents))
+	scores := make(map[string]float64)
+
+	for _, term := range uniqueStrings(tokenizeCode(query)) {
+		docs := l.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+
+		idf := math.Log(1 + (documentCount-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+		for id, frequency := range docs {
+			length := float64(l.documents[id].length)
+			tf := float64(frequency)
+			scores[id] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/averageLength))
+		}
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for id, score := range scores {
+		document := l.documents[id]
+		if match != nil && !match(document.metadata) {
+			continue
+		}
+		results = append(results, newSearchResult(id, document.content, document.metadata, score))
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results
+}
+
+// remove drops a document from the index. The caller must hold the write lock.
+func (l *LexicalIndex) remove(id string) {
+	document, ok := l.documents[id]
+	if !ok {
+		return
+	}
+
+	for term, docs := range l.postings {
+		if _, ok := docs[id]; ok {
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(l.postings, term)
+			}
+		}
+	}
+
+	l.totalTermsLen -= document.length
+	delete(l.documents, id)
+}
+
+// tokenizeCode splits text into lowercase search terms. Each identifier yields
+// itself plus its camelCase, PascalCase and snake_case parts.
+func tokenizeCode(text string) []string {
+	terms := make([]string, 0)
+
+	identifiers := strings.FieldsFunc(text, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+	})
+
+	for _, identifier := range identifiers {
+		lower := strings.ToLower(identifier)
+		terms = append(terms, lower)
+
+		parts := splitIdentifier(identifier)
+		if len(parts) > 1 {
+			for _, part := range parts {
+				terms = append(terms, strings.ToLower(part))
+			}
+		}
+	}
+
+	return terms
+}
+
+// splitIdentifier splits an identifier on underscores and case changes, keeping
+// acronyms together: "parseHTTPRequest_v2" -> parse, HTTP, Request, v2
+func splitIdentifier(identifier string) []string {
+	parts := make([]string, 0)
+
+	for _, word := range strings.Split(identifier, "_") {
+		runes := []rune(word)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			previous, current := runes[i-1], runes[i]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			lowerToUpper := unicode.IsLower(previous) && unicode.IsUpper(current)
+			acronymEnd := unicode.IsUpper(previous) && unicode.IsUpper(current) && nextIsLower
+
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+
+	return parts
+}
+
+// definedNames returns the identifiers declared in a piece of code
+func definedNames(code string) []string {
+	names := make([]string, 0)
+	for _, match := range definitionPattern.FindAllStringSubmatch(code, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
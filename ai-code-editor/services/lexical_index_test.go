@@ -0,0 +1,91 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexicalIndex_IdentifierQueryFindsDefiningFile(t *testing.T) {
+	index := NewLexicalIndex()
+	index.Add("caller", `func (c *CodeEditor) EditCode() {
+	for _, actions := range fileActions {
+		c.ExecuteEditFileAction(actions)
+	}
+}`, map[string]interface{}{"path": "codeEditor/code_editor.go"})
+	index.Add("definition", `func (c *CodeEditor) ExecuteEditFileAction(actions []BaseAction) {
+	services.EditFile(firstPath, editFileActions)
+}`, map[string]interface{}{"path": "codeEditor/actions.go"})
+	index.Add("unrelated", `func NewDirectoryTree(indent string) *DirectoryTree {
+	return &DirectoryTree{indent: indent}
+}`, map[string]interface{}{"path": "services/directory_tree.go"})
+
+	results := index.Search("where is ExecuteEditFileAction implemented", 3, nil)
+	if len(results) < 2 {
+		t.Fatalf("Expected at least 2 results, got %d", len(results))
+	}
+
+	if results[0].ID != "definition" {
+		t.Errorf("Expected the defining chunk first, got %s", results[0].ID)
+	}
+	if results[0].Path != "codeEditor/actions.go" {
+		t.Errorf("Expected path from metadata, got %q", results[0].Path)
+	}
+}
+
+func TestLexicalIndex_MatchesIdentifierParts(t *testing.T) {
+	index := NewLexicalIndex()
+	index.Add("tree", "func (dt *DirectoryTree) GetFunctionSignatures(filePath string) []string", nil)
+	index.Add("other", "func ParseResponse(response string) []BaseAction", nil)
+
+	results := index.Search("function signatures", 5, nil)
+	if len(results) != 1 || results[0].ID != "tree" {
+		t.Errorf("Expected only the chunk with GetFunctionSignatures, got %v", results)
+	}
+}
+
+func TestLexicalIndex_ReplaceAndFilter(t *testing.T) {
+	index := NewLexicalIndex()
+	index.Add("a", "alpha beta", map[string]interface{}{"path": "a.go"})
+	index.Add("a", "gamma", map[string]interface{}{"path": "a.go"})
+	index.Add("b", "gamma", map[string]interface{}{"path": "b_test.go"})
+
+	if index.Len() != 2 {
+		t.Errorf("Expected 2 documents after replacing one, got %d", index.Len())
+	}
+	if results := index.Search("alpha", 5, nil); len(results) != 0 {
+		t.Errorf("Expected replaced content to be gone, got %v", results)
+	}
+
+	results := index.Search("gamma", 5, func(metadata map[string]interface{}) bool {
+		return metadata["path"] != "b_test.go"
+	})
+	if len(results) != 1 || results[0].ID != "a" {
+		t.Errorf("Expected filter to keep only a, got %v", results)
+	}
+}
+
+func TestSplitIdentifier(t *testing.T) {
+	expected := []string{"parse", "HTTP", "Request", "v2"}
+	if parts := splitIdentifier("parseHTTPRequest_v2"); !reflect.DeepEqual(parts, expected) {
+		t.Errorf("Expected %v, got %v", expected, parts)
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	semantic := []SearchResult{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	lexical := []SearchResult{{ID: "c"}, {ID: "d"}}
+
+	fused := FuseRankings([][]SearchResult{semantic, lexical}, []float64{1, 1}, 60)
+	if len(fused) != 4 {
+		t.Fatalf("Expected 4 unique results, got %d", len(fused))
+	}
+	if fused[0].ID != "c" {
+		t.Errorf("Expected the result found by both searches first, got %s", fused[0].ID)
+	}
+
+	// Weighting the lexical ranking heavily puts its top hit first
+	fused = FuseRankings([][]SearchResult{semantic, lexical}, []float64{0.1, 1}, 60)
+	if fused[0].ID != "c" || fused[1].ID != "d" {
+		t.Errorf("Expected lexical results to lead, got %s, %s", fused[0].ID, fused[1].ID)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"ai-code-editor/ollama"
+
+	"github.com/amikos-tech/chroma-go/types"
+)
+
+// OllamaEmbeddingFunction embeds documents through Ollama's /api/embed
+// endpoint. It satisfies chroma's EmbeddingFunction so collections use it for
+// query embeddings as well. Callers decide the batch size: every call is one
+// request.
+type OllamaEmbeddingFunction struct {
+	client *ollama.Client
+	model  string
+}
+
+var _ types.EmbeddingFunction = (*OllamaEmbeddingFunction)(nil)
+
+// NewOllamaEmbeddingFunction creates an embedding function for the given model
+func NewOllamaEmbeddingFunction(client *ollama.Client, model string) *OllamaEmbeddingFunction {
+	return &OllamaEmbeddingFunction{
+		client: client,
+		model:  model,
+	}
+}
+
+// Embed returns one embedding per text using a single request
+func (e *OllamaEmbeddingFunction) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	embeddings, err := e.client.Embed(e.model, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed batch: %w", err)
+	}
+
+	return embeddings, nil
+}
+
+// EmbedDocuments returns a vector for each text
+func (e *OllamaEmbeddingFunction) EmbedDocuments(ctx context.Context, texts []string) ([]*types.Embedding, error) {
+	embeddings, err := e.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewEmbeddingsFromFloat32(embeddings), nil
+}
+
+// EmbedQuery embeds a single text
+func (e *OllamaEmbeddingFunction) EmbedQuery(ctx context.Context, text string) (*types.Embedding, error) {
+	embeddings, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewEmbeddingFromFloat32(embeddings[0]), nil
+}
+
+// EmbedRecords embeds records that do not have an embedding yet
+func (e *OllamaEmbeddingFunction) EmbedRecords(ctx context.Context, records []*types.Record, force bool) error {
+	return types.EmbedRecordsDefaultImpl(e, ctx, records, force)
+}
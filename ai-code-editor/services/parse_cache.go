@@ -0,0 +1,331 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const (
+	// parseCacheVersion changes whenever cached symbols are extracted differently,
+	// so stale caches are discarded
+	parseCacheVersion = 2
+	// maxCachedTrees bounds how many parse trees are kept for incremental re-parsing
+	maxCachedTrees = 64
+)
+
+// parseCacheEntry is what is remembered about one file
+type parseCacheEntry struct {
+	ModTime    time.Time      `json:"modTime"`
+	Size       int64          `json:"size"`
+	Hash       string         `json:"hash"`
+	Symbols    []Symbol       `json:"symbols"`
+	References map[string]int `json:"references"`
+}
+
+type parseCacheFile struct {
+	Version int                         `json:"version"`
+	Entries map[string]*parseCacheEntry `json:"entries"`
+}
+
+// cachedTree is a parse tree kept in memory with the source it was parsed from
+type cachedTree struct {
+	tree   *tree_sitter.Tree
+	source []byte
+}
+
+// ParseCache remembers the symbols and references of files, keyed by absolute
+// path and validated by modification time, size and content hash. It is saved
+// to disk so later runs skip unchanged files. Trees of recently parsed files
+// stay in memory, so a file edited during a run is re-parsed incrementally.
+type ParseCache struct {
+	mu        sync.Mutex
+	path      string
+	entries   map[string]*parseCacheEntry
+	trees     map[string]*cachedTree
+	treeOrder []string // oldest first
+	extractor *SymbolExtractor
+	dirty     bool
+}
+
+var (
+	sharedParseCache     *ParseCache
+	sharedParseCacheOnce sync.Once
+)
+
+// SharedParseCache returns the process wide cache stored at DefaultParseCachePath
+func SharedParseCache() *ParseCache {
+	sharedParseCacheOnce.Do(func() {
+		sharedParseCache = OpenParseCache(DefaultParseCachePath())
+	})
+	return sharedParseCache
+}
+
+// DefaultParseCachePath returns the cache file in the user's cache directory
+func DefaultParseCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ai-code-editor", "parse_cache.json")
+}
+
+// OpenParseCache loads the cache stored at path. A missing, unreadable or
+// outdated cache file gives an empty cache.
+func OpenParseCache(path string) *ParseCache {
+	cache := &ParseCache{
+		path:      path,
+		entries:   make(map[string]*parseCacheEntry),
+		trees:     make(map[string]*cachedTree),
+		extractor: NewSymbolExtractor(),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: failed to read parse cache %s: %v", path, err)
+		}
+		return cache
+	}
+
+	var file parseCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Printf("Warning: ignoring corrupt parse cache %s: %v", path, err)
+		return cache
+	}
+	if file.Version == parseCacheVersion && file.Entries != nil {
+		cache.entries = file.Entries
+	}
+
+	return cache
+}
+
+// Symbols returns the symbols declared in a file
+func (c *ParseCache) Symbols(path string) ([]Symbol, error) {
+	symbols, _, err := c.Analyze(path)
+	return symbols, err
+}
+
+// Analyze returns the symbols declared in a file and the identifiers it uses.
+// Files in unsupported languages have neither.
+func (c *ParseCache) Analyze(path string) ([]Symbol, map[string]int, error) {
+	language := SymbolLanguageForPath(path)
+	if language == nil {
+		return nil, nil, nil
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[absPath]
+	if ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
+		return withSymbolPath(entry.Symbols, path), entry.References, nil
+	}
+
+	source, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	hash := contentHash(source)
+
+	// Touched but unchanged files only need their timestamp updated
+	if ok && entry.Hash == hash {
+		entry.ModTime = info.ModTime()
+		entry.Size = info.Size()
+		c.dirty = true
+		return withSymbolPath(entry.Symbols, path), entry.References, nil
+	}
+
+	tree := c.parse(absPath, language, source)
+	references := make(map[string]int)
+	symbols := c.extractor.symbols(language, tree, source, path, references)
+
+	c.entries[absPath] = &parseCacheEntry{
+		ModTime:    info.ModTime(),
+		Size:       info.Size(),
+		Hash:       hash,
+		Symbols:    symbols,
+		References: references,
+	}
+	c.dirty = true
+
+	return symbols, references, nil
+}
+
+// parse parses a file, re-using its previous tree when one is cached
+func (c *ParseCache) parse(absPath string, language *SymbolLanguage, source []byte) *tree_sitter.Tree {
+	previous, ok := c.trees[absPath]
+	if !ok {
+		tree := c.extractor.parse(language, source, nil)
+		c.rememberTree(absPath, tree, source)
+		return tree
+	}
+
+	edit := sourceEdit(previous.source, source)
+	previous.tree.Edit(&edit)
+	tree := c.extractor.parse(language, source, previous.tree)
+	previous.tree.Close()
+
+	previous.tree = tree
+	previous.source = source
+	if tree == nil {
+		c.forgetTree(absPath)
+	}
+	return tree
+}
+
+// rememberTree keeps a tree for incremental re-parsing, closing the oldest
+// trees once there are too many
+func (c *ParseCache) rememberTree(absPath string, tree *tree_sitter.Tree, source []byte) {
+	if tree == nil {
+		return
+	}
+
+	c.trees[absPath] = &cachedTree{tree: tree, source: source}
+	c.treeOrder = append(c.treeOrder, absPath)
+
+	for len(c.treeOrder) > maxCachedTrees {
+		c.forgetTree(c.treeOrder[0])
+	}
+}
+
+func (c *ParseCache) forgetTree(absPath string) {
+	if cached, ok := c.trees[absPath]; ok {
+		if cached.tree != nil {
+			cached.tree.Close()
+		}
+		delete(c.trees, absPath)
+	}
+
+	for i, path := range c.treeOrder {
+		if path == absPath {
+			c.treeOrder = append(c.treeOrder[:i], c.treeOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// Save writes the cache to disk if it changed, dropping files that no longer exist
+func (c *ParseCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	for path := range c.entries {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(c.entries, path)
+		}
+	}
+
+	data, err := json.Marshal(parseCacheFile{Version: parseCacheVersion, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("failed to marshal parse cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create parse cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial cache
+	tempPath := c.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write parse cache: %w", err)
+	}
+	if err := os.Rename(tempPath, c.path); err != nil {
+		return fmt.Errorf("failed to replace parse cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// Close releases the cached trees and parsers. The cache is not saved.
+func (c *ParseCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cached := range c.trees {
+		cached.tree.Close()
+	}
+	c.trees = make(map[string]*cachedTree)
+	c.treeOrder = nil
+	c.extractor.Close()
+}
+
+// sourceEdit describes how oldSource became newSource as a single replaced
+// range between their common prefix and common suffix
+func sourceEdit(oldSource, newSource []byte) tree_sitter.InputEdit {
+	prefix := 0
+	for prefix < len(oldSource) && prefix < len(newSource) && oldSource[prefix] == newSource[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(oldSource)-prefix && suffix < len(newSource)-prefix &&
+		oldSource[len(oldSource)-1-suffix] == newSource[len(newSource)-1-suffix] {
+		suffix++
+	}
+
+	oldEnd := len(oldSource) - suffix
+	newEnd := len(newSource) - suffix
+
+	return tree_sitter.InputEdit{
+		StartByte:      uint(prefix),
+		OldEndByte:     uint(oldEnd),
+		NewEndByte:     uint(newEnd),
+		StartPosition:  pointAt(oldSource, prefix),
+		OldEndPosition: pointAt(oldSource, oldEnd),
+		NewEndPosition: pointAt(newSource, newEnd),
+	}
+}
+
+// pointAt returns the row and byte column of an offset in source
+func pointAt(source []byte, offset int) tree_sitter.Point {
+	var point tree_sitter.Point
+	for _, b := range source[:offset] {
+		if b == '\n' {
+			point.Row++
+			point.Column = 0
+		} else {
+			point.Column++
+		}
+	}
+	return point
+}
+
+// withSymbolPath returns copies of symbols pointing at path, which may be
+// relative while the cache is keyed by absolute paths
+func withSymbolPath(symbols []Symbol, path string) []Symbol {
+	copied := make([]Symbol, len(symbols))
+	for i, symbol := range symbols {
+		symbol.Path = path
+		copied[i] = symbol
+	}
+	return copied
+}
+
+func contentHash(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestParseCache_PersistsAndRevalidates(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "store.go")
+	cachePath := filepath.Join(dir, "cache", "parse_cache.json")
+	writeTestFiles(t, dir, map[string]string{"store.go": "package store\n\nfunc Get() {}\n"})
+
+	cache := OpenParseCache(cachePath)
+	symbols, references, err := cache.Analyze(sourcePath)
+	if err != nil || len(symbols) != 1 || references["Get"] != 1 {
+		t.Fatalf("Unexpected analysis: %v %v %v", symbols, references, err)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	cache.Close()
+
+	reopened := OpenParseCache(cachePath)
+	defer reopened.Close()
+	if len(reopened.entries) != 1 {
+		t.Fatalf("Expected the saved entry to be loaded, got %d entries", len(reopened.entries))
+	}
+
+	// An edit with a new modification time is picked up
+	writeTestFiles(t, dir, map[string]string{"store.go": "package store\n\nfunc Get() {}\n\nfunc Put() {}\n"})
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(sourcePath, later, later); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+
+	symbols, err = reopened.Symbols(sourcePath)
+	if err != nil {
+		t.Fatalf("Symbols failed: %v", err)
+	}
+	if summary := symbolSummary(symbols); !reflect.DeepEqual(summary, []string{"function Get", "function Put"}) {
+		t.Errorf("Expected the edited symbols, got %v", summary)
+	}
+}
+
+func TestParseCache_ReparsesIncrementally(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "app.py")
+	writeTestFiles(t, dir, map[string]string{"app.py": "def parse(text):\n    return text\n"})
+
+	cache := OpenParseCache(filepath.Join(dir, "parse_cache.json"))
+	defer cache.Close()
+
+	if _, err := cache.Symbols(sourcePath); err != nil {
+		t.Fatalf("Symbols failed: %v", err)
+	}
+
+	writeTestFiles(t, dir, map[string]string{"app.py": "def parse(text):\n    return text\n\nclass Parser:\n    pass\n"})
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(sourcePath, later, later)
+
+	symbols, err := cache.Symbols(sourcePath)
+	if err != nil {
+		t.Fatalf("Symbols failed: %v", err)
+	}
+	if summary := symbolSummary(symbols); !reflect.DeepEqual(summary, []string{"function parse", "class Parser"}) {
+		t.Errorf("Expected the edited symbols, got %v", summary)
+	}
+	if len(cache.trees) != 1 {
+		t.Errorf("Expected one cached tree, got %d", len(cache.trees))
+	}
+}
+
+func TestSourceEdit(t *testing.T) {
+	edit := sourceEdit([]byte("a\nbc\nd"), []byte("a\nbXYc\nd"))
+	expected := tree_sitter.InputEdit{
+		StartByte:      3,
+		OldEndByte:     3,
+		NewEndByte:     5,
+		StartPosition:  tree_sitter.Point{Row: 1, Column: 1},
+		OldEndPosition: tree_sitter.Point{Row: 1, Column: 1},
+		NewEndPosition: tree_sitter.Point{Row: 1, Column: 3},
+	}
+	if edit != expected {
+		t.Errorf("Expected %+v, got %+v", expected, edit)
+	}
+}
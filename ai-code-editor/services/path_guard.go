@@ -0,0 +1,208 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultDeniedPaths are never read or written by model actions: version
+// control internals, environment files and credentials. They use the
+// .gitignore syntax.
+var DefaultDeniedPaths = []string{
+	".git/",
+	".env",
+	".env.*",
+	".ssh/",
+	".aws/",
+	".gnupg/",
+	".netrc",
+	".npmrc",
+	".pypirc",
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"*.pfx",
+	"*.keystore",
+	"id_rsa*",
+	"id_dsa*",
+	"id_ecdsa*",
+	"id_ed25519*",
+	"credentials.json",
+}
+
+// diffFileHeader matches the "diff --git a/path b/path" line starting each file of a diff
+var diffFileHeader = regexp.MustCompile(`(?m)^diff --git a/(.+) b/(.+)$`)
+
+// PathRefusedError is returned for a path a model action may not use
+type PathRefusedError struct {
+	Path   string
+	Reason string
+}
+
+func (e *PathRefusedError) Error() string {
+	return fmt.Sprintf("access to %s refused: %s", e.Path, e.Reason)
+}
+
+// PathGuard confines the paths of model actions to the project root. Paths
+// are resolved against the root, may not leave it through ".." or symbolic
+// links, and may not match the deny list.
+type PathGuard struct {
+	root         string // as given, used to build the returned paths
+	resolvedRoot string // absolute with symbolic links resolved
+	denied       *ignoreRules
+}
+
+// NewPathGuard creates a guard for the project at root denying the given
+// patterns, in .gitignore syntax
+func NewPathGuard(root string, deniedPatterns []string) *PathGuard {
+	resolvedRoot, err := filepath.Abs(root)
+	if err != nil {
+		resolvedRoot = root
+	}
+	if resolved, err := filepath.EvalSymlinks(resolvedRoot); err == nil {
+		resolvedRoot = resolved
+	}
+
+	denied := &ignoreRules{}
+	for _, pattern := range deniedPatterns {
+		denied.addPattern(pattern, "")
+	}
+
+	return &PathGuard{
+		root:         root,
+		resolvedRoot: resolvedRoot,
+		denied:       denied,
+	}
+}
+
+// Resolve checks that path, relative to the root or absolute, may be used and
+// returns it joined to the root. The file does not have to exist, so paths of
+// new files can be checked too. Refused paths give a *PathRefusedError.
+func (g *PathGuard) Resolve(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", &PathRefusedError{Path: path, Reason: "the path is empty"}
+	}
+
+	absPath := path
+	if !filepath.IsAbs(absPath) {
+		absPath = filepath.Join(g.resolvedRoot, absPath)
+	}
+	absPath = filepath.Clean(absPath)
+
+	relPath, ok := g.relative(absPath)
+	if !ok {
+		// Absolute paths may name the root through a symbolic link
+		resolved, err := resolveExisting(absPath)
+		if err != nil {
+			return "", &PathRefusedError{Path: path, Reason: "it is outside the project"}
+		}
+		if relPath, ok = g.relative(resolved); !ok {
+			return "", &PathRefusedError{Path: path, Reason: "it is outside the project"}
+		}
+		absPath = filepath.Join(g.resolvedRoot, relPath)
+	}
+
+	if g.isDenied(relPath) {
+		return "", &PathRefusedError{Path: path, Reason: "it matches the deny list"}
+	}
+
+	// A symbolic link inside the project may point anywhere
+	resolved, err := resolveExisting(absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	resolvedRelPath, ok := g.relative(resolved)
+	if !ok {
+		return "", &PathRefusedError{Path: path, Reason: "it links outside the project"}
+	}
+	if g.isDenied(resolvedRelPath) {
+		return "", &PathRefusedError{Path: path, Reason: "it links to a path on the deny list"}
+	}
+
+	return filepath.Join(g.root, relPath), nil
+}
+
+// Allowed reports whether relPath, relative to the root, is not on the deny list
+func (g *PathGuard) Allowed(relPath string) bool {
+	return !g.isDenied(relPath)
+}
+
+// FilterDiff drops the files on the deny list from a git diff whose paths are
+// relative to the root, so secrets that changed are not shown to the model
+func (g *PathGuard) FilterDiff(diff string) string {
+	headers := diffFileHeader.FindAllStringSubmatchIndex(diff, -1)
+	if len(headers) == 0 {
+		return strings.TrimSpace(diff)
+	}
+
+	var kept strings.Builder
+	for i, header := range headers {
+		end := len(diff)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+
+		oldPath, newPath := diff[header[2]:header[3]], diff[header[4]:header[5]]
+		if g.isDenied(oldPath) || g.isDenied(newPath) {
+			continue
+		}
+		kept.WriteString(diff[header[0]:end])
+	}
+
+	return strings.TrimSpace(kept.String())
+}
+
+// relative returns absPath relative to the resolved root, and false when it
+// is outside the root
+func (g *PathGuard) relative(absPath string) (string, bool) {
+	relPath, err := filepath.Rel(g.resolvedRoot, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return relPath, true
+}
+
+// isDenied reports whether relPath or any directory containing it matches
+// the deny list
+func (g *PathGuard) isDenied(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	if relPath == "." {
+		return false
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if g.denied.ignored(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(g.resolvedRoot, filepath.FromSlash(relPath)))
+	isDir := err == nil && info.IsDir()
+	return g.denied.ignored(relPath, isDir)
+}
+
+// resolveExisting resolves the symbolic links of path. For a path that does
+// not exist yet the links of its nearest existing ancestor are resolved.
+func resolveExisting(path string) (string, error) {
+	missing := ""
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = filepath.Join(filepath.Base(path), missing)
+		path = parent
+	}
+}
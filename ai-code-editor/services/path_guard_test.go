@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathGuard_Resolve(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"main.go":            "package main\n",
+		"services/editor.go": "package services\n",
+		".git/config":        "[core]\n",
+		".env":               "TOKEN=secret\n",
+		"certs/server.key":   "key\n",
+	})
+	writeTestFiles(t, outside, map[string]string{"secret.txt": "secret\n"})
+
+	links := map[string]string{
+		"escape.txt":  filepath.Join(outside, "secret.txt"),
+		"escape_dir":  outside,
+		"config_link": filepath.Join(root, ".git", "config"),
+		"main_link":   filepath.Join(root, "main.go"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("Symbolic links are not supported: %v", err)
+		}
+	}
+
+	guard := NewPathGuard(root, DefaultDeniedPaths)
+
+	allowed := map[string]string{
+		"main.go":                            "main.go",
+		"./services/../main.go":              "main.go",
+		filepath.Join(root, "services/x.go"): "services/x.go",
+		"new/dir/file.go":                    "new/dir/file.go",
+		"main_link":                          "main_link",
+		".":                                  ".",
+	}
+	for path, expected := range allowed {
+		resolved, err := guard.Resolve(path)
+		if err != nil {
+			t.Errorf("Resolve(%q) failed: %v", path, err)
+			continue
+		}
+		if resolved != filepath.Join(root, filepath.FromSlash(expected)) {
+			t.Errorf("Resolve(%q) = %q, expected %q", path, resolved, expected)
+		}
+	}
+
+	refused := []string{
+		"",
+		"../outside.go",
+		"services/../../outside.go",
+		"/etc/passwd",
+		filepath.Join(outside, "secret.txt"),
+		".git/config",
+		".git",
+		".env",
+		"certs/server.key",
+		"escape.txt",
+		"escape_dir/secret.txt",
+		"config_link",
+	}
+	for _, path := range refused {
+		_, err := guard.Resolve(path)
+		var refusedErr *PathRefusedError
+		if !errors.As(err, &refusedErr) {
+			t.Errorf("Expected Resolve(%q) to be refused, got %v", path, err)
+		}
+	}
+}
+
+func TestPathGuard_FilterDiff(t *testing.T) {
+	guard := NewPathGuard(t.TempDir(), DefaultDeniedPaths)
+
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1 +1,2 @@
+ package main
++func main() {}
+diff --git a/.env b/.env
+--- a/.env
++++ b/.env
+@@ -1 +1 @@
+-TOKEN=one
++TOKEN=two
+diff --git a/config/certs/server.key b/config/certs/server.key
+new file mode 100644
+diff --git a/util.go b/util.go
+--- a/util.go
++++ b/util.go
+@@ -1 +1 @@
+-package util
++package main
+`
+
+	filtered := guard.FilterDiff(diff)
+	for _, expected := range []string{"+func main() {}", "+package main"} {
+		if !strings.Contains(filtered, expected) {
+			t.Errorf("Expected the filtered diff to contain %q, got %q", expected, filtered)
+		}
+	}
+	for _, unexpected := range []string{"TOKEN", "server.key"} {
+		if strings.Contains(filtered, unexpected) {
+			t.Errorf("Expected %q to be filtered out, got %q", unexpected, filtered)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ai-code-editor/ollama"
+)
+
+const (
+	// pageRankDamping is the probability of following a reference rather than
+	// jumping back to a file related to the task
+	pageRankDamping    = 0.85
+	pageRankIterations = 40
+	// maxSymbolsPerFile caps how many signatures are listed for a single file
+	maxSymbolsPerFile = 8
+	// mentionedSymbolWeight boosts references to symbols named in the task
+	mentionedSymbolWeight = 10
+)
+
+// repoFile is a parsed source file of the repository map
+type repoFile struct {
+	path       string // relative to the root
+	symbols    []Symbol
+	references map[string]int // identifier -> number of uses
+}
+
+// RepoMap is a compact overview of a repository. Files are ranked by how much
+// the rest of the code references them, and each file is shown with the
+// signatures of its most used symbols.
+type RepoMap struct {
+	root      string
+	extractor *SymbolExtractor
+	files     []*repoFile
+	definers  map[string][]int // symbol name -> indices of the files defining it
+}
+
+// RankedFile is a file of the repository map with its rank
+type RankedFile struct {
+	Path    string
+	Rank    float64
+	Symbols []Symbol // ordered by how much they are referenced
+}
+
+// NewRepoMap creates an empty repository map for root
+func NewRepoMap(root string) *RepoMap {
+	return &RepoMap{
+		root:      root,
+		extractor: NewSymbolExtractor(),
+		files:     make([]*repoFile, 0),
+		definers:  make(map[string][]int),
+	}
+}
+
+// BuildRepoMap parses the source files under root in every language with
+// symbol support, skipping ignored files, skipPaths (in .gitignore syntax) and
+// files larger than maxFileSize bytes (0 means DefaultMaxFileSize)
+func BuildRepoMap(root string, maxFileSize int64, skipPaths []string) (*RepoMap, error) {
+	repoMap := NewRepoMap(root)
+
+	parseCache := SharedParseCache()
+
+	walker := NewFileWalker(root, maxFileSize, skipPaths)
+	err := walker.Walk(nil, func(path string, info fs.FileInfo) error {
+		if SymbolLanguageForPath(path) == nil {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			relPath = path
+		}
+
+		symbols, references, err := parseCache.Analyze(path)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			return nil
+		}
+		repoMap.addFile(filepath.ToSlash(relPath), withSymbolPath(symbols, filepath.ToSlash(relPath)), references)
+		return nil
+	})
+	if err != nil {
+		repoMap.Close()
+		return nil, fmt.Errorf("failed to build repository map: %w", err)
+	}
+
+	if err := parseCache.Save(); err != nil {
+		log.Printf("Warning: failed to save parse cache: %v", err)
+	}
+
+	return repoMap, nil
+}
+
+// Close releases the parsers
+func (m *RepoMap) Close() {
+	m.extractor.Close()
+}
+
+// AddFile parses a source file and adds its symbols and references to the map
+func (m *RepoMap) AddFile(relPath string, source []byte) {
+	references := make(map[string]int)
+	symbols := m.extractor.analyze(relPath, source, references)
+	m.addFile(relPath, symbols, references)
+}
+
+// addFile adds a file that was already analyzed
+func (m *RepoMap) addFile(relPath string, symbols []Symbol, references map[string]int) {
+	file := &repoFile{
+		path:       relPath,
+		symbols:    symbols,
+		references: references,
+	}
+
+	index := len(m.files)
+	m.files = append(m.files, file)
+	for _, symbol := range file.symbols {
+		m.definers[symbol.Name] = append(m.definers[symbol.Name], index)
+	}
+}
+
+// Rank orders the files by a PageRank over the reference graph, personalized
+// towards files whose path or symbols are mentioned in task and towards
+// focusFiles. Without either, every file starts out equal.
+func (m *RepoMap) Rank(task string, focusFiles []string) []RankedFile {
+	count := len(m.files)
+	if count == 0 {
+		return nil
+	}
+
+	mentioned := mentionedWords(task)
+	personalization := m.personalization(mentioned, focusFiles)
+
+	// edges[from][to] is how strongly file from depends on file to
+	edges := make([]map[int]float64, count)
+	// symbolUses[file][name] is how much other files use a symbol of file
+	symbolUses := make([]map[string]float64, count)
+	for i := range edges {
+		edges[i] = make(map[int]float64)
+		symbolUses[i] = make(map[string]float64)
+	}
+
+	for from, file := range m.files {
+		for name, uses := range file.references {
+			definers := m.definers[name]
+			if len(definers) == 0 {
+				continue
+			}
+
+			weight := math.Sqrt(float64(uses)) / float64(len(definers))
+			if mentioned[strings.ToLower(name)] {
+				weight *= mentionedSymbolWeight
+			}
+
+			for _, to := range definers {
+				if to == from {
+					continue
+				}
+				edges[from][to] += weight
+				symbolUses[to][name] += weight
+			}
+		}
+	}
+
+	ranks := pageRank(edges, personalization)
+
+	ranked := make([]RankedFile, count)
+	for i, file := range m.files {
+		ranked[i] = RankedFile{
+			Path:    file.path,
+			Rank:    ranks[i],
+			Symbols: rankSymbols(file.symbols, symbolUses[i], mentioned),
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Rank != ranked[j].Rank {
+			return ranked[i].Rank > ranked[j].Rank
+		}
+		return ranked[i].Path < ranked[j].Path
+	})
+
+	return ranked
+}
+
+// Render returns the repository map for task, fitted to budgetTokens. The
+// highest ranked files are listed first with their signatures; files that do
+// not fit are counted at the end.
+func (m *RepoMap) Render(task string, focusFiles []string, budgetTokens int) string {
+	ranked := m.Rank(task, focusFiles)
+
+	var output strings.Builder
+	used := 0
+	omitted := 0
+
+	for _, file := range ranked {
+		block := renderRankedFile(file)
+		tokens := ollama.EstimateTokens(block)
+		if used+tokens > budgetTokens {
+			omitted++
+			continue
+		}
+
+		output.WriteString(block)
+		used += tokens
+	}
+
+	if omitted > 0 {
+		output.WriteString(fmt.Sprintf("... %d more files not shown\n", omitted))
+	}
+
+	return output.String()
+}
+
+// personalization returns the restart distribution of the PageRank: files
+// mentioned by the task or asked for directly get most of the weight
+func (m *RepoMap) personalization(mentioned map[string]bool, focusFiles []string) []float64 {
+	weights := make([]float64, len(m.files))
+	total := 0.0
+
+	for i, file := range m.files {
+		if containsString(focusFiles, file.path) {
+			weights[i] += 10
+		}
+
+		base := strings.TrimSuffix(filepath.Base(file.path), filepath.Ext(file.path))
+		if mentioned[strings.ToLower(base)] {
+			weights[i] += 5
+		}
+
+		for _, symbol := range file.symbols {
+			if mentioned[strings.ToLower(symbol.Name)] {
+				weights[i] += 2
+			}
+		}
+
+		total += weights[i]
+	}
+
+	// Nothing in the task points at a file, so start from all of them equally
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = float64(len(weights))
+	}
+
+	for i := range weights {
+		weights[i] /= total
+	}
+
+	return weights
+}
+
+// pageRank runs a personalized PageRank over weighted edges. Rank of files
+// without outgoing edges flows back through the personalization.
+func pageRank(edges []map[int]float64, personalization []float64) []float64 {
+	count := len(edges)
+
+	outWeight := make([]float64, count)
+	for from, targets := range edges {
+		for _, weight := range targets {
+			outWeight[from] += weight
+		}
+	}
+
+	ranks := make([]float64, count)
+	copy(ranks, personalization)
+
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		next := make([]float64, count)
+		dangling := 0.0
+
+		for from, targets := range edges {
+			if outWeight[from] == 0 {
+				dangling += ranks[from]
+				continue
+			}
+			for to, weight := range targets {
+				next[to] += pageRankDamping * ranks[from] * weight / outWeight[from]
+			}
+		}
+
+		for i := range next {
+			next[i] += (1 - pageRankDamping + pageRankDamping*dangling) * personalization[i]
+		}
+		ranks = next
+	}
+
+	return ranks
+}
+
+// rankSymbols orders symbols by how much other files use them, preferring
+// symbols named in the task, then exported ones, then source order
+func rankSymbols(symbols []Symbol, uses map[string]float64, mentioned map[string]bool) []Symbol {
+	ordered := make([]Symbol, len(symbols))
+	copy(ordered, symbols)
+
+	score := func(symbol Symbol) float64 {
+		value := uses[symbol.Name]
+		if mentioned[strings.ToLower(symbol.Name)] {
+			value += mentionedSymbolWeight
+		}
+		return value
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		scoreI, scoreJ := score(ordered[i]), score(ordered[j])
+		if scoreI != scoreJ {
+			return scoreI > scoreJ
+		}
+		exportedI, exportedJ := isExported(ordered[i].Name), isExported(ordered[j].Name)
+		if exportedI != exportedJ {
+			return exportedI
+		}
+		return ordered[i].StartLine < ordered[j].StartLine
+	})
+
+	return ordered
+}
+
+// renderRankedFile formats a file and its top symbols for the map
+func renderRankedFile(file RankedFile) string {
+	var block strings.Builder
+	block.WriteString(file.Path + ":\n")
+
+	shown := file.Symbols
+	if len(shown) > maxSymbolsPerFile {
+		shown = shown[:maxSymbolsPerFile]
+	}
+
+	// Keep the shown symbols in source order so the file reads naturally
+	inOrder := make([]Symbol, len(shown))
+	copy(inOrder, shown)
+	sort.SliceStable(inOrder, func(i, j int) bool { return inOrder[i].StartLine < inOrder[j].StartLine })
+
+	for _, symbol := range inOrder {
+		block.WriteString("    " + symbol.Signature + "\n")
+	}
+	if hidden := len(file.Symbols) - len(shown); hidden > 0 {
+		block.WriteString(fmt.Sprintf("    ... %d more\n", hidden))
+	}
+
+	return block.String()
+}
+
+// mentionedWords returns the lowercased identifiers of a task and their parts
+func mentionedWords(task string) map[string]bool {
+	words := make(map[string]bool)
+	for _, term := range tokenizeCode(task) {
+		words[term] = true
+	}
+	return words
+}
+
+func isExported(name string) bool {
+	for _, r := range name {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// Helper function to build a small repository map
+func testRepoMap() *RepoMap {
+	repoMap := NewRepoMap(".")
+	repoMap.AddFile("store/store.go", []byte(`package store
+
+type Store struct {
+	items map[string]string
+}
+
+func NewStore() *Store {
+	return &Store{items: map[string]string{}}
+}
+
+func (s *Store) Get(key string) (string, bool) {
+	value, ok := s.items[key]
+	return value, ok
+}
+`))
+	repoMap.AddFile("api/handler.go", []byte(`package api
+
+func HandleGet(s *store.Store, key string) string {
+	value, _ := s.Get(key)
+	return value
+}
+`))
+	repoMap.AddFile("cmd/main.go", []byte(`package main
+
+func main() {
+	s := store.NewStore()
+	api.HandleGet(s, "key")
+}
+`))
+	repoMap.AddFile("billing/invoice.go", []byte(`package billing
+
+func RenderInvoice(total int) string {
+	return ""
+}
+`))
+	return repoMap
+}
+
+func TestRepoMap_RanksReferencedFilesFirst(t *testing.T) {
+	repoMap := testRepoMap()
+	defer repoMap.Close()
+
+	ranked := repoMap.Rank("", nil)
+	if ranked[0].Path != "store/store.go" {
+		t.Errorf("Expected the most referenced file first, got %s", ranked[0].Path)
+	}
+	if ranked[len(ranked)-1].Path == "store/store.go" {
+		t.Errorf("Expected store.go not to rank last")
+	}
+}
+
+func TestRepoMap_FocusesOnTask(t *testing.T) {
+	repoMap := testRepoMap()
+	defer repoMap.Close()
+
+	ranked := repoMap.Rank("Add tax to RenderInvoice", nil)
+	if ranked[0].Path != "billing/invoice.go" {
+		t.Errorf("Expected the file defining a symbol named in the task first, got %s", ranked[0].Path)
+	}
+}
+
+func TestRepoMap_RenderFitsBudget(t *testing.T) {
+	repoMap := testRepoMap()
+	defer repoMap.Close()
+
+	rendered := repoMap.Render("", nil, 10000)
+	for _, expected := range []string{"store/store.go:\n", "    type Store struct\n", "    func (s *Store) Get(key string) (string, bool)\n"} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("Expected map to contain %q, got:\n%s", expected, rendered)
+		}
+	}
+
+	rendered = repoMap.Render("", nil, 40)
+	if !strings.HasPrefix(rendered, "store/store.go:") || !strings.Contains(rendered, "more files not shown") {
+		t.Errorf("Expected only the top file and an omission note, got:\n%s", rendered)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Review finding severities, most severe first
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// ReviewSeverities lists the severities a finding may have
+var ReviewSeverities = []string{SeverityError, SeverityWarning, SeverityInfo}
+
+// ReviewFinding is a problem found while reviewing a diff
+type ReviewFinding struct {
+	File         string `json:"file"`
+	Line         int    `json:"line"`
+	Severity     string `json:"severity"`
+	Message      string `json:"message"`
+	SuggestedFix string `json:"suggested_fix,omitempty"`
+}
+
+// SortFindings orders findings by file, then line, then severity
+func SortFindings(findings []ReviewFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return severityRank(findings[i].Severity) < severityRank(findings[j].Severity)
+	})
+}
+
+// WriteFindingsText prints findings for a terminal, with each suggested fix indented below
+func WriteFindingsText(out io.Writer, findings []ReviewFinding) error {
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(out, "No findings.")
+		return err
+	}
+
+	for _, finding := range findings {
+		if _, err := fmt.Fprintf(out, "%s:%d: %s: %s\n", finding.File, finding.Line, finding.Severity, finding.Message); err != nil {
+			return err
+		}
+		if finding.SuggestedFix != "" {
+			fmt.Fprintf(out, "    suggested fix:\n        %s\n", strings.ReplaceAll(strings.TrimRight(finding.SuggestedFix, "\n"), "\n", "\n        "))
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	_, err := fmt.Fprintf(out, "\n%d findings: %d errors, %d warnings, %d info\n",
+		len(findings), counts[SeverityError], counts[SeverityWarning], counts[SeverityInfo])
+	return err
+}
+
+// WriteFindingsJSON writes {"findings": [...]}
+func WriteFindingsJSON(out io.Writer, findings []ReviewFinding) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Findings []ReviewFinding `json:"findings"`
+	}{Findings: findings})
+}
+
+// SARIF 2.1.0, the subset editors need to show results at their locations
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteFindingsSARIF writes the findings as a SARIF 2.1.0 log with one rule
+// per severity. Suggested fixes are added to the message and kept in the
+// suggestedFix property.
+func WriteFindingsSARIF(out io.Writer, toolName string, findings []ReviewFinding) error {
+	rules := make([]sarifRule, len(ReviewSeverities))
+	for i, severity := range ReviewSeverities {
+		rules[i] = sarifRule{ID: "review/" + severity, ShortDescription: sarifMessage{Text: "Code review " + severity}}
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, finding := range findings {
+		result := sarifResult{
+			RuleID:  "review/" + finding.Severity,
+			Level:   sarifLevel(finding.Severity),
+			Message: sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: strings.TrimPrefix(finding.File, "./")},
+				Region:           sarifRegion{StartLine: max(finding.Line, 1)},
+			}}},
+		}
+		if finding.SuggestedFix != "" {
+			result.Message.Text += "\n\nSuggested fix:\n" + finding.SuggestedFix
+			result.Properties = map[string]string{"suggestedFix": finding.SuggestedFix}
+		}
+		results = append(results, result)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: toolName, Rules: rules}},
+			Results: results,
+		}},
+	})
+}
+
+// sarifLevel maps a severity to a SARIF result level
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// severityRank orders severities, most severe first
+func severityRank(severity string) int {
+	for i, known := range ReviewSeverities {
+		if severity == known {
+			return i
+		}
+	}
+	return len(ReviewSeverities)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testFindings() []ReviewFinding {
+	findings := []ReviewFinding{
+		{File: "b.go", Line: 4, Severity: SeverityInfo, Message: "Consider a constant"},
+		{File: "a.go", Line: 9, Severity: SeverityWarning, Message: "Error is ignored", SuggestedFix: "if err != nil {\n\treturn err\n}"},
+		{File: "a.go", Line: 9, Severity: SeverityError, Message: "Nil map write"},
+	}
+	SortFindings(findings)
+	return findings
+}
+
+func TestSortFindings(t *testing.T) {
+	findings := testFindings()
+	if findings[0].Severity != SeverityError || findings[1].Severity != SeverityWarning || findings[2].File != "b.go" {
+		t.Errorf("Unexpected order: %+v", findings)
+	}
+}
+
+func TestWriteFindingsText(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteFindingsText(&out, testFindings()); err != nil {
+		t.Fatalf("WriteFindingsText failed: %v", err)
+	}
+
+	text := out.String()
+	for _, expected := range []string{"a.go:9: error: Nil map write", "        if err != nil {\n        \treturn err", "3 findings: 1 errors, 1 warnings, 1 info"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected the output to contain %q, got:\n%s", expected, text)
+		}
+	}
+}
+
+func TestWriteFindingsSARIF(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteFindingsSARIF(&out, "ai-code-editor", testFindings()); err != nil {
+		t.Fatalf("WriteFindingsSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("Invalid SARIF JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 3 {
+		t.Fatalf("Unexpected SARIF log: %+v", log)
+	}
+
+	result := log.Runs[0].Results[1]
+	location := result.Locations[0].PhysicalLocation
+	if result.Level != "warning" || location.ArtifactLocation.URI != "a.go" || location.Region.StartLine != 9 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result.Properties["suggestedFix"] == "" || !strings.Contains(result.Message.Text, "Suggested fix:") {
+		t.Errorf("Expected the suggested fix in the result, got %+v", result)
+	}
+	if level := log.Runs[0].Results[2].Level; level != "note" {
+		t.Errorf("Expected info findings as notes, got %s", level)
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TestFilter controls whether test files are searched
+type TestFilter int
+
+const (
+	TestsIncluded TestFilter = iota // search test and non-test files
+	TestsExcluded                   // skip test files
+	TestsOnly                       // search only test files
+)
+
+// ParseTestFilter converts "include", "exclude" or "only" to a TestFilter
+func ParseTestFilter(value string) (TestFilter, error) {
+	switch strings.ToLower(value) {
+	case "", "include":
+		return TestsIncluded, nil
+	case "exclude":
+		return TestsExcluded, nil
+	case "only":
+		return TestsOnly, nil
+	default:
+		return TestsIncluded, fmt.Errorf("invalid test filter %q, expected include, exclude or only", value)
+	}
+}
+
+// SearchFilter restricts searches to chunks whose metadata matches every set
+// field. Empty fields do not filter, and a nil *SearchFilter matches everything.
+type SearchFilter struct {
+	Extensions   []string     // file extensions including the dot, e.g. ".go"
+	PathPrefixes []string     // directory prefixes relative to the indexed root, e.g. "services/"
+	Languages    []string     // language names as returned by LanguageForPath
+	SymbolKinds  []SymbolKind // kind of the first declaration in the chunk
+	Tests        TestFilter
+}
+
+// Where returns the Chroma where clause for the filter. Path prefixes match the
+// ancestor directories stored by fileMetadata, or the path of a single file.
+func (f *SearchFilter) Where() map[string]interface{} {
+	if f == nil {
+		return nil
+	}
+
+	conditions := make([]map[string]interface{}, 0)
+
+	if len(f.Extensions) > 0 {
+		conditions = append(conditions, map[string]interface{}{
+			"extension": map[string]interface{}{"$in": normalizeExtensions(f.Extensions)},
+		})
+	}
+	if len(f.Languages) > 0 {
+		conditions = append(conditions, map[string]interface{}{
+			"language": map[string]interface{}{"$in": lowerAll(f.Languages)},
+		})
+	}
+	if len(f.SymbolKinds) > 0 {
+		kinds := make([]string, len(f.SymbolKinds))
+		for i, kind := range f.SymbolKinds {
+			kinds[i] = string(kind)
+		}
+		conditions = append(conditions, map[string]interface{}{
+			"symbol_kind": map[string]interface{}{"$in": kinds},
+		})
+	}
+	if f.Tests != TestsIncluded {
+		conditions = append(conditions, map[string]interface{}{
+			"is_test": map[string]interface{}{"$eq": f.Tests == TestsOnly},
+		})
+	}
+	if where := pathPrefixWhere(f.PathPrefixes); where != nil {
+		conditions = append(conditions, where)
+	}
+
+	switch len(conditions) {
+	case 0:
+		return nil
+	case 1:
+		return conditions[0]
+	default:
+		return map[string]interface{}{"$and": conditions}
+	}
+}
+
+// pathPrefixWhere returns the where clause matching chunks inside one of the
+// prefix directories, or nil when a prefix is the root and everything matches
+func pathPrefixWhere(prefixes []string) map[string]interface{} {
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	paths := make([]string, 0, len(prefixes))
+	dirsByDepth := make(map[int][]string)
+	for _, prefix := range prefixes {
+		cleanPrefix := cleanPathPrefix(prefix)
+		if cleanPrefix == "." || cleanPrefix == "" {
+			return nil
+		}
+		paths = append(paths, cleanPrefix)
+		depth := strings.Count(cleanPrefix, "/") + 1
+		dirsByDepth[depth] = append(dirsByDepth[depth], cleanPrefix)
+	}
+
+	// A prefix may name a file, matched by its path, or a directory, matched by
+	// the ancestor directory at the prefix's depth
+	alternatives := []map[string]interface{}{
+		{"path": map[string]interface{}{"$in": paths}},
+	}
+	depths := make([]int, 0, len(dirsByDepth))
+	for depth := range dirsByDepth {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+	for _, depth := range depths {
+		alternatives = append(alternatives, map[string]interface{}{
+			dirKey(depth): map[string]interface{}{"$in": dirsByDepth[depth]},
+		})
+	}
+
+	return map[string]interface{}{"$or": alternatives}
+}
+
+// Matches reports whether a chunk's metadata satisfies the filter
+func (f *SearchFilter) Matches(metadata map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+
+	path, _ := metadata["path"].(string)
+
+	if len(f.Extensions) > 0 && !containsString(normalizeExtensions(f.Extensions), metadataString(metadata, "extension", filepath.Ext(path))) {
+		return false
+	}
+	if len(f.Languages) > 0 && !containsString(lowerAll(f.Languages), metadataString(metadata, "language", LanguageForPath(path))) {
+		return false
+	}
+	if len(f.SymbolKinds) > 0 {
+		kind := SymbolKind(metadataString(metadata, "symbol_kind", string(SymbolKindNone)))
+		found := false
+		for _, wanted := range f.SymbolKinds {
+			if wanted == kind {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Tests != TestsIncluded {
+		isTest, ok := metadata["is_test"].(bool)
+		if !ok {
+			isTest = IsTestFile(path)
+		}
+		if isTest != (f.Tests == TestsOnly) {
+			return false
+		}
+	}
+	if len(f.PathPrefixes) > 0 && !hasAnyPathPrefix(path, f.PathPrefixes) {
+		return false
+	}
+
+	return true
+}
+
+// hasAnyPathPrefix reports whether path is inside one of the prefix directories.
+// Prefixes match whole path components, so "service" does not match "services/".
+func hasAnyPathPrefix(path string, prefixes []string) bool {
+	slashPath := cleanPathPrefix(path)
+
+	for _, prefix := range prefixes {
+		cleanPrefix := cleanPathPrefix(prefix)
+		if cleanPrefix == "." || cleanPrefix == "" {
+			return true
+		}
+		if slashPath == cleanPrefix || strings.HasPrefix(slashPath, cleanPrefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cleanPathPrefix returns a path in the slash separated form stored in metadata
+func cleanPathPrefix(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+}
+
+func metadataString(metadata map[string]interface{}, key, fallback string) string {
+	if value, ok := metadata[key].(string); ok {
+		return value
+	}
+	return fallback
+}
+
+func normalizeExtensions(extensions []string) []string {
+	normalized := make([]string, len(extensions))
+	for i, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized[i] = ext
+	}
+	return normalized
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return lowered
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchFilter_NilMatchesEverything(t *testing.T) {
+	var filter *SearchFilter
+
+	if filter.Where() != nil {
+		t.Errorf("Expected no where clause for a nil filter")
+	}
+	if !filter.Matches(map[string]interface{}{"path": "main.go"}) {
+		t.Errorf("Expected a nil filter to match")
+	}
+}
+
+func TestSearchFilter_Where(t *testing.T) {
+	filter := &SearchFilter{Extensions: []string{"go"}}
+	expected := map[string]interface{}{
+		"extension": map[string]interface{}{"$in": []string{".go"}},
+	}
+	if where := filter.Where(); !reflect.DeepEqual(where, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v", expected, where)
+	}
+
+	filter = &SearchFilter{Languages: []string{"Go"}, Tests: TestsExcluded, PathPrefixes: []string{"./services/", "codeEditor/actions", "commands"}}
+	expected = map[string]interface{}{
+		"$and": []map[string]interface{}{
+			{"language": map[string]interface{}{"$in": []string{"go"}}},
+			{"is_test": map[string]interface{}{"$eq": false}},
+			{"$or": []map[string]interface{}{
+				{"path": map[string]interface{}{"$in": []string{"services", "codeEditor/actions", "commands"}}},
+				{"dir_1": map[string]interface{}{"$in": []string{"services", "commands"}}},
+				{"dir_2": map[string]interface{}{"$in": []string{"codeEditor/actions"}}},
+			}},
+		},
+	}
+	if where := filter.Where(); !reflect.DeepEqual(where, expected) {
+		t.Errorf("Expected:\n%v\nGot:\n%v", expected, where)
+	}
+
+	// The root prefix matches everything
+	filter = &SearchFilter{PathPrefixes: []string{"services", "."}}
+	if where := filter.Where(); where != nil {
+		t.Errorf("Expected no where clause for the root prefix, got %v", where)
+	}
+}
+
+func TestFileMetadata_AncestorDirs(t *testing.T) {
+	metadata := fileMetadata("codeEditor/actions/edit.go")
+
+	expected := map[string]string{"dir": "codeEditor/actions", "dir_1": "codeEditor", "dir_2": "codeEditor/actions"}
+	for key, value := range expected {
+		if metadata[key] != value {
+			t.Errorf("Expected %s to be %q, got %v", key, value, metadata[key])
+		}
+	}
+	if _, ok := metadata["dir_3"]; ok {
+		t.Errorf("Expected no dir_3 for a file two directories deep")
+	}
+	if _, ok := fileMetadata("main.go")["dir_1"]; ok {
+		t.Errorf("Expected no ancestor directories for a file in the root")
+	}
+}
+
+func TestSearchFilter_Matches(t *testing.T) {
+	filter := &SearchFilter{
+		PathPrefixes: []string{"services/"},
+		Tests:        TestsExcluded,
+		SymbolKinds:  []SymbolKind{SymbolKindFunction, SymbolKindMethod},
+	}
+
+	cases := []struct {
+		metadata map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"path": "services/file_editor.go", "is_test": false, "symbol_kind": "function"}, true},
+		{map[string]interface{}{"path": "services/file_editor_test.go", "is_test": true, "symbol_kind": "function"}, false},
+		{map[string]interface{}{"path": "servicesx/file.go", "is_test": false, "symbol_kind": "function"}, false},
+		{map[string]interface{}{"path": "main.go", "is_test": false, "symbol_kind": "function"}, false},
+		{map[string]interface{}{"path": "services/config.go", "is_test": false, "symbol_kind": "type"}, false},
+	}
+
+	for _, c := range cases {
+		if result := filter.Matches(c.metadata); result != c.expected {
+			t.Errorf("Matches(%v) = %v, expected %v", c.metadata, result, c.expected)
+		}
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	cases := map[string]bool{
+		"services/file_editor_test.go": true,
+		"services/file_editor.go":      false,
+		"tests/helpers.py":             true,
+		"test_parser.py":               true,
+		"src/app.spec.ts":              true,
+		"src/ParserTests.cs":           true,
+		"src/Latest.java":              false,
+		"src/contest.go":               false,
+	}
+
+	for path, expected := range cases {
+		if result := IsTestFile(path); result != expected {
+			t.Errorf("IsTestFile(%q) = %v, expected %v", path, result, expected)
+		}
+	}
+}
+
+func TestDetectSymbolKind(t *testing.T) {
+	cases := map[string]SymbolKind{
+		"func (c *CodeEditor) EditCode() {}":         SymbolKindMethod,
+		"// helper\nfunc mergeFileLists() {}":        SymbolKindFunction,
+		"type BaseAction interface {\n}":             SymbolKindInterface,
+		"type Config struct {\n}":                    SymbolKindClass,
+		"export class Parser {}":                     SymbolKindClass,
+		"type TestFilter int":                        SymbolKindType,
+		"\tfor _, file := range files {\n\t}":        SymbolKindNone,
+		"}\n\ndef parse(text):\n    return text":     SymbolKindFunction,
+		"x := 1\ntype A struct{}\nfunc (a A) B() {}": SymbolKindClass,
+	}
+
+	for code, expected := range cases {
+		if kind := DetectSymbolKind(code); kind != expected {
+			t.Errorf("DetectSymbolKind(%q) = %s, expected %s", code, kind, expected)
+		}
+	}
+}
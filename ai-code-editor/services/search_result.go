@@ -0,0 +1,91 @@
+package services
+
+import "sort"
+
+// SearchResult is a code chunk returned by a search, with its relevance score.
+// Higher scores are more relevant; the scale depends on the search that produced it.
+type SearchResult struct {
+	ID        string
+	Path      string
+	Content   string
+	StartLine int
+	EndLine   int
+	Metadata  map[string]interface{}
+	Score     float64
+	Reranked  bool // Score comes from the reranker rather than the search
+}
+
+// newSearchResult builds a result from a stored document and its metadata
+func newSearchResult(id, document string, metadata map[string]interface{}, score float64) SearchResult {
+	path, _ := metadata["path"].(string)
+
+	return SearchResult{
+		ID:        id,
+		Path:      path,
+		Content:   document,
+		StartLine: metadataInt(metadata, "start_line"),
+		EndLine:   metadataInt(metadata, "end_line"),
+		Metadata:  metadata,
+		Score:     score,
+	}
+}
+
+// metadataInt reads an integer metadata value. Values that went through JSON come
+// back as float64, values set in process are ints.
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch value := metadata[key].(type) {
+	case int:
+		return value
+	case float64:
+		return int(value)
+	case float32:
+		return int(value)
+	default:
+		return 0
+	}
+}
+
+// FuseRankings merges ranked result lists with weighted reciprocal rank fusion.
+// Each result scores the sum of weight/(k+rank) over the lists it appears in, so
+// results ranked highly by several searches rise to the top. Results are matched
+// by ID and the returned scores are the fused scores.
+func FuseRankings(rankings [][]SearchResult, weights []float64, k int) []SearchResult {
+	if k <= 0 {
+		k = 60
+	}
+
+	fused := make(map[string]*SearchResult)
+	order := make([]string, 0)
+
+	for i, ranking := range rankings {
+		weight := 1.0
+		if i < len(weights) {
+			weight = weights[i]
+		}
+
+		for rank, result := range ranking {
+			score := weight / float64(k+rank+1)
+
+			if existing, ok := fused[result.ID]; ok {
+				existing.Score += score
+				continue
+			}
+
+			merged := result
+			merged.Score = score
+			fused[result.ID] = &merged
+			order = append(order, result.ID)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *fused[id])
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
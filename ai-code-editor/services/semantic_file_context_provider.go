@@ -2,127 +2,321 @@ package services
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+
+	"ai-code-editor/config"
 )
 
 // SemanticFileContextProvider provides file context based on semantic similarity
 type SemanticFileContextProvider struct {
 	embeddingService *CodeEmbeddingService
-	chunkingService  *CodeChunkingService
 	baseDir          string
+	indexWorkers     int
+	semanticWeight   float64
+	lexicalWeight    float64
+	rrfK             int
+	queryExpander    QueryExpander
+	reranker         Reranker
+	threshold        float64
+	maxFileSize      int64
+}
+
+// QueryExpander rewrites a query into several more targeted search queries
+type QueryExpander interface {
+	ExpandQuery(query string) ([]string, error)
+}
+
+// Reranker rescores search results against the query, returning them most
+// relevant first with scores between 0 and 1
+type Reranker interface {
+	Rerank(query string, results []SearchResult) ([]SearchResult, error)
+}
+
+// RelevantFile is a file returned by a search with the score of its best chunk
+type RelevantFile struct {
+	Path  string
+	Score float64
 }
 
+// searchCandidateMultiplier is how many more candidates than requested each
+// search contributes before the rankings are fused
+const searchCandidateMultiplier = 3
+
+// contextSourceResults is how many chunks the provider offers as a ContextSource
+const contextSourceResults = 10
+
 // NewSemanticFileContextProvider creates a new semantic file context provider
-func NewSemanticFileContextProvider(embeddingService *CodeEmbeddingService, baseDir string) *SemanticFileContextProvider {
+func NewSemanticFileContextProvider(cfg *config.Config, embeddingService *CodeEmbeddingService, baseDir string) *SemanticFileContextProvider {
+	indexWorkers := cfg.IndexWorkers
+	if indexWorkers <= 0 {
+		indexWorkers = 1
+	}
+
 	return &SemanticFileContextProvider{
 		embeddingService: embeddingService,
-		chunkingService:  NewCodeChunkingService(1500), // Use 1500 as default chunk size
 		baseDir:          baseDir,
+		indexWorkers:     indexWorkers,
+		semanticWeight:   cfg.SearchSemanticWeight,
+		lexicalWeight:    cfg.SearchLexicalWeight,
+		rrfK:             cfg.SearchRRFK,
+		maxFileSize:      cfg.MaxFileSizeBytes,
 	}
 }
 
-// IndexDirectory indexes all code files in a directory
+// IndexDirectory indexes all code files in a directory, several files at a time
 func (p *SemanticFileContextProvider) IndexDirectory(dir string, extensions []string) error {
-	files, err := GetFilesWithExtensions(dir, extensions)
+	if len(extensions) == 0 {
+		extensions = defaultCodeExtensions
+	}
+
+	files, err := NewFileWalker(dir, p.maxFileSize, nil).Files(extensions)
 	if err != nil {
 		return fmt.Errorf("failed to get files: %w", err)
 	}
 
+	progress := NewIndexProgress(os.Stderr, len(files))
+	// Warnings from the workers are printed above the progress bar
+	logOutput := log.Writer()
+	log.SetOutput(progress)
+	defer log.SetOutput(logOutput)
+
+	jobs := make(chan string)
+
+	var wg sync.WaitGroup
+	for range min(p.indexWorkers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				progress.FileDone(p.indexFile(file))
+			}
+		}()
+	}
+
 	for _, file := range files {
-		// Read file content
-		content, err := ioutil.ReadFile(file)
-		if err != nil {
-			log.Printf("Warning: Failed to read file %s: %v", file, err)
-			continue
-		}
+		jobs <- file
+	}
+	close(jobs)
 
-		// Get relative path for storage
-		relPath, err := filepath.Rel(p.baseDir, file)
-		if err != nil {
-			relPath = file // Use absolute path if relative path fails
-		}
+	wg.Wait()
+	if err := p.embeddingService.Flush(); err != nil {
+		log.Printf("Warning: Failed to store the last chunks: %v", err)
+	}
+	progress.Finish()
 
-		// Store file content using the chunk size from the chunking service
-		err = p.embeddingService.StoreCodeChunks(
-			relPath,
-			string(content),
-			p.chunkingService.defaultChunkSize,
-			map[string]interface{}{
-				"path":      relPath,
-				"extension": filepath.Ext(file),
-				"type":      "code",
-			},
-		)
-		if err != nil {
-			log.Printf("Warning: Failed to store file %s: %v", file, err)
+	return nil
+}
+
+// indexFile chunks, embeds and stores a single file, returning the number of chunks stored
+func (p *SemanticFileContextProvider) indexFile(file string) int {
+	// Read file content
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Printf("Warning: Failed to read file %s: %v", file, err)
+		return 0
+	}
+
+	// Get relative path for storage
+	relPath, err := filepath.Rel(p.baseDir, file)
+	if err != nil {
+		relPath = file // Use absolute path if relative path fails
+	}
+
+	// Store file content, chunked according to the configured chunk parameters
+	chunks, err := p.embeddingService.StoreCodeChunks(
+		relPath,
+		string(content),
+		fileMetadata(relPath),
+	)
+	if err != nil {
+		log.Printf("Warning: Failed to store file %s: %v", file, err)
+	}
+
+	return chunks
+}
+
+// SetQueryExpander enables query expansion. Each search then also runs the
+// expanded queries and merges their results with the original query's.
+func (p *SemanticFileContextProvider) SetQueryExpander(expander QueryExpander) {
+	p.queryExpander = expander
+}
+
+// SetReranker enables reranking. Search candidates are rescored by the reranker
+// and results scoring below threshold are dropped.
+func (p *SemanticFileContextProvider) SetReranker(reranker Reranker, threshold float64) {
+	p.reranker = reranker
+	p.threshold = threshold
+}
+
+// Search returns the code chunks most relevant to a query, fusing the semantic
+// and lexical rankings. Without a reranker scores are the fused reciprocal rank
+// scores; with one they are the reranker's 0 to 1 relevance scores, neutral for
+// chunks it failed to score, which the threshold does not drop. Only chunks
+// matching the filter are returned; a nil filter searches everything.
+func (p *SemanticFileContextProvider) Search(query string, limit int, filter *SearchFilter) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 5 // Default limit
+	}
+
+	// Fetch a deeper candidate pool from each search so fusion has room to reorder
+	candidates := limit * searchCandidateMultiplier
+
+	results, err := p.hybridSearch(query, candidates, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.queryExpander != nil {
+		results = p.mergeExpandedQueries(query, results, candidates, filter)
+	}
+
+	if p.reranker != nil {
+		results = p.rerank(query, results, candidates)
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// hybridSearch runs the semantic and lexical searches for one query and fuses them
+func (p *SemanticFileContextProvider) hybridSearch(query string, candidates int, filter *SearchFilter) ([]SearchResult, error) {
+	lexicalResults := p.embeddingService.QueryLexicalCode(query, candidates, filter)
+
+	semanticResults, err := p.embeddingService.QuerySimilarCode(query, candidates, filter)
+	if err != nil {
+		if len(lexicalResults) == 0 {
+			return nil, fmt.Errorf("failed to query similar code: %w", err)
 		}
+		log.Printf("Warning: semantic search failed, using lexical results only: %v", err)
+		semanticResults = []SearchResult{}
 	}
 
-	return nil
+	return FuseRankings(
+		[][]SearchResult{semanticResults, lexicalResults},
+		[]float64{p.semanticWeight, p.lexicalWeight},
+		p.rrfK,
+	), nil
 }
 
-// GetRelevantFiles returns files relevant to a query
-func (p *SemanticFileContextProvider) GetRelevantFiles(query string, limit int) ([]string, error) {
-	results, err := p.embeddingService.QuerySimilarCode(query, limit)
+// mergeExpandedQueries searches with each expanded query and fuses those rankings
+// with the original one. Expansion failures fall back to the original results.
+func (p *SemanticFileContextProvider) mergeExpandedQueries(query string, results []SearchResult, candidates int, filter *SearchFilter) []SearchResult {
+	expandedQueries, err := p.queryExpander.ExpandQuery(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query similar code: %w", err)
+		log.Printf("Warning: query expansion failed, using the original query only: %v", err)
+		return results
 	}
 
-	// Extract unique file paths
-	uniquePaths := make(map[string]bool)
-	for _, result := range results {
-		metadata, ok := result["metadata"].(map[string]interface{})
-		if !ok {
+	log.Printf("Expanded search queries: %v", expandedQueries)
+
+	rankings := [][]SearchResult{results}
+	for _, expandedQuery := range expandedQueries {
+		expandedResults, err := p.hybridSearch(expandedQuery, candidates, filter)
+		if err != nil {
+			log.Printf("Warning: search for expanded query %q failed: %v", expandedQuery, err)
 			continue
 		}
+		rankings = append(rankings, expandedResults)
+	}
 
-		path, ok := metadata["path"].(string)
-		if !ok {
-			continue
+	// The original query is weighted like all expansions combined so a poor
+	// rewrite cannot push out what the user actually asked for
+	weights := make([]float64, len(rankings))
+	weights[0] = float64(max(len(rankings)-1, 1))
+	for i := 1; i < len(weights); i++ {
+		weights[i] = 1
+	}
+
+	return FuseRankings(rankings, weights, p.rrfK)
+}
+
+// rerank rescores the top candidates and drops those below the threshold.
+// Reranking failures fall back to the fused ranking, and candidates the
+// reranker could not score are kept since their relevance is unknown. Scores
+// of reranked and unscored candidates are both in the range 0 to 1.
+func (p *SemanticFileContextProvider) rerank(query string, results []SearchResult, candidates int) []SearchResult {
+	if len(results) > candidates {
+		results = results[:candidates]
+	}
+
+	reranked, err := p.reranker.Rerank(query, results)
+	if err != nil {
+		log.Printf("Warning: reranking failed, using fused ranking: %v", err)
+		return results
+	}
+
+	filtered := make([]SearchResult, 0, len(reranked))
+	for _, result := range reranked {
+		if !result.Reranked || result.Score >= p.threshold {
+			filtered = append(filtered, result)
 		}
+	}
+
+	return filtered
+}
 
-		uniquePaths[path] = true
+// GetRelevantFiles returns files relevant to a query, most relevant first.
+// A nil filter searches everything.
+func (p *SemanticFileContextProvider) GetRelevantFiles(query string, limit int, filter *SearchFilter) ([]string, error) {
+	files, err := p.GetRelevantFilesWithScores(query, limit, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert to slice
-	paths := make([]string, 0, len(uniquePaths))
-	for path := range uniquePaths {
-		paths = append(paths, path)
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
 	}
 
 	return paths, nil
 }
 
-// GetRelevantContext returns code snippets relevant to a query
-func (p *SemanticFileContextProvider) GetRelevantContext(query string, limit int) (map[string]string, error) {
-	results, err := p.embeddingService.QuerySimilarCode(query, limit)
+// GetRelevantFilesWithScores returns files relevant to a query, most relevant first,
+// each scored by its best matching chunk
+func (p *SemanticFileContextProvider) GetRelevantFilesWithScores(query string, limit int, filter *SearchFilter) ([]RelevantFile, error) {
+	results, err := p.Search(query, limit, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query similar code: %w", err)
+		return nil, err
 	}
 
-	// Group snippets by file
-	fileSnippets := make(map[string][]string)
+	// Extract unique file paths, keeping rank order
+	uniquePaths := make(map[string]bool)
+	files := make([]RelevantFile, 0, len(results))
 	for _, result := range results {
-		metadata, ok := result["metadata"].(map[string]interface{})
-		if !ok {
+		if result.Path == "" || uniquePaths[result.Path] {
 			continue
 		}
 
-		path, ok := metadata["path"].(string)
-		if !ok {
-			continue
-		}
+		uniquePaths[result.Path] = true
+		files = append(files, RelevantFile{Path: result.Path, Score: result.Score})
+	}
+
+	return files, nil
+}
 
-		document, ok := result["document"].(string)
-		if !ok {
+// GetRelevantContext returns code snippets relevant to a query.
+// A nil filter searches everything.
+func (p *SemanticFileContextProvider) GetRelevantContext(query string, limit int, filter *SearchFilter) (map[string]string, error) {
+	results, err := p.Search(query, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group snippets by file
+	fileSnippets := make(map[string][]string)
+	for _, result := range results {
+		if result.Path == "" {
 			continue
 		}
 
-		fileSnippets[path] = append(fileSnippets[path], document)
+		fileSnippets[result.Path] = append(fileSnippets[result.Path], result.Content)
 	}
 
 	// Combine snippets for each file
@@ -134,39 +328,40 @@ func (p *SemanticFileContextProvider) GetRelevantContext(query string, limit int
 	return context, nil
 }
 
-// Helper function to get files with specific extensions
-func GetFilesWithExtensions(dir string, extensions []string) ([]string, error) {
-	var files []string
-
-	// If no extensions provided, use common code extensions
-	if len(extensions) == 0 {
-		extensions = []string{".go", ".js", ".ts", ".py", ".java", ".c", ".cpp", ".h", ".hpp", ".cs", ".php", ".rb"}
-	}
-
-	// Create a map for faster lookup
-	extMap := make(map[string]bool)
-	for _, ext := range extensions {
-		extMap[ext] = true
+// ContextItems returns the chunks most relevant to task, making the provider a
+// ContextSource
+func (p *SemanticFileContextProvider) ContextItems(task string) ([]ContextItem, error) {
+	results, err := p.Search(task, contextSourceResults, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	items := make([]ContextItem, 0, len(results))
+	for _, result := range results {
+		if result.Path == "" {
+			continue
 		}
 
-		// Skip directories
-		if info.IsDir() {
-			return nil
-		}
+		items = append(items, ContextItem{
+			Kind:      ContextChunk,
+			Path:      result.Path,
+			Content:   result.Content,
+			StartLine: result.StartLine,
+			EndLine:   result.EndLine,
+			Relevance: result.Score,
+		})
+	}
 
-		// Check if file has one of the specified extensions
-		ext := filepath.Ext(path)
-		if extMap[ext] {
-			files = append(files, path)
-		}
+	return items, nil
+}
 
-		return nil
-	})
+// GetFilesWithExtensions returns the files under dir with one of extensions, or
+// with a common code extension when none are given. Ignored and binary files
+// are skipped, and so are files larger than maxFileSize bytes.
+func GetFilesWithExtensions(dir string, maxFileSize int64, extensions []string) ([]string, error) {
+	if len(extensions) == 0 {
+		extensions = defaultCodeExtensions
+	}
 
-	return files, err
+	return NewFileWalker(dir, maxFileSize, nil).Files(extensions)
 }
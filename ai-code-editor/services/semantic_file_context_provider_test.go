@@ -0,0 +1,129 @@
+package services
+
+import (
+	"ai-code-editor/ollama"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	chromago "github.com/amikos-tech/chroma-go"
+)
+
+// stubReranker scores results by path, leaving paths without a score unscored
+type stubReranker struct {
+	scores map[string]float64
+}
+
+func (r stubReranker) Rerank(query string, results []SearchResult) ([]SearchResult, error) {
+	reranked := make([]SearchResult, len(results))
+	for i, result := range results {
+		if score, ok := r.scores[result.Path]; ok {
+			result.Score = score
+			result.Reranked = true
+		}
+		reranked[i] = result
+	}
+	return reranked, nil
+}
+
+func TestSemanticFileContextProvider_RerankKeepsUnscored(t *testing.T) {
+	provider := &SemanticFileContextProvider{
+		reranker:  stubReranker{scores: map[string]float64{"a.go": 0.9, "b.go": 0.1}},
+		threshold: 0.4,
+	}
+
+	results := []SearchResult{
+		{Path: "a.go", Score: 0.03},
+		{Path: "b.go", Score: 0.02},
+		{Path: "c.go", Score: 0.01},
+	}
+
+	paths := make([]string, 0)
+	for _, result := range provider.rerank("query", results, 10) {
+		paths = append(paths, result.Path)
+	}
+
+	if expected := []string{"a.go", "c.go"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected the low score dropped and the unscored result kept, got %v", paths)
+	}
+}
+
+// stubExpander returns fixed queries, or err
+type stubExpander struct {
+	queries []string
+	err     error
+}
+
+func (e stubExpander) ExpandQuery(query string) ([]string, error) {
+	return e.queries, e.err
+}
+
+// newLexicalOnlyProvider creates a provider whose semantic search fails, as when
+// Ollama is down, so searches use the lexical index of the given files
+func newLexicalOnlyProvider(t *testing.T, files map[string]string) *SemanticFileContextProvider {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	embeddingFunction := NewOllamaEmbeddingFunction(ollama.NewClient(server.URL, true), "test")
+	service := &CodeEmbeddingService{
+		collection:        &chromago.Collection{ID: "test", EmbeddingFunction: embeddingFunction},
+		chromaURL:         server.URL,
+		httpClient:        &http.Client{Timeout: 5 * time.Second},
+		embeddingFunction: embeddingFunction,
+		chunkingService:   newTestChunkingService(1000, 0),
+		lexicalIndex:      NewLexicalIndex(),
+		batchSize:         100,
+	}
+	for path, code := range files {
+		service.StoreCodeChunks(path, code, nil)
+	}
+
+	return &SemanticFileContextProvider{
+		embeddingService: service,
+		semanticWeight:   1,
+		lexicalWeight:    1,
+		rrfK:             60,
+	}
+}
+
+func TestSemanticFileContextProvider_MergesExpandedQueries(t *testing.T) {
+	provider := newLexicalOnlyProvider(t, map[string]string{
+		"inventory.go": "func LoadInventory() {}\n",
+		"stock.go":     "func ParseStockFile() {}\n",
+		"unrelated.go": "func RenderChart() {}\n",
+	})
+
+	paths := func(results []SearchResult) []string {
+		found := make([]string, len(results))
+		for i, result := range results {
+			found[i] = result.Path
+		}
+		return found
+	}
+
+	provider.SetQueryExpander(stubExpander{queries: []string{"ParseStockFile", "LoadInventory"}})
+	results, err := provider.Search("LoadInventory", 5, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	// The original query is weighted like all expansions, so its hit stays first
+	if expected := []string{"inventory.go", "stock.go"}; !reflect.DeepEqual(paths(results), expected) {
+		t.Errorf("Expected %v, got %v", expected, paths(results))
+	}
+
+	provider.SetQueryExpander(stubExpander{err: fmt.Errorf("model unavailable")})
+	results, err = provider.Search("LoadInventory", 5, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if expected := []string{"inventory.go"}; !reflect.DeepEqual(paths(results), expected) {
+		t.Errorf("Expected the original results when expansion fails, got %v", paths(results))
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// maxSignatureLength caps signatures taken from source text, such as a
+// declaration whose parameters span many lines
+const maxSignatureLength = 200
+
+// identifierPattern finds identifiers in files parsed without a grammar
+var identifierPattern = regexp.MustCompile(`[A-Za-z_]\w*`)
+
+// controlKeywords look like method declarations to the line patterns
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "function": true, "foreach": true, "using": true, "lock": true,
+}
+
+// Symbol is a declaration found in a source file
+type Symbol struct {
+	Name      string
+	Kind      SymbolKind
+	Signature string
+	Path      string
+	StartLine int // 1-based
+	EndLine   int
+	Doc       string   // the comment above the declaration, if any
+	Members   []string // struct fields or interface methods, for languages that record them
+}
+
+// SymbolExtractor finds the symbols of source files in any registered
+// language. It keeps one parser and compiled query per language, so it is not
+// safe for concurrent use.
+type SymbolExtractor struct {
+	parsers map[string]*tree_sitter.Parser
+	queries map[string]*tree_sitter.Query
+}
+
+// NewSymbolExtractor creates a SymbolExtractor
+func NewSymbolExtractor() *SymbolExtractor {
+	return &SymbolExtractor{
+		parsers: make(map[string]*tree_sitter.Parser),
+		queries: make(map[string]*tree_sitter.Query),
+	}
+}
+
+// Supports reports whether symbols can be extracted from the file at path
+func (e *SymbolExtractor) Supports(path string) bool {
+	return SymbolLanguageForPath(path) != nil
+}
+
+// Extract returns the symbols declared in source, in source order
+func (e *SymbolExtractor) Extract(path string, source []byte) []Symbol {
+	return e.analyze(path, source, nil)
+}
+
+// Close releases the parsers and queries
+func (e *SymbolExtractor) Close() {
+	for _, parser := range e.parsers {
+		parser.Close()
+	}
+	for _, query := range e.queries {
+		query.Close()
+	}
+	e.parsers = make(map[string]*tree_sitter.Parser)
+	e.queries = make(map[string]*tree_sitter.Query)
+}
+
+// analyze extracts the symbols of source and, when references is not nil,
+// counts the identifiers it uses
+func (e *SymbolExtractor) analyze(path string, source []byte, references map[string]int) []Symbol {
+	language := SymbolLanguageForPath(path)
+	if language == nil {
+		return nil
+	}
+
+	tree := e.parse(language, source, nil)
+	if tree != nil {
+		defer tree.Close()
+	}
+
+	return e.symbols(language, tree, source, path, references)
+}
+
+// parse parses source with the language's grammar. When oldTree is given it
+// must already be edited to match source, and only the changed parts are
+// re-parsed. It returns nil for languages without a grammar.
+func (e *SymbolExtractor) parse(language *SymbolLanguage, source []byte, oldTree *tree_sitter.Tree) *tree_sitter.Tree {
+	if language.Grammar == nil {
+		return nil
+	}
+	return e.parser(language).Parse(source, oldTree)
+}
+
+// symbols extracts the symbols from a parsed tree, or from the source lines
+// when tree is nil, and counts references when references is not nil
+func (e *SymbolExtractor) symbols(language *SymbolLanguage, tree *tree_sitter.Tree, source []byte, path string, references map[string]int) []Symbol {
+	if tree == nil {
+		if language.Grammar != nil {
+			log.Printf("Warning: failed to parse %s", path)
+			return nil
+		}
+		if references != nil {
+			for _, identifier := range identifierPattern.FindAll(source, -1) {
+				references[string(identifier)]++
+			}
+		}
+		return patternSymbols(language, source, path)
+	}
+
+	if references != nil {
+		collectReferences(tree.RootNode(), source, references)
+	}
+
+	if language.Extract != nil {
+		return language.Extract(tree.RootNode(), source, path)
+	}
+	return e.querySymbols(language, tree.RootNode(), source, path)
+}
+
+func (e *SymbolExtractor) parser(language *SymbolLanguage) *tree_sitter.Parser {
+	if parser, ok := e.parsers[language.Name]; ok {
+		return parser
+	}
+
+	parser := tree_sitter.NewParser()
+	parser.SetLanguage(tree_sitter.NewLanguage(language.Grammar()))
+	e.parsers[language.Name] = parser
+	return parser
+}
+
+// querySymbols runs the language's query. A declaration matched by several
+// patterns keeps the most specific kind, so functions inside classes are methods.
+func (e *SymbolExtractor) querySymbols(language *SymbolLanguage, root *tree_sitter.Node, source []byte, path string) []Symbol {
+	query, ok := e.queries[language.Name]
+	if !ok {
+		var queryErr *tree_sitter.QueryError
+		query, queryErr = tree_sitter.NewQuery(tree_sitter.NewLanguage(language.Grammar()), language.Query)
+		if queryErr != nil {
+			log.Printf("Warning: invalid symbol query for %s: %v", language.Name, queryErr)
+			return nil
+		}
+		e.queries[language.Name] = query
+	}
+
+	cursor := tree_sitter.NewQueryCursor()
+	defer cursor.Close()
+
+	captureNames := query.CaptureNames()
+	symbols := make([]Symbol, 0)
+	byStart := make(map[uint]int) // declaration start byte -> index in symbols
+
+	matches := cursor.Matches(query, root, source)
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var name string
+		var definition *tree_sitter.Node
+		var kind SymbolKind
+
+		for _, capture := range match.Captures {
+			captureName := captureNames[capture.Index]
+			switch {
+			case captureName == "name":
+				name = capture.Node.Utf8Text(source)
+			case strings.HasPrefix(captureName, "definition."):
+				node := capture.Node
+				definition = &node
+				kind = SymbolKind(strings.TrimPrefix(captureName, "definition."))
+			}
+		}
+		if name == "" || definition == nil {
+			continue
+		}
+
+		if index, seen := byStart[definition.StartByte()]; seen {
+			if kind == SymbolKindMethod {
+				symbols[index].Kind = kind
+			}
+			continue
+		}
+
+		byStart[definition.StartByte()] = len(symbols)
+		symbols = append(symbols, newSymbol(definition, name, kind, declarationSignature(definition, source), path))
+	}
+
+	return symbols
+}
+
+// declarationSignature returns the text of a declaration up to its body, with
+// whitespace collapsed
+func declarationSignature(node *tree_sitter.Node, source []byte) string {
+	end := node.EndByte()
+	if body := node.ChildByFieldName("body"); body != nil {
+		end = body.StartByte()
+	} else if newline := strings.IndexByte(node.Utf8Text(source), '\n'); newline >= 0 {
+		end = node.StartByte() + uint(newline)
+	}
+
+	signature := strings.Join(strings.Fields(string(source[node.StartByte():end])), " ")
+	signature = strings.TrimRight(signature, " {:=")
+	if len(signature) > maxSignatureLength {
+		signature = signature[:maxSignatureLength] + "..."
+	}
+	return signature
+}
+
+// patternSymbols finds declarations line by line for languages without a grammar
+func patternSymbols(language *SymbolLanguage, source []byte, path string) []Symbol {
+	symbols := make([]Symbol, 0)
+	lines := strings.Split(string(source), "\n")
+
+	for i, line := range lines {
+		for _, pattern := range language.Patterns {
+			match := pattern.Pattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+
+			name := ""
+			for _, group := range match[1:] {
+				if group != "" {
+					name = group
+					break
+				}
+			}
+			if name == "" || controlKeywords[name] {
+				continue
+			}
+
+			signature := strings.TrimRight(strings.TrimSpace(line), " {")
+			symbols = append(symbols, Symbol{
+				Name:      name,
+				Kind:      pattern.Kind,
+				Signature: signature,
+				Path:      path,
+				StartLine: i + 1,
+				EndLine:   blockEndLine(lines, i) + 1,
+			})
+			break
+		}
+	}
+
+	return symbols
+}
+
+// blockEndLine returns the index of the line closing the brace block that
+// starts on line start, or start when the declaration has no block
+func blockEndLine(lines []string, start int) int {
+	depth := 0
+	opened := false
+
+	for i := start; i < len(lines); i++ {
+		for _, r := range lines[i] {
+			switch r {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+
+		if opened && depth <= 0 {
+			return i
+		}
+		// A declaration without a block on its first two lines has no body
+		if !opened && i > start {
+			return start
+		}
+	}
+
+	return start
+}
+
+func newSymbol(node *tree_sitter.Node, name string, kind SymbolKind, signature, path string) Symbol {
+	return Symbol{
+		Name:      name,
+		Kind:      kind,
+		Signature: signature,
+		Path:      path,
+		StartLine: int(node.StartPosition().Row) + 1,
+		EndLine:   int(node.EndPosition().Row) + 1,
+	}
+}
+
+// collectReferences counts the identifiers used anywhere under node
+func collectReferences(node *tree_sitter.Node, source []byte, references map[string]int) {
+	if strings.HasSuffix(node.Kind(), "identifier") {
+		references[node.Utf8Text(source)]++
+		return
+	}
+
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		collectReferences(node.NamedChild(i), source, references)
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Helper function to summarize symbols as "kind name" pairs
+func symbolSummary(symbols []Symbol) []string {
+	summary := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		summary[i] = string(symbol.Kind) + " " + symbol.Name
+	}
+	return summary
+}
+
+func TestSymbolExtractor_Languages(t *testing.T) {
+	extractor := NewSymbolExtractor()
+	defer extractor.Close()
+
+	cases := []struct {
+		path     string
+		source   string
+		expected []string
+	}{
+		{"app.py", `
+class Parser(Base):
+    @staticmethod
+    def create():
+        pass
+
+    def parse(self, text):
+        return text
+
+def main():
+    pass
+`, []string{"class Parser", "method create", "method parse", "function main"}},
+		{"app.js", `
+class Parser {
+  parse(text) { return text }
+}
+function main() {}
+export const helper = (x) => x * 2
+`, []string{"class Parser", "method parse", "function main", "function helper"}},
+		{"Parser.java", `
+public class Parser implements Reader {
+    public Parser() {}
+    public String parse(String text) { return text; }
+}
+interface Reader {}
+`, []string{"class Parser", "method Parser", "method parse", "interface Reader"}},
+		{"lib.rs", `
+pub struct Parser { depth: usize }
+pub trait Reader { fn read(&self) -> String; }
+impl Parser {
+    pub fn parse(&self, text: &str) -> String { text.to_string() }
+}
+fn main() {}
+`, []string{"class Parser", "interface Reader", "method read", "method parse", "function main"}},
+		{"app.ts", `
+export interface Reader {
+  read(): string;
+}
+export class Parser {
+  public parse(text: string): string {
+    if (text) {
+      return text
+    }
+  }
+}
+export const helper = (x: number): number => x * 2
+export type Mode = "fast" | "slow"
+`, []string{"interface Reader", "method read", "class Parser", "method parse", "function helper", "type Mode"}},
+		{"Parser.cs", `
+public interface IReader {}
+public sealed class Parser : IReader
+{
+    public async Task<string> ParseAsync(string text)
+    {
+        return text;
+    }
+}
+`, []string{"interface IReader", "class Parser", "method ParseAsync"}},
+	}
+
+	for _, c := range cases {
+		symbols := extractor.Extract(c.path, []byte(c.source))
+		if summary := symbolSummary(symbols); !reflect.DeepEqual(summary, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.path, c.expected, summary)
+		}
+	}
+}
+
+func TestSymbolExtractor_SignaturesAndLines(t *testing.T) {
+	extractor := NewSymbolExtractor()
+	defer extractor.Close()
+
+	symbols := extractor.Extract("app.py", []byte("def parse(text,\n          strict=False) -> str:\n    return text\n"))
+	if len(symbols) != 1 {
+		t.Fatalf("Expected 1 symbol, got %v", symbols)
+	}
+	if symbols[0].Signature != "def parse(text, strict=False) -> str" {
+		t.Errorf("Unexpected signature %q", symbols[0].Signature)
+	}
+	if symbols[0].StartLine != 1 || symbols[0].EndLine != 3 {
+		t.Errorf("Expected lines 1-3, got %d-%d", symbols[0].StartLine, symbols[0].EndLine)
+	}
+
+	symbols = extractor.Extract("Parser.cs", []byte("class Parser\n{\n    public void Run()\n    {\n    }\n}\n"))
+	if len(symbols) != 2 || symbols[0].EndLine != 6 || symbols[1].StartLine != 3 || symbols[1].EndLine != 5 {
+		t.Errorf("Unexpected line ranges: %+v", symbols)
+	}
+
+	// Multi-line signatures and braces in strings and comments do not confuse the grammars
+	symbols = extractor.Extract("app.ts", []byte("function parse(\n  text: string,\n): string {\n  const open = \"{\" // }\n  return text\n}\n"))
+	if len(symbols) != 1 || symbols[0].StartLine != 1 || symbols[0].EndLine != 6 || symbols[0].Signature != "function parse( text: string, ): string" {
+		t.Errorf("Unexpected TypeScript symbol: %+v", symbols)
+	}
+
+	symbols = extractor.Extract("Parser.cs", []byte("class Parser\n{\n    public string Parse(\n        string text)\n    {\n        var open = \"{\"; // {\n        return text;\n    }\n}\n"))
+	if len(symbols) != 2 || symbols[1].Name != "Parse" || symbols[1].StartLine != 3 || symbols[1].EndLine != 8 || symbols[0].EndLine != 9 {
+		t.Errorf("Unexpected C# symbols: %+v", symbols)
+	}
+
+	if extractor.Supports("notes.txt") {
+		t.Errorf("Expected text files to be unsupported")
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unsafe"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_csharp "github.com/tree-sitter/tree-sitter-c-sharp/bindings/go"
+	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
+	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+	tree_sitter_rust "github.com/tree-sitter/tree-sitter-rust/bindings/go"
+	tree_sitter_typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+)
+
+// SymbolLanguage describes how to find the symbols of one language.
+//
+// Languages with a tree-sitter grammar either provide Extract or a Query whose
+// patterns capture the symbol name as @name and the whole declaration as
+// @definition.<kind>, where kind is a SymbolKind. Languages without a grammar
+// fall back to Patterns, which are matched against each line.
+type SymbolLanguage struct {
+	Name       string
+	Extensions []string
+	Grammar    func() unsafe.Pointer
+	Query      string
+	Extract    func(root *tree_sitter.Node, source []byte, path string) []Symbol
+	Patterns   []SymbolPattern
+}
+
+// SymbolPattern finds a declaration on a single line. The first non-empty
+// group of Pattern is the symbol name.
+type SymbolPattern struct {
+	Kind    SymbolKind
+	Pattern *regexp.Regexp
+}
+
+// typescriptSymbolQuery is shared by TypeScript and TSX, whose grammars differ
+// only in JSX support
+const typescriptSymbolQuery = `
+(class_declaration name: (type_identifier) @name) @definition.class
+(abstract_class_declaration name: (type_identifier) @name) @definition.class
+(interface_declaration name: (type_identifier) @name) @definition.interface
+(type_alias_declaration name: (type_identifier) @name) @definition.type
+(enum_declaration name: (identifier) @name) @definition.type
+(method_definition name: (property_identifier) @name) @definition.method
+(method_signature name: (property_identifier) @name) @definition.method
+(abstract_method_signature name: (property_identifier) @name) @definition.method
+(function_declaration name: (identifier) @name) @definition.function
+(generator_function_declaration name: (identifier) @name) @definition.function
+(function_signature name: (identifier) @name) @definition.function
+(program (lexical_declaration (variable_declarator name: (identifier) @name value: [(arrow_function) (function_expression)]) @definition.function))
+(export_statement (lexical_declaration (variable_declarator name: (identifier) @name value: [(arrow_function) (function_expression)]) @definition.function))
+`
+
+// symbolLanguages maps lowercased file extensions to their language
+var symbolLanguages = make(map[string]*SymbolLanguage)
+
+// RegisterSymbolLanguage adds a language, replacing any language registered
+// for the same extensions
+func RegisterSymbolLanguage(language *SymbolLanguage) {
+	for _, ext := range language.Extensions {
+		symbolLanguages[strings.ToLower(ext)] = language
+	}
+}
+
+// SymbolLanguageForPath returns the language of a file, or nil when symbols
+// cannot be extracted from it
+func SymbolLanguageForPath(path string) *SymbolLanguage {
+	return symbolLanguages[strings.ToLower(filepath.Ext(path))]
+}
+
+func init() {
+	RegisterSymbolLanguage(&SymbolLanguage{
+		Name:       "go",
+		Extensions: []string{".go"},
+		Grammar:    tree_sitter_go.Language,
+		Extract:    goSymbols,
+	})
+
+	RegisterSymbolLanguage(&SymbolLanguage{
+		Name:       "python",
+		Extensions: []string{".py"},
+		Grammar:    tree_sitter_python.Language,
+		Query: `
+(class_definition name: (identifier) @name) @definition.class
+(class_definition body: (block (function_definition name: (identifier) @name) @definition.method))
+(class_definition body: (block (decorated_definition (function_definition name: (identifier) @name) @definition.method)))
+(function_definition name: (identifier) @name) @definition.function
+`,
+	})
+
+	RegisterSymbolLanguage(&SymbolLanguage{
+		Name:       "javascript",
+		Extensions: []string{".js", ".jsx", ".mjs", ".cjs"},
+		Grammar:    tree_sitter_javascript.Language,
+		Query: `
+(class_declaration name: (identifier) @name) @definition.class
+(method_definition name: (property_identifier) @name) @definition.method
+(function_declaration name: (identifier) @name) @definition.function
+(generator_function_declaration name: (identifier) @name) @definition.function
+(program (lexical_declaration (variable_declarator name: (identifier) @name value: [(arrow_function) (function_expression)]) @definition.function))
+(export_statement (lexical_declaration (variable_declarator name: (identifier) @name value: [(arrow_function) (function_expression)]) @definition.function))
+`,
+	})
+
+	RegisterSymbolLanguage(&SymbolLanguage{
+		Name:       "java",
+		Extensions: []string{".java"},
+		Grammar:    tree_sitter_java.Language,
+		Query: `
+(class_declaration name: (identifier) @name) @definition.class
+(record_declaration name: (identifier) @name) @definition.class
+(interface_declaration name: (identifier) @name) @definition.interface
+(enum_declaration name: (identifier) @name) @definition.type
+(method_declaration name: (identifier) @name) @definition.method
+(constructor_declaration name: (identifier) @name) @definition.method
+`,
+	})
+
+	RegisterSymbolLanguage(&SymbolLanguage{
+		Name:       "rust",
+		Extensions: []string{".rs"},
+		Grammar:    tree_sitter_rust.Language,
+		Query: `
+(struct_item name: (type_identifier) @name) @definition.class
+(enum_item name: (type_identifier) @name) @definition.type
+(type_item name: (type_identifier) @name) @definition.type
+(trait_item name: (type_identifier) @name) @definition.interface
+(declaration_list (function_item name: (identifier) @name) @definition.method)
+(declaration_list (function_signature_item name: (identifier) @name) @definition.method)
+(function_item name: (identifier) @name) @definition.function
+`,
+	})
+
+	RegisterSymbolLanguage(&SymbolLanguage{
+		Name:       "typescript",
+		Extensions: []string{".ts", ".mts", ".cts"},
+		Grammar:    tree_sitter_typescript.LanguageTypescript,
+		Query:      typescriptSymbolQuery,
+	})
+
+	RegisterSymbolLanguage(&SymbolLanguage{
+		Name:       "tsx",
+		Extensions: []string{".tsx"},
+		Grammar:    tree_sitter_typescript.LanguageTSX,
+		Query:      typescriptSymbolQuery,
+	})
+
+	RegisterSymbolLanguage(&SymbolLanguage{
+		Name:       "csharp",
+		Extensions: []string{".cs"},
+		Grammar:    tree_sitter_csharp.Language,
+		Query: `
+(class_declaration name: (identifier) @name) @definition.class
+(struct_declaration name: (identifier) @name) @definition.class
+(record_declaration name: (identifier) @name) @definition.class
+(interface_declaration name: (identifier) @name) @definition.interface
+(enum_declaration name: (identifier) @name) @definition.type
+(delegate_declaration name: (identifier) @name) @definition.type
+(method_declaration name: (identifier) @name) @definition.method
+(constructor_declaration name: (identifier) @name) @definition.method
+`,
+	})
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxReferenceResults bounds the references returned for one name
+	maxReferenceResults = 50
+	// maxSymbolMatches bounds how many definitions read_symbol returns
+	maxSymbolMatches = 3
+	// maxSymbolLines bounds the source returned for one definition
+	maxSymbolLines = 300
+)
+
+// SymbolReference is a line that uses a name
+type SymbolReference struct {
+	Path string
+	Line int // 1-based
+	Text string
+}
+
+// SymbolNavigator looks up definitions and references by name in the files
+// under a root directory, using the parse cache
+type SymbolNavigator struct {
+	root        string
+	maxFileSize int64
+	skipPaths   []string
+	parseCache  *ParseCache
+}
+
+// NewSymbolNavigator creates a navigator for the project at root, skipping
+// files larger than maxFileSize bytes (0 means DefaultMaxFileSize) and skipPaths
+func NewSymbolNavigator(root string, maxFileSize int64, skipPaths []string) *SymbolNavigator {
+	return &SymbolNavigator{
+		root:        root,
+		maxFileSize: maxFileSize,
+		skipPaths:   skipPaths,
+		parseCache:  SharedParseCache(),
+	}
+}
+
+// FindSymbol returns the definitions of name. A name such as "Store.Get" only
+// matches members whose signature mentions the qualifier.
+func (n *SymbolNavigator) FindSymbol(name string) ([]Symbol, error) {
+	qualifier, member := splitQualifiedName(name)
+	definitions := make([]Symbol, 0)
+
+	err := n.walkSupported(func(path string) error {
+		symbols, err := n.parseCache.Symbols(path)
+		if err != nil {
+			return nil
+		}
+
+		for _, symbol := range symbols {
+			if symbol.Name != member {
+				continue
+			}
+			if qualifier != "" && !strings.Contains(symbol.Signature, qualifier) {
+				continue
+			}
+			definitions = append(definitions, symbol)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find symbol %s: %w", name, err)
+	}
+
+	return definitions, nil
+}
+
+// FindReferences returns the lines that use name as a whole word, at most
+// maxReferenceResults of them, and the total number found
+func (n *SymbolNavigator) FindReferences(name string) ([]SymbolReference, int, error) {
+	_, member := splitQualifiedName(name)
+	pattern, err := regexp.Compile(`\b` + regexp.QuoteMeta(member) + `\b`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid name %s: %w", name, err)
+	}
+
+	references := make([]SymbolReference, 0)
+	total := 0
+
+	err = n.walkSupported(func(path string) error {
+		// Files whose parse never saw the identifier can be skipped without reading them
+		_, identifiers, err := n.parseCache.Analyze(path)
+		if err != nil || identifiers[member] == 0 {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		for i, line := range strings.Split(string(content), "\n") {
+			if !pattern.MatchString(line) {
+				continue
+			}
+			total++
+			if len(references) < maxReferenceResults {
+				references = append(references, SymbolReference{Path: path, Line: i + 1, Text: strings.TrimSpace(line)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find references to %s: %w", name, err)
+	}
+
+	return references, total, nil
+}
+
+// ReadSymbol returns the source of the definitions of name with line numbers,
+// in the format file contents are shown to the model. When path is not empty
+// only definitions in that file are read.
+func (n *SymbolNavigator) ReadSymbol(name, path string) (string, error) {
+	definitions, err := n.FindSymbol(name)
+	if err != nil {
+		return "", err
+	}
+
+	if path != "" {
+		inFile := make([]Symbol, 0)
+		for _, definition := range definitions {
+			if filepath.Clean(definition.Path) == filepath.Clean(path) {
+				inFile = append(inFile, definition)
+			}
+		}
+		definitions = inFile
+	}
+
+	if len(definitions) == 0 {
+		return "", fmt.Errorf("symbol %s not found", name)
+	}
+
+	var output strings.Builder
+	for i, definition := range definitions {
+		if i == maxSymbolMatches {
+			fmt.Fprintf(&output, "\n... %d more definitions of %s\n", len(definitions)-maxSymbolMatches, name)
+			break
+		}
+
+		content, err := os.ReadFile(definition.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", definition.Path, err)
+		}
+
+		// Include the doc comment above the definition
+		startLine := definition.StartLine - strings.Count(definition.Doc, "\n")
+		if definition.Doc != "" {
+			startLine--
+		}
+
+		item := ContextItem{
+			Kind:      ContextChunk,
+			Path:      definition.Path,
+			Content:   lineNumbered(string(content), startLine, definition.EndLine, maxSymbolLines),
+			StartLine: startLine,
+			EndLine:   definition.EndLine,
+		}
+		output.WriteString(renderContextItem(item, item.Content))
+	}
+
+	return output.String(), nil
+}
+
+// walkSupported calls fn for every file under the root in a supported language
+func (n *SymbolNavigator) walkSupported(fn func(path string) error) error {
+	walker := NewFileWalker(n.root, n.maxFileSize, n.skipPaths)
+	return walker.Walk(nil, func(path string, info fs.FileInfo) error {
+		if SymbolLanguageForPath(path) == nil {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// lineNumbered returns lines startLine to endLine of content prefixed with
+// their numbers, keeping at most maxLines of them
+func lineNumbered(content string, startLine, endLine, maxLines int) string {
+	lines := strings.Split(content, "\n")
+	startLine = max(startLine, 1)
+	endLine = min(endLine, len(lines))
+
+	var output strings.Builder
+	for line := startLine; line <= endLine; line++ {
+		if line-startLine == maxLines {
+			fmt.Fprintf(&output, "... [truncated %d more lines]\n", endLine-line+1)
+			break
+		}
+		fmt.Fprintf(&output, "%d: %s\n", line, lines[line-1])
+	}
+
+	return strings.TrimSuffix(output.String(), "\n")
+}
+
+// splitQualifiedName splits "Store.Get" into "Store" and "Get"
+func splitQualifiedName(name string) (string, string) {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
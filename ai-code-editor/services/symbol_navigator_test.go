@@ -0,0 +1,104 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestNavigator(t *testing.T) (*SymbolNavigator, string) {
+	t.Helper()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"store.go": `package store
+
+// Store keeps values by key
+type Store struct {
+	values map[string]string
+}
+
+// Get returns the value for key
+func (s *Store) Get(key string) string {
+	return s.values[key]
+}
+`,
+		"cache.go": `package store
+
+type Cache struct{}
+
+func (c *Cache) Get(key string) string {
+	return ""
+}
+`,
+		"main.go": `package store
+
+func lookup(s *Store) string {
+	return s.Get("name")
+}
+`,
+	})
+
+	navigator := NewSymbolNavigator(root, 0, nil)
+	navigator.parseCache = OpenParseCache(filepath.Join(t.TempDir(), "cache.json"))
+	t.Cleanup(navigator.parseCache.Close)
+	return navigator, root
+}
+
+func TestSymbolNavigator_FindSymbol(t *testing.T) {
+	navigator, _ := newTestNavigator(t)
+
+	definitions, err := navigator.FindSymbol("Get")
+	if err != nil {
+		t.Fatalf("FindSymbol failed: %v", err)
+	}
+	if len(definitions) != 2 {
+		t.Fatalf("Expected 2 definitions of Get, got %d", len(definitions))
+	}
+
+	definitions, err = navigator.FindSymbol("Store.Get")
+	if err != nil {
+		t.Fatalf("FindSymbol failed: %v", err)
+	}
+	if len(definitions) != 1 || filepath.Base(definitions[0].Path) != "store.go" {
+		t.Errorf("Expected Store.Get only in store.go, got %+v", definitions)
+	}
+}
+
+func TestSymbolNavigator_FindReferences(t *testing.T) {
+	navigator, _ := newTestNavigator(t)
+
+	references, total, err := navigator.FindReferences("Store")
+	if err != nil {
+		t.Fatalf("FindReferences failed: %v", err)
+	}
+	// The doc comment, type declaration, receiver and parameter
+	if total != 4 || len(references) != 4 {
+		t.Errorf("Expected 4 references to Store, got %d (%d returned)", total, len(references))
+	}
+	for _, reference := range references {
+		if filepath.Base(reference.Path) == "cache.go" {
+			t.Errorf("Unexpected reference in cache.go: %+v", reference)
+		}
+	}
+}
+
+func TestSymbolNavigator_ReadSymbol(t *testing.T) {
+	navigator, root := newTestNavigator(t)
+
+	output, err := navigator.ReadSymbol("Get", filepath.Join(root, "store.go"))
+	if err != nil {
+		t.Fatalf("ReadSymbol failed: %v", err)
+	}
+	for _, expected := range []string{"8: // Get returns the value for key", "9: func (s *Store) Get(key string) string {", "11: }"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "Cache") {
+		t.Errorf("Expected only the definition in store.go, got:\n%s", output)
+	}
+
+	if _, err := navigator.ReadSymbol("Missing", ""); err == nil {
+		t.Error("Expected an error for an unknown symbol")
+	}
+}
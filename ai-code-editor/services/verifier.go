@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxDiagnosticsPerCheck bounds the diagnostics reported for one failing check
+	maxDiagnosticsPerCheck = 20
+	// diagnosticContextLines are shown around each diagnostic's line
+	diagnosticContextLines = 3
+)
+
+var (
+	// compilerDiagnostic matches "path/file.go:12:5: message" and "file.go:12: message",
+	// as printed by the compiler, vet and failing tests
+	compilerDiagnostic = regexp.MustCompile(`^\s*(?:\./)?([^\s:]+\.\w+):(\d+)(?::(\d+))?:\s*(.*)$`)
+	// panicFrame matches "\t/abs/path/file.go:12 +0x1d" lines of a panic trace
+	panicFrame = regexp.MustCompile(`^\s+(\S+\.go):(\d+)(?:\s+\+0x[0-9a-f]+)?$`)
+)
+
+// Diagnostic is a problem reported by a check at a position in a file
+type Diagnostic struct {
+	Path    string
+	Line    int // 1-based
+	Column  int // 0 when unknown
+	Message string
+}
+
+// CheckFailure is a check that did not pass
+type CheckFailure struct {
+	Command     string
+	Output      string
+	Diagnostics []Diagnostic
+}
+
+// VerifyResult is the outcome of running every check
+type VerifyResult struct {
+	Passed   bool
+	Failures []CheckFailure
+}
+
+// Verifier runs the checks that must pass after an edit, such as building
+// and testing the project
+type Verifier struct {
+	root   string
+	checks []string
+	runner *CommandRunner
+}
+
+// NewVerifier creates a verifier running checks in root. Checks run without
+// confirmation since they are configured by the user, not the model.
+func NewVerifier(root string, checks []string, runner *CommandRunner) *Verifier {
+	return &Verifier{
+		root:   root,
+		checks: checks,
+		runner: runner,
+	}
+}
+
+// DefaultVerifyChecks returns the checks for the project at root: building and
+// testing Go modules, none for other projects
+func DefaultVerifyChecks(root string) []string {
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+		return []string{"go build ./...", "go test ./..."}
+	}
+	return []string{}
+}
+
+// Checks returns the commands the verifier runs
+func (v *Verifier) Checks() []string {
+	return v.checks
+}
+
+// Verify runs the checks in order and stops at the first failure, since a
+// build failure makes the tests fail too
+func (v *Verifier) Verify() VerifyResult {
+	for _, check := range v.checks {
+		result, err := v.runner.Run(check, "")
+		if err != nil {
+			return VerifyResult{Failures: []CheckFailure{{Command: check, Output: err.Error()}}}
+		}
+
+		if result.TimedOut || result.ExitCode != 0 {
+			output := result.Output
+			if result.TimedOut {
+				output = "timed out\n" + output
+			}
+			return VerifyResult{Failures: []CheckFailure{{
+				Command:     check,
+				Output:      output,
+				Diagnostics: v.projectDiagnostics(ParseDiagnostics(output)),
+			}}}
+		}
+	}
+
+	return VerifyResult{Passed: true}
+}
+
+// Regressions returns the failures of result that baseline, the result of the
+// checks before an edit, did not already have, so an edit is not blamed for a
+// project that was already failing. Diagnostics are compared by file and
+// message since an edit moves lines. A failing check without diagnostics only
+// counts when it passed before.
+func (v *Verifier) Regressions(baseline, result VerifyResult) VerifyResult {
+	known := make(map[string][]Diagnostic)
+	for _, failure := range baseline.Failures {
+		known[failure.Command] = failure.Diagnostics
+	}
+
+	failures := make([]CheckFailure, 0, len(result.Failures))
+	for _, failure := range result.Failures {
+		before, failedBefore := known[failure.Command]
+		if !failedBefore {
+			failures = append(failures, failure)
+			continue
+		}
+
+		seen := make(map[string]bool, len(before))
+		for _, diagnostic := range before {
+			seen[diagnostic.Path+":"+diagnostic.Message] = true
+		}
+
+		introduced := make([]Diagnostic, 0)
+		for _, diagnostic := range failure.Diagnostics {
+			if !seen[diagnostic.Path+":"+diagnostic.Message] {
+				introduced = append(introduced, diagnostic)
+			}
+		}
+		if len(introduced) > 0 {
+			failure.Diagnostics = introduced
+			failures = append(failures, failure)
+		}
+	}
+
+	return VerifyResult{Passed: len(failures) == 0, Failures: failures}
+}
+
+// Report describes the failures for the model, showing the source around
+// each diagnostic with line numbers. Checks without recognizable diagnostics
+// show their output instead.
+func (v *Verifier) Report(result VerifyResult) string {
+	var report strings.Builder
+
+	for _, failure := range result.Failures {
+		fmt.Fprintf(&report, "`%s` failed:\n", failure.Command)
+
+		if len(failure.Diagnostics) == 0 {
+			report.WriteString(failure.Output)
+			report.WriteString("\n")
+			continue
+		}
+
+		for i, diagnostic := range failure.Diagnostics {
+			if i == maxDiagnosticsPerCheck {
+				fmt.Fprintf(&report, "... %d more diagnostics\n", len(failure.Diagnostics)-maxDiagnosticsPerCheck)
+				break
+			}
+
+			fmt.Fprintf(&report, "%s:%d: %s\n", diagnostic.Path, diagnostic.Line, diagnostic.Message)
+			if content, err := os.ReadFile(filepath.Join(v.root, diagnostic.Path)); err == nil {
+				report.WriteString(lineNumbered(string(content), diagnostic.Line-diagnosticContextLines, diagnostic.Line+diagnosticContextLines, 2*diagnosticContextLines+1))
+				report.WriteString("\n")
+			}
+		}
+	}
+
+	return report.String()
+}
+
+// projectDiagnostics makes absolute paths relative to the root and drops
+// positions outside the project, such as standard library panic frames
+func (v *Verifier) projectDiagnostics(diagnostics []Diagnostic) []Diagnostic {
+	root, err := filepath.Abs(v.root)
+	if err != nil {
+		return diagnostics
+	}
+
+	kept := make([]Diagnostic, 0, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		if filepath.IsAbs(diagnostic.Path) {
+			relPath, err := filepath.Rel(root, diagnostic.Path)
+			if err != nil || strings.HasPrefix(relPath, "..") {
+				continue
+			}
+			diagnostic.Path = filepath.ToSlash(relPath)
+		}
+		kept = append(kept, diagnostic)
+	}
+
+	return kept
+}
+
+// ParseDiagnostics finds file:line positions in compiler, vet and test output.
+// Lines repeating an earlier position are dropped.
+func ParseDiagnostics(output string) []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	seen := make(map[string]bool)
+
+	for _, line := range strings.Split(output, "\n") {
+		var diagnostic Diagnostic
+
+		if match := compilerDiagnostic.FindStringSubmatch(line); match != nil {
+			diagnostic.Path = match[1]
+			diagnostic.Line, _ = strconv.Atoi(match[2])
+			diagnostic.Column, _ = strconv.Atoi(match[3])
+			diagnostic.Message = strings.TrimSpace(match[4])
+		} else if match := panicFrame.FindStringSubmatch(line); match != nil {
+			diagnostic.Path = match[1]
+			diagnostic.Line, _ = strconv.Atoi(match[2])
+			diagnostic.Message = "in panic stack trace"
+		} else {
+			continue
+		}
+
+		key := fmt.Sprintf("%s:%d:%s", diagnostic.Path, diagnostic.Line, diagnostic.Message)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `# ai-code-editor/services
+services/repo_map.go:42:9: undefined: rankFiles
+./main.go:10:2: "os" imported and not used
+--- FAIL: TestRank (0.00s)
+    repo_map_test.go:31: Expected 2 files, got 1
+    repo_map_test.go:31: Expected 2 files, got 1
+panic: runtime error [recovered]
+	/home/user/project/services/repo_map.go:88 +0x1d
+FAIL
+`
+
+	expected := []Diagnostic{
+		{Path: "services/repo_map.go", Line: 42, Column: 9, Message: "undefined: rankFiles"},
+		{Path: "main.go", Line: 10, Column: 2, Message: `"os" imported and not used`},
+		{Path: "repo_map_test.go", Line: 31, Message: "Expected 2 files, got 1"},
+		{Path: "/home/user/project/services/repo_map.go", Line: 88, Message: "in panic stack trace"},
+	}
+
+	if diagnostics := ParseDiagnostics(output); !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, diagnostics)
+	}
+}
+
+func TestVerifier_ReportsFirstFailure(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tbroken()\n}\n",
+	})
+
+	checks := []string{
+		"true",
+		"sh -c 'echo main.go:4:2: undefined: broken; printf \"\\t%s:4 +0x1d\\n\\t/usr/lib/go/src/runtime/panic.go:1 +0x1d\\n\" " + filepath.Join(root, "main.go") + "; exit 1'",
+		"sh -c 'echo never run; exit 1'",
+	}
+	verifier := NewVerifier(root, checks, NewCommandRunner(root, checks, 0, 0, nil))
+
+	result := verifier.Verify()
+	if result.Passed || len(result.Failures) != 1 || result.Failures[0].Command != checks[1] {
+		t.Fatalf("Expected the second check to fail, got %+v", result)
+	}
+
+	expected := []Diagnostic{
+		{Path: "main.go", Line: 4, Column: 2, Message: "undefined: broken"},
+		{Path: "main.go", Line: 4, Message: "in panic stack trace"},
+	}
+	if !reflect.DeepEqual(result.Failures[0].Diagnostics, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result.Failures[0].Diagnostics)
+	}
+
+	report := verifier.Report(result)
+	for _, line := range []string{"main.go:4: undefined: broken", "3: func main() {", "4: \tbroken()"} {
+		if !strings.Contains(report, line) {
+			t.Errorf("Expected report to contain %q, got:\n%s", line, report)
+		}
+	}
+}
+
+func TestVerifier_Passes(t *testing.T) {
+	root := t.TempDir()
+	verifier := NewVerifier(root, []string{"true"}, NewCommandRunner(root, []string{"true"}, 0, 0, nil))
+
+	if result := verifier.Verify(); !result.Passed {
+		t.Errorf("Expected checks to pass, got %+v", result)
+	}
+}
+
+func TestVerifier_Regressions(t *testing.T) {
+	verifier := NewVerifier(t.TempDir(), []string{}, nil)
+
+	baseline := VerifyResult{Failures: []CheckFailure{{
+		Command:     "go test ./...",
+		Diagnostics: []Diagnostic{{Path: "store_test.go", Line: 10, Message: "Expected 2, got 1"}},
+	}}}
+
+	tests := []struct {
+		name     string
+		result   VerifyResult
+		passed   bool
+		messages []string
+	}{
+		{"passing", VerifyResult{Passed: true}, true, nil},
+		{"same failure moved", VerifyResult{Failures: []CheckFailure{{
+			Command:     "go test ./...",
+			Diagnostics: []Diagnostic{{Path: "store_test.go", Line: 14, Message: "Expected 2, got 1"}},
+		}}}, true, nil},
+		{"new diagnostic", VerifyResult{Failures: []CheckFailure{{
+			Command: "go test ./...",
+			Diagnostics: []Diagnostic{
+				{Path: "store_test.go", Line: 10, Message: "Expected 2, got 1"},
+				{Path: "store.go", Line: 3, Message: "undefined: Get"},
+			},
+		}}}, false, []string{"undefined: Get"}},
+		{"new check failing", VerifyResult{Failures: []CheckFailure{{
+			Command: "go build ./...",
+			Output:  "build failed",
+		}}}, false, nil},
+	}
+
+	for _, tt := range tests {
+		regressions := verifier.Regressions(baseline, tt.result)
+		if regressions.Passed != tt.passed {
+			t.Errorf("%s: expected passed %v, got %+v", tt.name, tt.passed, regressions)
+			continue
+		}
+		for _, message := range tt.messages {
+			if len(regressions.Failures) != 1 || len(regressions.Failures[0].Diagnostics) != 1 || regressions.Failures[0].Diagnostics[0].Message != message {
+				t.Errorf("%s: expected only %q to be reported, got %+v", tt.name, message, regressions.Failures)
+			}
+		}
+	}
+}
+
+func TestDefaultVerifyChecks(t *testing.T) {
+	root := t.TempDir()
+	if checks := DefaultVerifyChecks(root); len(checks) != 0 {
+		t.Errorf("Expected no checks without go.mod, got %v", checks)
+	}
+
+	writeTestFiles(t, root, map[string]string{"go.mod": "module example\n"})
+	if checks := DefaultVerifyChecks(root); !reflect.DeepEqual(checks, []string{"go build ./...", "go test ./..."}) {
+		t.Errorf("Expected Go checks, got %v", checks)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxGrepMatches bounds the matches returned for one search
+	maxGrepMatches = 50
+	// maxGrepContextLines bounds the context lines shown around a match
+	maxGrepContextLines = 5
+	// maxGrepLineLength truncates long lines such as minified code
+	maxGrepLineLength = 200
+	// maxDirEntries bounds the entries returned for one directory
+	maxDirEntries = 200
+)
+
+// GrepMatch is a line matching a search, with the lines around it
+type GrepMatch struct {
+	Path   string
+	Line   int // 1-based
+	Text   string
+	Before []string // context lines above the match, nearest last
+	After  []string
+}
+
+// GrepResult holds the first maxGrepMatches matches and the total found
+type GrepResult struct {
+	Matches []GrepMatch
+	Total   int
+}
+
+// DirEntry is a file or directory in a listing
+type DirEntry struct {
+	Name  string
+	IsDir bool
+	Size  int64
+}
+
+// DirListing holds the first maxDirEntries entries of a directory and the total
+type DirListing struct {
+	Path    string
+	Entries []DirEntry
+	Total   int
+}
+
+// WorkspaceExplorer searches and lists the files under a root directory,
+// skipping the files FileWalker skips
+type WorkspaceExplorer struct {
+	root   string
+	walker *FileWalker
+}
+
+// NewWorkspaceExplorer creates an explorer for the project at root
+func NewWorkspaceExplorer(root string, maxFileSize int64, skipPaths []string) *WorkspaceExplorer {
+	return &WorkspaceExplorer{
+		root:   root,
+		walker: NewFileWalker(root, maxFileSize, skipPaths),
+	}
+}
+
+// Grep returns the lines matching the regular expression pattern. When glob is
+// not empty only files whose relative path or name match it are searched, e.g.
+// "*.go" or "services/**/*_test.go". contextLines lines around each match are
+// included, at most maxGrepContextLines.
+func (e *WorkspaceExplorer) Grep(pattern, glob string, contextLines int) (GrepResult, error) {
+	result := GrepResult{Matches: make([]GrepMatch, 0)}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return result, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+
+	var globRegex *regexp.Regexp
+	if glob != "" {
+		globRegex, err = regexp.Compile("^" + globToRegexp(strings.TrimPrefix(glob, "/")) + "$")
+		if err != nil {
+			return result, fmt.Errorf("invalid glob %s: %w", glob, err)
+		}
+	}
+
+	contextLines = max(0, min(contextLines, maxGrepContextLines))
+
+	err = e.walker.Walk(nil, func(path string, info fs.FileInfo) error {
+		relPath := e.relativePath(path)
+		if globRegex != nil && !globRegex.MatchString(relPath) && !globRegex.MatchString(filepath.Base(relPath)) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		lines := strings.Split(string(content), "\n")
+		for i, line := range lines {
+			if !regex.MatchString(line) {
+				continue
+			}
+			result.Total++
+			if len(result.Matches) >= maxGrepMatches {
+				continue
+			}
+
+			result.Matches = append(result.Matches, GrepMatch{
+				Path:   relPath,
+				Line:   i + 1,
+				Text:   truncateLine(line),
+				Before: truncateLines(lines[max(0, i-contextLines):i]),
+				After:  truncateLines(lines[i+1 : min(len(lines), i+1+contextLines)]),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to search for %s: %w", pattern, err)
+	}
+
+	return result, nil
+}
+
+// ListDir returns the entries of the directory at relDir, relative to the root
+func (e *WorkspaceExplorer) ListDir(relDir string) (DirListing, error) {
+	if relDir == "" {
+		relDir = "."
+	}
+	listing := DirListing{Path: filepath.ToSlash(filepath.Clean(relDir)), Entries: make([]DirEntry, 0)}
+
+	if filepath.IsAbs(relDir) || listing.Path == ".." || strings.HasPrefix(listing.Path, "../") {
+		return listing, fmt.Errorf("directory %s is outside the project", relDir)
+	}
+
+	entries, err := e.walker.ListDir(relDir)
+	if err != nil {
+		return listing, err
+	}
+
+	listing.Total = len(entries)
+	for _, entry := range entries {
+		if len(listing.Entries) == maxDirEntries {
+			break
+		}
+
+		dirEntry := DirEntry{Name: entry.Name(), IsDir: entry.IsDir()}
+		if info, err := entry.Info(); err == nil && !entry.IsDir() {
+			dirEntry.Size = info.Size()
+		}
+		listing.Entries = append(listing.Entries, dirEntry)
+	}
+
+	return listing, nil
+}
+
+// relativePath returns path relative to the root with forward slashes
+func (e *WorkspaceExplorer) relativePath(path string) string {
+	relPath, err := filepath.Rel(e.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(relPath)
+}
+
+func truncateLine(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if len(line) > maxGrepLineLength {
+		return line[:maxGrepLineLength] + "..."
+	}
+	return line
+}
+
+func truncateLines(lines []string) []string {
+	truncated := make([]string, len(lines))
+	for i, line := range lines {
+		truncated[i] = truncateLine(line)
+	}
+	return truncated
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestExplorer(t *testing.T) *WorkspaceExplorer {
+	t.Helper()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".gitignore":         "build/\n*.log\n",
+		"main.go":            "package main\n\nfunc main() {\n\trun()\n}\n\nfunc run() {}\n",
+		"app.log":            "run\n",
+		"build/out.go":       "func run() {}\n",
+		"services/run.go":    "package services\n\nfunc Run() {}\n",
+		"services/README.md": "Call run() to start\n",
+	})
+	return NewWorkspaceExplorer(root, 0, nil)
+}
+
+func TestWorkspaceExplorer_Grep(t *testing.T) {
+	explorer := newTestExplorer(t)
+
+	result, err := explorer.Grep(`func \w+\(`, "*.go", 1)
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+
+	locations := make([]string, 0)
+	for _, match := range result.Matches {
+		locations = append(locations, match.Path+":"+match.Text)
+	}
+	expected := []string{"main.go:func main() {", "main.go:func run() {}", "services/run.go:func Run() {}"}
+	if !reflect.DeepEqual(locations, expected) {
+		t.Errorf("Expected %v, got %v", expected, locations)
+	}
+
+	first := result.Matches[0]
+	if first.Line != 3 || !reflect.DeepEqual(first.Before, []string{""}) || !reflect.DeepEqual(first.After, []string{"\trun()"}) {
+		t.Errorf("Unexpected context for first match: %+v", first)
+	}
+}
+
+func TestWorkspaceExplorer_GrepGlobMatchesPaths(t *testing.T) {
+	explorer := newTestExplorer(t)
+
+	result, err := explorer.Grep(`run`, "services/**", 0)
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+	if result.Total != 1 || result.Matches[0].Path != "services/README.md" {
+		t.Errorf("Expected one match in services/README.md, got %+v", result.Matches)
+	}
+
+	if _, err := explorer.Grep(`(`, "", 0); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestWorkspaceExplorer_ListDir(t *testing.T) {
+	explorer := newTestExplorer(t)
+
+	listing, err := explorer.ListDir(".")
+	if err != nil {
+		t.Fatalf("ListDir failed: %v", err)
+	}
+
+	names := make([]string, 0)
+	for _, entry := range listing.Entries {
+		names = append(names, entry.Name)
+	}
+	// Directories first; ignored and hidden entries are skipped
+	expected := []string{"services", "main.go"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+
+	for _, dir := range []string{"build", "../", "/etc"} {
+		if _, err := explorer.ListDir(dir); err == nil {
+			t.Errorf("Expected an error listing %s", dir)
+		}
+	}
+}